@@ -17,6 +17,10 @@ func main() {
 		flags.WithRateLimit(),
 		flags.WithTLSKeyPath(),
 		flags.WithGrpcAddr(),
+		flags.WithRuntimeMetrics(),
+		flags.WithCgroupPath(),
+		flags.WithStatsd(),
+		flags.WithLogCheckpoint(),
 	)
 
 	// Создание контекста для возможности отмены операций.
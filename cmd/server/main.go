@@ -4,6 +4,8 @@ import (
 	"context"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
 	serverRunner "github.com/ZnNr/go-musthave-metrics.git/internal/server/runner/server"
+	"log"
+	"os"
 )
 
 func main() {
@@ -20,8 +22,21 @@ func main() {
 		flags.WithTrustedSubnet(),
 		flags.WithGrpc(),
 		flags.WithGrpcAddr(),
+		flags.WithExpiry(),
+		flags.WithAdminToken(),
+		flags.WithLimits(),
+		flags.WithAlerting(),
+		flags.WithAlertEmail(),
 	)
 
+	// Режим проверки файла правил оповещений, например в CI.
+	if params.CheckRules != "" {
+		if err := serverRunner.CheckRules(params.CheckRules, os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Создание контекста для возможности отмены операций.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -25,6 +25,7 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	"log"
+	"net/http"
 	"os"
 	"sync"
 	"time"
@@ -42,7 +43,18 @@ func (a *Agent) CollectMetrics(ctx context.Context) {
 				return
 			case <-storeTicker.C:
 				a.storage.RuntimeMetricStore()
-				a.storage.GopsutilMetricStore()
+				if err := a.storage.GopsutilMetricStore(); err != nil {
+					a.log.Errorf("error while collecting gopsutil metrics: %s", err.Error())
+				}
+				if err := a.storage.ProcessMetricStore(); err != nil {
+					a.log.Errorf("error while collecting process metrics: %s", err.Error())
+				}
+				if err := a.storage.CgroupMetricStore(); err != nil {
+					a.log.Errorf("error while collecting cgroup metrics: %s", err.Error())
+				}
+				if err := a.storage.SourceMetricStore(); err != nil {
+					a.log.Errorf("error while storing custom metrics: %s", err.Error())
+				}
 			}
 		}
 	}()
@@ -85,14 +97,16 @@ func (a *Agent) SendMetricsLoop(ctx context.Context) (err error) {
 	}
 }
 
-// SendMetrics - метод для отправки метрик
+// SendMetrics - метод для отправки метрик.
+// Счетчики отправляются как прирост с момента предыдущей успешной отправки: после того как HTTP сервер
+// принял счетчик, отправленное значение вычитается из накопленного агентом.
 func (a *Agent) SendMetrics(ctx context.Context) error {
-	if err := a.sendHTTP(ctx); err != nil {
-		return err
-	}
+	metrics := collector.Collector().Snapshot()
+	sent := a.sendHTTP(ctx, metrics)
+	collector.Collector().SubtractCounters(sent)
 	a.log.Info("metrics were successfully sent to HTTP server")
 	if a.params.GrpcRunAddr != "" {
-		if err := a.sendGrpc(ctx); err != nil {
+		if err := a.sendGrpc(ctx, metrics); err != nil {
 			return err
 		}
 		a.log.Info("metrics were successfully sent to gRPC server")
@@ -100,17 +114,35 @@ func (a *Agent) SendMetrics(ctx context.Context) error {
 	return nil
 }
 
-func (a *Agent) sendGrpc(ctx context.Context) error {
-	for _, v := range collector.Collector().Metrics {
+func (a *Agent) sendGrpc(ctx context.Context, metrics []collector.StoredMetric) error {
+	for _, v := range metrics {
 		request := pb.MetricRequest{
 			ID:    v.ID,
 			MType: v.MType,
 		}
+		if md, ok := a.metadata[v.ID]; ok {
+			request.Metadata = &pb.MetricMetadata{
+				Unit:        md.Unit,
+				Description: md.Description,
+				Type:        md.Type,
+				Owner:       md.Owner,
+			}
+		}
 		switch request.MType {
 		case collector.Gauge:
 			request.Value = *v.GaugeValue
 		case collector.Counter:
 			request.Delta = *v.CounterValue
+		case collector.Histogram:
+			request.Histogram = &pb.Histogram{
+				Count:     v.HistogramValue.Count,
+				Sum:       v.HistogramValue.Sum,
+				Bounds:    v.HistogramValue.Bounds,
+				Buckets:   v.HistogramValue.Buckets,
+				Schema:    v.HistogramValue.Schema,
+				ZeroCount: v.HistogramValue.ZeroCount,
+				Positive:  v.HistogramValue.Positive,
+			}
 		}
 
 		if _, err := a.grpcMetricsClient.SaveMetricFromJSON(ctx, &request); err != nil {
@@ -121,26 +153,33 @@ func (a *Agent) sendGrpc(ctx context.Context) error {
 }
 
 // sendHTTP — метод, инкапсулирующий логику отправки http-запроса на сервер.
-func (a *Agent) sendHTTP(ctx context.Context) error {
-	req := a.client.SetRetryCount(3).R().
-		SetHeader("Content-Type", "application/json").
-		SetHeader("Accept-Encoding", "gzip").
-		SetHeader("Content-Encoding", "gzip").
-		SetContext(ctx)
-	a.SetRealIPFromRequest(req) // Вызываем метод SetRealIPFromRequest для сохранения реального IP-адреса клиента
+// Возвращает счетчики, которые сервер принял.
+func (a *Agent) sendHTTP(ctx context.Context, metrics []collector.StoredMetric) []collector.StoredMetric {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		sent []collector.StoredMetric
+	)
 
-	var wg sync.WaitGroup
-
-	for _, v := range collector.Collector().Metrics {
+	for _, v := range metrics {
 		wg.Add(1)
 		go func(metric collector.StoredMetric) {
 			defer wg.Done()
 
+			req := a.client.R().
+				SetHeader("Content-Type", "application/json").
+				SetHeader("Accept-Encoding", "gzip").
+				SetHeader("Content-Encoding", "gzip").
+				SetContext(ctx)
+			a.SetRealIPFromRequest(req) // Вызываем метод SetRealIPFromRequest для сохранения реального IP-адреса клиента
+
 			jsonInput, err := json.Marshal(collector.MetricRequest{
-				ID:    metric.ID,
-				MType: metric.MType,
-				Delta: metric.CounterValue,
-				Value: metric.GaugeValue,
+				ID:        metric.ID,
+				MType:     metric.MType,
+				Delta:     metric.CounterValue,
+				Value:     metric.GaugeValue,
+				Histogram: metric.HistogramValue,
+				Metadata:  a.metadata[metric.ID],
 			})
 			if err != nil {
 				a.log.Errorf("Error marshaling MetricRequest: %v", err)
@@ -163,12 +202,18 @@ func (a *Agent) sendHTTP(ctx context.Context) error {
 
 			if err := a.sendRequestsWithRetries(req, message); err != nil {
 				a.log.Errorf("Error sending agent request for counter metric: %v", err)
+				return
+			}
+			if metric.MType == collector.Counter {
+				mu.Lock()
+				sent = append(sent, metric)
+				mu.Unlock()
 			}
 		}(v)
 	}
 
 	wg.Wait()
-	return nil
+	return sent
 }
 
 // SetRealIPFromRequest - метод для извлечения реального IP-адреса из заголовка запроса и сохранения его.
@@ -189,9 +234,17 @@ func (a *Agent) sendRequestsWithRetries(req *resty.Request, jsonInput string) er
 	}
 
 	if err := retry.Do(func() error {
-		if _, err := req.SetBody(buf).Post(fmt.Sprintf("http://%s/update/", a.params.FlagRunAddr)); err != nil {
+		resp, err := req.SetBody(bytes.NewReader(buf.Bytes())).Post(fmt.Sprintf("http://%s/update/", a.params.FlagRunAddr))
+		if err != nil {
 			return fmt.Errorf("error while trying to create post request: %w", err)
 		}
+		if resp.StatusCode() >= http.StatusInternalServerError {
+			return fmt.Errorf("server responded with status %d", resp.StatusCode())
+		}
+		if resp.IsError() {
+			// запрос отклонен сервером, повтор того же запроса не поможет
+			return retry.Unrecoverable(fmt.Errorf("server responded with status %d", resp.StatusCode()))
+		}
 		return nil
 	}, retry.Attempts(10), retry.OnRetry(func(n uint, err error) {
 		log.Printf("Retrying request after error: %v", err)
@@ -207,7 +260,18 @@ func New(params *flags.Params, storage *metrics.Storage, log *zap.SugaredLogger)
 		params:  params,
 		storage: storage,
 		log:     log,
-		client:  resty.New(),
+		client:  resty.New().SetRetryCount(3),
+	}
+	if len(params.MetricMetadata) != 0 {
+		agent.metadata = make(map[string]*collector.MetricMetadata, len(params.MetricMetadata))
+		for _, md := range params.MetricMetadata {
+			agent.metadata[md.Name] = &collector.MetricMetadata{
+				Unit:        md.Unit,
+				Description: md.Description,
+				Type:        md.Type,
+				Owner:       md.Owner,
+			}
+		}
 	}
 	if params.CryptoKeyPath != "" {
 		b, err := os.ReadFile(params.CryptoKeyPath)
@@ -235,5 +299,6 @@ type Agent struct {
 	log               *zap.SugaredLogger
 	client            *resty.Client
 	grpcMetricsClient pb.MetricsClient
-	RealIP            string // Добавляем поле для хранения реального IP-адреса клиента
+	metadata          map[string]*collector.MetricMetadata // описания метрик из конфигурации, передаются вместе со значениями
+	RealIP            string                               // Добавляем поле для хранения реального IP-адреса клиента
 }
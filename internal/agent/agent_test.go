@@ -1,9 +1,20 @@
 package agent_test
 
 import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -15,3 +26,60 @@ func TestSetRealIPFromRequest(t *testing.T) {
 
 	assert.Equal(t, "192.168.1.1", a.RealIP, "Real IP should be set correctly")
 }
+
+func TestSendMetrics_CounterDeltas(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		totals = make(map[string]int64)
+		reject bool
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		var metric collector.MetricRequest
+		require.NoError(t, json.NewDecoder(zr).Decode(&metric))
+		mu.Lock()
+		defer mu.Unlock()
+		if reject {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if metric.Delta != nil {
+			totals[metric.ID] += *metric.Delta
+		}
+	}))
+	defer srv.Close()
+
+	a, err := agent.New(&flags.Params{FlagRunAddr: strings.TrimPrefix(srv.URL, "http://")}, nil, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	c := collector.Collector()
+	c.Restore(nil)
+	ctx := context.Background()
+
+	// первый цикл отчета
+	c.AddCounter("ReportCounter", 5)
+	require.NoError(t, a.SendMetrics(ctx))
+	// второй цикл: отправляется только прирост после первой отправки
+	c.AddCounter("ReportCounter", 3)
+	require.NoError(t, a.SendMetrics(ctx))
+	assert.Equal(t, int64(8), totals["ReportCounter"])
+
+	// отклоненный отчет не сбрасывает накопленное значение
+	c.AddCounter("ReportCounter", 2)
+	mu.Lock()
+	reject = true
+	mu.Unlock()
+	require.NoError(t, a.SendMetrics(ctx))
+	m, err := c.GetMetric("ReportCounter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), *m.CounterValue)
+
+	mu.Lock()
+	reject = false
+	mu.Unlock()
+	require.NoError(t, a.SendMetrics(ctx))
+	assert.Equal(t, int64(10), totals["ReportCounter"])
+	m, err = c.GetMetric("ReportCounter")
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), *m.CounterValue)
+}
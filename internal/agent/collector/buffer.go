@@ -0,0 +1,65 @@
+package collector
+
+import "sync"
+
+// Buffer накапливает метрики, полученные вне цикла опроса агента (скрипты, внешние источники),
+// до следующего опроса. Приращения counter суммируются, для gauge хранится последнее значение.
+// Buffer безопасен для использования из нескольких горутин.
+type Buffer struct {
+	mu      sync.Mutex
+	order   []string
+	metrics map[string]MetricRequest
+}
+
+// NewBuffer создает пустой буфер метрик.
+func NewBuffer() *Buffer {
+	return &Buffer{
+		metrics: make(map[string]MetricRequest),
+	}
+}
+
+// Add добавляет метрику в буфер.
+// Возвращает ErrBadRequest для метрики без имени или значения и для отрицательного приращения counter,
+// которое сервер отклоняет, и ErrNotImplemented для неизвестного типа.
+func (b *Buffer) Add(metric MetricRequest) error {
+	if metric.ID == "" {
+		return ErrBadRequest
+	}
+	switch metric.MType {
+	case Counter:
+		if metric.Delta == nil || *metric.Delta < 0 {
+			return ErrBadRequest
+		}
+	case Gauge:
+		if metric.Value == nil {
+			return ErrBadRequest
+		}
+	default:
+		return ErrNotImplemented
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stored, ok := b.metrics[metric.ID]
+	if !ok {
+		b.order = append(b.order, metric.ID)
+	}
+	if ok && stored.MType == Counter && metric.MType == Counter {
+		metric.Delta = PtrInt64(*stored.Delta + *metric.Delta)
+	}
+	b.metrics[metric.ID] = metric
+	return nil
+}
+
+// Drain возвращает накопленные метрики в порядке их первого появления и очищает буфер.
+func (b *Buffer) Drain() []MetricRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result := make([]MetricRequest, 0, len(b.order))
+	for _, id := range b.order {
+		result = append(result, b.metrics[id])
+	}
+	b.order = nil
+	b.metrics = make(map[string]MetricRequest)
+	return result
+}
@@ -0,0 +1,24 @@
+package collector
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestBuffer(t *testing.T) {
+	b := NewBuffer()
+	assert.NoError(t, b.Add(MetricRequest{ID: "Requests", MType: Counter, Delta: PtrInt64(2)}))
+	assert.NoError(t, b.Add(MetricRequest{ID: "Queue", MType: Gauge, Value: PtrFloat64(10)}))
+	assert.NoError(t, b.Add(MetricRequest{ID: "Requests", MType: Counter, Delta: PtrInt64(3)}))
+	assert.NoError(t, b.Add(MetricRequest{ID: "Queue", MType: Gauge, Value: PtrFloat64(7)}))
+	assert.ErrorIs(t, b.Add(MetricRequest{ID: "", MType: Gauge, Value: PtrFloat64(1)}), ErrBadRequest)
+	assert.ErrorIs(t, b.Add(MetricRequest{ID: "NoValue", MType: Counter}), ErrBadRequest)
+	assert.ErrorIs(t, b.Add(MetricRequest{ID: "Requests", MType: Counter, Delta: PtrInt64(-1)}), ErrBadRequest)
+	assert.ErrorIs(t, b.Add(MetricRequest{ID: "Text", MType: "text"}), ErrNotImplemented)
+
+	assert.Equal(t, []MetricRequest{
+		{ID: "Requests", MType: Counter, Delta: PtrInt64(5)},
+		{ID: "Queue", MType: Gauge, Value: PtrFloat64(7)},
+	}, b.Drain())
+	assert.Empty(t, b.Drain())
+}
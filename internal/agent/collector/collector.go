@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"errors"
 	"strconv"
+	"sync"
+	"time"
 )
 
 var (
@@ -13,78 +15,270 @@ var (
 	ErrNotImplemented = errors.New("not implemented")
 	// ErrNotFound  представляет ошибку для не найденных данных.
 	ErrNotFound = errors.New("not found")
+	// ErrConflict представляет ошибку для метрики, тип которой расходится с зарегистрированным.
+	ErrConflict = errors.New("conflict")
 )
 
 var metricsCollector = collector{
 	Metrics: make([]StoredMetric, 0),
 }
 
+// collectMu сериализует вызовы Collect, чтобы чтение сохраненного значения и его обновление
+// (приращение counter, операции add и sub для gauge, объединение гистограмм) выполнялись атомарно.
+// Методы чтения захватывают его на чтение, так как Expire и Restore заменяют список метрик целиком.
+var collectMu sync.RWMutex
+
 func Collector() *collector {
 	return &metricsCollector
 }
 
 // Collect - метод добавления метрики из MetricRequest.
+// Counter принимает только неотрицательные приращения, gauge - любые значения.
+// Для gauge операция metric.Op задает, устанавливается ли значение (set), прибавляется (add) или вычитается (sub).
+// Метрика, тип которой расходится с зарегистрированным описанием, отклоняется с ErrConflict;
+// описание из metric.Metadata регистрируется после сохранения значения.
+// Лимиты, заданные в SetLimits, проверяются только как глобальные; см. CollectFrom.
 func (c *collector) Collect(metric MetricRequest, metricValue string) error {
-	if (metric.Delta != nil && *metric.Delta < 0) || (metric.Value != nil && *metric.Value < 0) || metric.ID == "" {
+	return c.CollectFrom("", metric, metricValue)
+}
+
+// validateRequest проверяет запрос до захвата collectMu.
+// Метрики с префиксом ReservedPrefix записывает только сам сервер.
+func validateRequest(metric MetricRequest) error {
+	if (metric.Delta != nil && *metric.Delta < 0) || metric.ID == "" {
+		return ErrBadRequest
+	}
+	if metric.Op != "" && metric.MType != Gauge {
+		return ErrBadRequest
+	}
+	if IsReserved(metric.ID) {
 		return ErrBadRequest
 	}
 
+	if metric.Metadata != nil && metric.Metadata.Type != "" && metric.Metadata.Type != metric.MType {
+		return ErrConflict
+	}
+	return nil
+}
+
+// prepare вычисляет новое значение метрики по запросу и ее текущему значению prev без записи,
+// вызывается под collectMu. Ошибка запроса обнаруживается до записи, поэтому пакет метрик
+// записывается целиком или не записывается совсем.
+func prepare(metric MetricRequest, metricValue string, prev StoredMetric) (StoredMetric, error) {
+	if err := checkRegisteredType(metric.ID, metric.MType); err != nil {
+		return StoredMetric{}, err
+	}
+
 	switch metric.MType {
 	case Counter:
-		v, err := c.GetMetric(metric.ID)
-		if err != nil {
-			if !errors.Is(err, ErrNotFound) {
-				return err
-			}
-		}
 		value, err := strconv.Atoi(metricValue)
-		if err != nil {
-			return ErrBadRequest
+		if err != nil || value < 0 {
+			return StoredMetric{}, ErrBadRequest
 		}
-		if v.CounterValue != nil {
-			value = value + int(*v.CounterValue)
+		if prev.CounterValue != nil {
+			value = value + int(*prev.CounterValue)
 		}
-		c.UpsertMetric(StoredMetric{
+		return StoredMetric{
 			ID:           metric.ID,
 			MType:        metric.MType,
 			CounterValue: PtrInt64(int64(value)),
 			TextValue:    PtrString(strconv.Itoa(value)),
-		})
+		}, nil
 	case Gauge:
 		value, err := strconv.ParseFloat(metricValue, 64)
 		if err != nil {
-			return ErrBadRequest
+			return StoredMetric{}, ErrBadRequest
 		}
-		c.UpsertMetric(StoredMetric{
+		switch metric.Op {
+		case "", GaugeSet:
+		case GaugeAdd, GaugeSub:
+			if metric.Op == GaugeSub {
+				value = -value
+			}
+			if prev.MType == Gauge && prev.GaugeValue != nil {
+				value += *prev.GaugeValue
+			}
+		default:
+			return StoredMetric{}, ErrBadRequest
+		}
+		return StoredMetric{
 			ID:         metric.ID,
 			MType:      metric.MType,
 			GaugeValue: &value,
-			TextValue:  &metricValue,
-		})
-	default:
-		return ErrNotImplemented
+			TextValue:  PtrString(FormatGauge(value)),
+		}, nil
+	case Histogram:
+		var value *HistogramValue
+		if prev.MType == Histogram && prev.HistogramValue != nil {
+			value = prev.HistogramValue.Clone()
+		}
+		if metric.Histogram != nil {
+			// гистограмма целиком: объединяется с сохраненной
+			if err := metric.Histogram.Validate(); err != nil {
+				return StoredMetric{}, err
+			}
+			if value == nil {
+				value = metric.Histogram.Clone()
+			} else if err := value.Merge(metric.Histogram); err != nil {
+				return StoredMetric{}, err
+			}
+		} else {
+			// одно наблюдение: добавляется в сохраненную гистограмму или в новую с корзинами по умолчанию
+			observation, err := strconv.ParseFloat(metricValue, 64)
+			if err != nil {
+				return StoredMetric{}, ErrBadRequest
+			}
+			if value == nil {
+				value = NewHistogram(DefaultBuckets)
+			}
+			if err = value.Observe(observation); err != nil {
+				return StoredMetric{}, err
+			}
+		}
+		return StoredMetric{
+			ID:             metric.ID,
+			MType:          metric.MType,
+			HistogramValue: value,
+			TextValue:      PtrString(value.String()),
+		}, nil
+	}
+	return StoredMetric{}, ErrNotImplemented
+}
+
+// apply записывает значение m, вычисленное prepare для запроса metric, вызывается под collectMu.
+func (c *collector) apply(metric MetricRequest, m StoredMetric, now time.Time) error {
+	c.upsertMetric(m)
+	switch {
+	case m.CounterValue != nil:
+		recordSample(m.ID, float64(*m.CounterValue), now)
+	case m.GaugeValue != nil:
+		recordSample(m.ID, *m.GaugeValue, now)
+	}
+	touch(m.ID)
+	if metric.Metadata != nil {
+		md := *metric.Metadata
+		md.ID = metric.ID
+		md.Type = metric.MType
+		return c.registerMetadata(md)
 	}
 	return nil
 }
 
+// GetQuantile возвращает оценку квантиля q метрики типа histogram.
+// Возвращает ErrNotFound, если метрика не найдена или не является гистограммой.
+func (c *collector) GetQuantile(metricName string, q float64) (float64, error) {
+	m, err := c.GetMetric(metricName)
+	if err != nil {
+		return 0, err
+	}
+	if m.MType != Histogram || m.HistogramValue == nil {
+		return 0, ErrNotFound
+	}
+	return m.HistogramValue.Quantile(q)
+}
+
+// DeleteMetric удаляет метрику заданного типа; удаление попадает в хранилище при следующем сохранении.
+// Возвращает ErrNotFound, если метрики с таким именем и типом нет.
+func (c *collector) DeleteMetric(metricName, mtype string) error {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	for i, m := range c.Metrics {
+		if m.ID == metricName && m.MType == mtype {
+			c.Metrics = append(c.Metrics[:i:i], c.Metrics[i+1:]...)
+			forgetSeries(metricName)
+			tombstones[metricName] = struct{}{}
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// ResetMetric сбрасывает значение метрики заданного типа: counter и gauge обнуляются,
+// у histogram обнуляются наблюдения с сохранением корзин.
+// Возвращает ErrNotFound, если метрики с таким именем и типом нет.
+func (c *collector) ResetMetric(metricName, mtype string) error {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	m, err := c.getMetric(metricName)
+	if err != nil {
+		return err
+	}
+	if m.MType != mtype {
+		return ErrNotFound
+	}
+	reset := StoredMetric{ID: m.ID, MType: m.MType}
+	switch m.MType {
+	case Counter:
+		reset.CounterValue = PtrInt64(0)
+		reset.TextValue = PtrString("0")
+		recordSample(metricName, 0, time.Now())
+	case Gauge:
+		reset.GaugeValue = PtrFloat64(0)
+		reset.TextValue = PtrString("0")
+		recordSample(metricName, 0, time.Now())
+	case Histogram:
+		if m.HistogramValue != nil && m.HistogramValue.Schema != nil {
+			reset.HistogramValue = NewExponentialHistogram(*m.HistogramValue.Schema)
+		} else if m.HistogramValue != nil {
+			reset.HistogramValue = NewHistogram(m.HistogramValue.Bounds)
+		} else {
+			reset.HistogramValue = NewHistogram(DefaultBuckets)
+		}
+		reset.TextValue = PtrString(reset.HistogramValue.String())
+	}
+	c.upsertMetric(reset)
+	touch(metricName)
+	return nil
+}
+
+// SubtractCounters уменьшает счетчики на отправленные значения sent.
+// Агент вызывает его после успешной отправки, чтобы следующий отчет содержал только прирост,
+// накопленный после снимка для отправки.
+func (c *collector) SubtractCounters(sent []StoredMetric) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	for _, s := range sent {
+		m, err := c.getMetric(s.ID)
+		if err != nil || m.MType != Counter || m.CounterValue == nil || s.CounterValue == nil {
+			continue
+		}
+		value := *m.CounterValue - *s.CounterValue
+		if value < 0 {
+			value = 0
+		}
+		c.upsertMetric(StoredMetric{
+			ID:           m.ID,
+			MType:        Counter,
+			CounterValue: PtrInt64(value),
+			TextValue:    PtrString(strconv.FormatInt(value, 10)),
+		})
+	}
+}
+
 // GetMetricJSON - метод для получения значения метрики по имени метрики.
 // Returns the JSON.
 func (c *collector) GetMetricJSON(metricName string) ([]byte, error) {
-	for _, m := range c.Metrics {
-		if m.ID == metricName {
-			resultJSON, err := json.Marshal(m)
-			if err != nil {
-				return nil, ErrBadRequest
-			}
-			return resultJSON, nil
-		}
+	m, err := c.GetMetric(metricName)
+	if err != nil {
+		return nil, err
+	}
+	resultJSON, err := json.Marshal(m)
+	if err != nil {
+		return nil, ErrBadRequest
 	}
-	return nil, ErrNotFound
+	return resultJSON, nil
 }
 
 // GetMetric возвращает значение заданной метрики по имени метрики
 // Returns the struct of type StoredMetric
 func (c *collector) GetMetric(metricName string) (StoredMetric, error) {
+	collectMu.RLock()
+	defer collectMu.RUnlock()
+	return c.getMetric(metricName)
+}
+
+// getMetric возвращает значение метрики, вызывается под collectMu.
+func (c *collector) getMetric(metricName string) (StoredMetric, error) {
 	for _, m := range c.Metrics {
 		if m.ID == metricName {
 			return m, nil
@@ -96,6 +290,8 @@ func (c *collector) GetMetric(metricName string) (StoredMetric, error) {
 // GetAvailableMetrics Метод возвращает слайс со всеми доступными метриками.
 // Внутри метода перебираются элементы счетчиков и показателей в объекте "metrics" и добавляются в срез.
 func (c *collector) GetAvailableMetrics() []string {
+	collectMu.RLock()
+	defer collectMu.RUnlock()
 	names := make([]string, 0, len(c.Metrics))
 	for _, m := range c.Metrics {
 		names = append(names, m.ID)
@@ -105,6 +301,13 @@ func (c *collector) GetAvailableMetrics() []string {
 
 // UpsertMetric добавляет или обновляет метрику в коллекторе.
 func (c *collector) UpsertMetric(metric StoredMetric) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	c.upsertMetric(metric)
+}
+
+// upsertMetric добавляет или обновляет метрику, вызывается под collectMu.
+func (c *collector) upsertMetric(metric StoredMetric) {
 	for i, m := range c.Metrics {
 		if m.ID == metric.ID {
 			c.Metrics[i] = metric
@@ -114,6 +317,22 @@ func (c *collector) UpsertMetric(metric StoredMetric) {
 	c.Metrics = append(c.Metrics, metric)
 }
 
+// Restore заменяет список метрик восстановленным из хранилища.
+// Метрикам без текстового значения оно задается по сохраненному значению.
+func (c *collector) Restore(metrics []StoredMetric) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	if metrics == nil {
+		metrics = make([]StoredMetric, 0)
+	}
+	for i := range metrics {
+		if metrics[i].TextValue == nil {
+			metrics[i].TextValue = PtrString(metrics[i].Text())
+		}
+	}
+	c.Metrics = metrics
+}
+
 // PtrFloat64 создает указатель на float64 с заданным значением.
 func PtrFloat64(f float64) *float64 {
 	return &f
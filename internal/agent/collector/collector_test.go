@@ -169,6 +169,110 @@ func TestCollector_Collect(t *testing.T) {
 			},
 			expectedError: ErrBadRequest,
 		},
+		{
+			name: "histogram merge",
+			storage: collector{[]StoredMetric{
+				{
+					ID:             "Latency",
+					MType:          "histogram",
+					HistogramValue: &HistogramValue{Count: 1, Sum: 0.5, Bounds: []float64{1, 2}, Buckets: []uint64{1, 0, 0}},
+				},
+			}},
+			request: MetricRequest{
+				ID:        "Latency",
+				MType:     "histogram",
+				Histogram: &HistogramValue{Count: 2, Sum: 4.5, Bounds: []float64{1, 2}, Buckets: []uint64{0, 1, 1}},
+			},
+			expected: []StoredMetric{
+				{
+					ID:             "Latency",
+					MType:          "histogram",
+					HistogramValue: &HistogramValue{Count: 3, Sum: 5, Bounds: []float64{1, 2}, Buckets: []uint64{1, 1, 1}},
+					TextValue:      PtrString(`{"count":3,"sum":5,"bounds":[1,2],"buckets":[1,1,1]}`),
+				},
+			},
+		},
+		{
+			name:        "histogram observation",
+			storage:     collector{[]StoredMetric{}},
+			request:     MetricRequest{ID: "Latency", MType: "histogram"},
+			metricValue: "0.3",
+			expected: []StoredMetric{
+				{
+					ID:             "Latency",
+					MType:          "histogram",
+					HistogramValue: &HistogramValue{Count: 1, Sum: 0.3, Bounds: DefaultBuckets, Buckets: []uint64{0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0}},
+					TextValue:      PtrString(`{"count":1,"sum":0.3,"bounds":[0.005,0.01,0.025,0.05,0.1,0.25,0.5,1,2.5,5,10],"buckets":[0,0,0,0,0,0,1,0,0,0,0,0]}`),
+				},
+			},
+		},
+		{
+			name: "histogram with other buckets",
+			storage: collector{[]StoredMetric{
+				{
+					ID:             "Latency",
+					MType:          "histogram",
+					HistogramValue: &HistogramValue{Count: 1, Sum: 0.5, Bounds: []float64{1, 2}, Buckets: []uint64{1, 0, 0}},
+				},
+			}},
+			request: MetricRequest{
+				ID:        "Latency",
+				MType:     "histogram",
+				Histogram: &HistogramValue{Count: 1, Sum: 0.5, Bounds: []float64{1}, Buckets: []uint64{1, 0}},
+			},
+			expected: []StoredMetric{
+				{
+					ID:             "Latency",
+					MType:          "histogram",
+					HistogramValue: &HistogramValue{Count: 1, Sum: 0.5, Bounds: []float64{1, 2}, Buckets: []uint64{1, 0, 0}},
+				},
+			},
+			expectedError: ErrBadRequest,
+		},
+		{
+			name:    "gauge set in canonical form",
+			storage: collector{[]StoredMetric{}},
+			request: MetricRequest{
+				ID:    "Load",
+				MType: "gauge",
+				Value: PtrFloat64(1.5),
+			},
+			metricValue: "1.50",
+			expected: []StoredMetric{
+				{
+					ID:         "Load",
+					MType:      "gauge",
+					GaugeValue: PtrFloat64(1.5),
+					TextValue:  PtrString("1.5"),
+				},
+			},
+		},
+		{
+			name: "gauge add in the same form as set",
+			storage: collector{[]StoredMetric{
+				{
+					ID:         "Load",
+					MType:      "gauge",
+					GaugeValue: PtrFloat64(1),
+					TextValue:  PtrString("1"),
+				},
+			}},
+			request: MetricRequest{
+				ID:    "Load",
+				MType: "gauge",
+				Value: PtrFloat64(0.5),
+				Op:    GaugeAdd,
+			},
+			metricValue: "0.50",
+			expected: []StoredMetric{
+				{
+					ID:         "Load",
+					MType:      "gauge",
+					GaugeValue: PtrFloat64(1.5),
+					TextValue:  PtrString("1.5"),
+				},
+			},
+		},
 	}
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -308,3 +412,28 @@ func TestCollector_GetMetricJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestCollector_Restore(t *testing.T) {
+	histogram := NewHistogram([]float64{1})
+	testCases := []struct {
+		name         string
+		metric       StoredMetric
+		expectedText string
+	}{
+		{name: "counter", metric: StoredMetric{ID: "c", MType: Counter, CounterValue: PtrInt64(42)}, expectedText: "42"},
+		{name: "gauge", metric: StoredMetric{ID: "g", MType: Gauge, GaugeValue: PtrFloat64(0.25)}, expectedText: "0.25"},
+		{name: "histogram", metric: StoredMetric{ID: "h", MType: Histogram, HistogramValue: histogram}, expectedText: histogram.String()},
+		{name: "text kept", metric: StoredMetric{ID: "t", MType: Gauge, GaugeValue: PtrFloat64(1), TextValue: PtrString("1.0")}, expectedText: "1.0"},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			c := collector{}
+			c.Restore([]StoredMetric{tt.metric})
+			metric, err := c.GetMetric(tt.metric.ID)
+			assert.NoError(t, err)
+			if assert.NotNil(t, metric.TextValue) {
+				assert.Equal(t, tt.expectedText, *metric.TextValue)
+			}
+		})
+	}
+}
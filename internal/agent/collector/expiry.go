@@ -0,0 +1,165 @@
+package collector
+
+import (
+	"sort"
+	"time"
+)
+
+var (
+	// lastUpdated хранит время последнего обновления каждой метрики, доступ защищен collectMu.
+	// Метрики, восстановленные из хранилища, получают отметку при первой проверке устаревания.
+	lastUpdated = make(map[string]time.Time)
+	// agentLastSeen хранит время последней записи от каждого агента, доступ защищен collectMu.
+	agentLastSeen = make(map[string]time.Time)
+	// tombstones - имена удаленных устаревших метрик, которые еще нужно удалить из хранилища.
+	tombstones = make(map[string]struct{})
+	// staleAfter и deleteAfter - время без обновлений, после которого метрика считается устаревшей
+	// и удаляется; нулевое значение отключает соответствующий этап.
+	staleAfter, deleteAfter time.Duration
+)
+
+// SetExpiry задает время без обновлений, после которого метрика считается устаревшей (stale)
+// и после которого она удаляется. Нулевое значение отключает соответствующий этап.
+func (c *collector) SetExpiry(stale, expire time.Duration) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	staleAfter, deleteAfter = stale, expire
+}
+
+// IsStale сообщает, не обновлялась ли метрика дольше заданного в SetExpiry времени.
+func (c *collector) IsStale(metricName string) bool {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	updated, ok := lastUpdated[metricName]
+	return ok && staleAfter > 0 && time.Since(updated) > staleAfter
+}
+
+// Expire удаляет метрики, не обновлявшиеся дольше заданного в SetExpiry времени удаления,
+// и запоминает их имена до вызова DrainTombstones. Возвращает имена удаленных метрик.
+func (c *collector) Expire(now time.Time) []string {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	var deleted []string
+	kept := make([]StoredMetric, 0, len(c.Metrics))
+	for _, m := range c.Metrics {
+		updated, ok := lastUpdated[m.ID]
+		if !ok {
+			lastUpdated[m.ID] = now
+			updated = now
+		}
+		if deleteAfter > 0 && now.Sub(updated) > deleteAfter {
+			forgetSeries(m.ID)
+			tombstones[m.ID] = struct{}{}
+			deleted = append(deleted, m.ID)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	c.Metrics = kept
+	return deleted
+}
+
+// DrainTombstones возвращает имена удаленных метрик, которые нужно удалить из хранилища, и очищает список.
+func (c *collector) DrainTombstones() []string {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	ids := make([]string, 0, len(tombstones))
+	for id := range tombstones {
+		ids = append(ids, id)
+	}
+	tombstones = make(map[string]struct{})
+	return ids
+}
+
+// RestoreTombstones возвращает в список имена метрик, удаление которых из хранилища не удалось,
+// чтобы повторить его при следующем сохранении. Метрики, записанные заново после удаления, пропускаются.
+func (c *collector) RestoreTombstones(ids []string) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	for _, id := range ids {
+		if _, err := c.getMetric(id); err != nil {
+			tombstones[id] = struct{}{}
+		}
+	}
+}
+
+// touch отмечает обновление метрики и отменяет ее удаление из хранилища, вызывается под collectMu.
+func touch(metricName string) {
+	lastUpdated[metricName] = time.Now()
+	delete(tombstones, metricName)
+}
+
+// touchAgent отмечает запись от агента, вызывается под collectMu.
+func touchAgent(agent string) {
+	agentLastSeen[agent] = time.Now()
+}
+
+// LastSeenState - время последнего обновления метрик и последней записи от агентов.
+// Сохраняется в хранилище, чтобы проверки устаревания и оповещения об отсутствии данных
+// не сбрасывались при перезапуске сервера.
+type LastSeenState struct {
+	Metrics map[string]time.Time `json:"metrics"` // время последнего обновления метрик
+	Agents  map[string]time.Time `json:"agents"`  // время последней записи от агентов
+}
+
+// LastSeen возвращает копию времени последнего обновления метрик и последней записи от агентов.
+func (c *collector) LastSeen() LastSeenState {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	state := LastSeenState{
+		Metrics: make(map[string]time.Time, len(lastUpdated)),
+		Agents:  make(map[string]time.Time, len(agentLastSeen)),
+	}
+	for name, t := range lastUpdated {
+		state.Metrics[name] = t
+	}
+	for agent, t := range agentLastSeen {
+		state.Agents[agent] = t
+	}
+	return state
+}
+
+// RestoreLastSeen восстанавливает время последнего обновления метрик и записи от агентов.
+// Отметки, появившиеся после запуска сервера, не перезаписываются.
+func (c *collector) RestoreLastSeen(state LastSeenState) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	for name, t := range state.Metrics {
+		if _, ok := lastUpdated[name]; !ok {
+			lastUpdated[name] = t
+		}
+	}
+	for agent, t := range state.Agents {
+		if _, ok := agentLastSeen[agent]; !ok {
+			agentLastSeen[agent] = t
+		}
+	}
+}
+
+// MetricLastSeen возвращает время последнего обновления метрики.
+func (c *collector) MetricLastSeen(metricName string) (time.Time, bool) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	t, ok := lastUpdated[metricName]
+	return t, ok
+}
+
+// AgentLastSeen возвращает время последней записи от агента.
+func (c *collector) AgentLastSeen(agent string) (time.Time, bool) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	t, ok := agentLastSeen[agent]
+	return t, ok
+}
+
+// Agents возвращает отсортированный список агентов, от которых были записи.
+func (c *collector) Agents() []string {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	agents := make([]string, 0, len(agentLastSeen))
+	for agent := range agentLastSeen {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+	return agents
+}
@@ -0,0 +1,73 @@
+package collector
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCollector_Expire(t *testing.T) {
+	c := collector{[]StoredMetric{
+		{ID: "RestoredGauge", MType: "gauge", GaugeValue: PtrFloat64(1), TextValue: PtrString("1")},
+	}}
+	c.SetExpiry(time.Minute, time.Hour)
+	defer c.SetExpiry(0, 0)
+
+	assert.NoError(t, c.Collect(MetricRequest{ID: "ExpiringCounter", MType: "counter", Delta: PtrInt64(1)}, "1"))
+	assert.False(t, c.IsStale("ExpiringCounter"))
+
+	// восстановленная метрика получает отметку времени при первой проверке
+	now := time.Now()
+	assert.Empty(t, c.Expire(now))
+	assert.Len(t, c.Metrics, 2)
+
+	collectMu.Lock()
+	lastUpdated["ExpiringCounter"] = now.Add(-2 * time.Minute)
+	collectMu.Unlock()
+	assert.True(t, c.IsStale("ExpiringCounter"))
+	assert.False(t, c.IsStale("RestoredGauge"))
+
+	collectMu.Lock()
+	lastUpdated["ExpiringCounter"] = now.Add(-2 * time.Hour)
+	collectMu.Unlock()
+	assert.Equal(t, []string{"ExpiringCounter"}, c.Expire(now))
+	assert.Equal(t, []StoredMetric{
+		{ID: "RestoredGauge", MType: "gauge", GaugeValue: PtrFloat64(1), TextValue: PtrString("1")},
+	}, c.Metrics)
+	assert.Equal(t, []string{"ExpiringCounter"}, c.DrainTombstones())
+	assert.Empty(t, c.DrainTombstones())
+
+	// метрика, обновленная после удаления, не удаляется из хранилища
+	assert.Equal(t, []string{"RestoredGauge"}, c.Expire(now.Add(2*time.Hour)))
+	assert.NoError(t, c.Collect(MetricRequest{ID: "RestoredGauge", MType: "gauge", Value: PtrFloat64(2)}, "2"))
+	assert.Empty(t, c.DrainTombstones())
+	assert.False(t, c.IsStale("RestoredGauge"))
+}
+
+func TestCollector_LastSeen(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	assert.NoError(t, c.CollectFrom("10.1.1.1", MetricRequest{ID: "SeenGauge", MType: "gauge", Value: PtrFloat64(1)}, "1"))
+
+	state := c.LastSeen()
+	assert.Contains(t, state.Metrics, "SeenGauge")
+	assert.Contains(t, state.Agents, "10.1.1.1")
+	assert.Contains(t, c.Agents(), "10.1.1.1")
+
+	// восстановленные отметки не перезаписывают отметки, появившиеся после запуска
+	old := time.Now().Add(-time.Hour)
+	c.RestoreLastSeen(LastSeenState{
+		Metrics: map[string]time.Time{"SeenGauge": old, "RestoredSeen": old},
+		Agents:  map[string]time.Time{"10.1.1.1": old, "10.1.1.2": old},
+	})
+	seen, ok := c.MetricLastSeen("SeenGauge")
+	assert.True(t, ok)
+	assert.True(t, seen.After(old))
+	seen, ok = c.MetricLastSeen("RestoredSeen")
+	assert.True(t, ok)
+	assert.Equal(t, old, seen)
+	seen, ok = c.AgentLastSeen("10.1.1.2")
+	assert.True(t, ok)
+	assert.Equal(t, old, seen)
+	_, ok = c.AgentLastSeen("10.1.1.3")
+	assert.False(t, ok)
+}
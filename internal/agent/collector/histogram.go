@@ -0,0 +1,297 @@
+package collector
+
+import (
+	"encoding/json"
+	"math"
+	"slices"
+	"sort"
+)
+
+// DefaultBuckets - границы корзин гистограммы по умолчанию, используются при первом наблюдении,
+// переданном без описания корзин (например, через URL).
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+const (
+	// MinSchema - минимальная схема экспоненциальной гистограммы (основание 2^16)
+	MinSchema = -4
+	// MaxSchema - максимальная схема экспоненциальной гистограммы (основание 2^(1/256))
+	MaxSchema = 8
+)
+
+// HistogramValue - значение метрики типа histogram: количество и сумма наблюдений и счетчики корзин.
+//
+// Корзины задаются одним из двух способов:
+//   - явными границами Bounds: корзина Buckets[i] содержит значения из (Bounds[i-1], Bounds[i]],
+//     последняя корзина Buckets[len(Bounds)] - значения больше последней границы;
+//   - экспоненциально со схемой Schema: корзина Positive[i] содержит значения из (base^(i-1), base^i],
+//     где base = 2^(2^-Schema), нулевые значения учитываются в ZeroCount. Отрицательные значения
+//     экспоненциальная гистограмма не принимает.
+//
+// Счетчики корзин не накопительные: сумма всех корзин равна Count.
+type HistogramValue struct {
+	Count     uint64           `json:"count"`                // количество наблюдений
+	Sum       float64          `json:"sum"`                  // сумма наблюдений
+	Bounds    []float64        `json:"bounds,omitempty"`     // верхние границы явных корзин по возрастанию
+	Buckets   []uint64         `json:"buckets,omitempty"`    // счетчики явных корзин, на одну больше, чем границ
+	Schema    *int32           `json:"schema,omitempty"`     // схема экспоненциальных корзин
+	ZeroCount uint64           `json:"zero_count,omitempty"` // количество нулевых наблюдений экспоненциальной гистограммы
+	Positive  map[int32]uint64 `json:"positive,omitempty"`   // счетчики экспоненциальных корзин по индексу
+}
+
+// NewHistogram создает пустую гистограмму с явными границами корзин.
+func NewHistogram(bounds []float64) *HistogramValue {
+	return &HistogramValue{
+		Bounds:  slices.Clone(bounds),
+		Buckets: make([]uint64, len(bounds)+1),
+	}
+}
+
+// NewExponentialHistogram создает пустую гистограмму с экспоненциальными корзинами.
+func NewExponentialHistogram(schema int32) *HistogramValue {
+	return &HistogramValue{
+		Schema:   &schema,
+		Positive: make(map[int32]uint64),
+	}
+}
+
+// Validate проверяет согласованность гистограммы.
+// Возвращает ErrBadRequest, если корзины не заданы, заданы обоими способами или не сходятся с Count.
+func (h *HistogramValue) Validate() error {
+	var total uint64
+	switch {
+	case h.Schema != nil:
+		if len(h.Bounds) != 0 || len(h.Buckets) != 0 || *h.Schema < MinSchema || *h.Schema > MaxSchema {
+			return ErrBadRequest
+		}
+		total = h.ZeroCount
+		for _, n := range h.Positive {
+			total += n
+		}
+	case len(h.Buckets) != 0:
+		if len(h.Bounds) == 0 || len(h.Buckets) != len(h.Bounds)+1 || h.ZeroCount != 0 || len(h.Positive) != 0 {
+			return ErrBadRequest
+		}
+		for i, b := range h.Bounds {
+			if math.IsNaN(b) || math.IsInf(b, 0) || (i > 0 && b <= h.Bounds[i-1]) {
+				return ErrBadRequest
+			}
+		}
+		for _, n := range h.Buckets {
+			total += n
+		}
+	default:
+		return ErrBadRequest
+	}
+	if total != h.Count || math.IsNaN(h.Sum) {
+		return ErrBadRequest
+	}
+	return nil
+}
+
+// Observe добавляет в гистограмму одно наблюдение.
+func (h *HistogramValue) Observe(v float64) error {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return ErrBadRequest
+	}
+	if h.Schema != nil {
+		switch {
+		case v < 0:
+			return ErrBadRequest
+		case v == 0:
+			h.ZeroCount++
+		default:
+			if h.Positive == nil {
+				h.Positive = make(map[int32]uint64)
+			}
+			h.Positive[exponentialIndex(v, *h.Schema)]++
+		}
+	} else {
+		h.Buckets[sort.SearchFloat64s(h.Bounds, v)]++
+	}
+	h.Count++
+	h.Sum += v
+	return nil
+}
+
+// Merge добавляет к гистограмме наблюдения гистограммы other.
+// Явные корзины объединяются только при совпадении границ, экспоненциальные приводятся к меньшей схеме.
+// Возвращает ErrBadRequest, если корзины несовместимы.
+func (h *HistogramValue) Merge(other *HistogramValue) error {
+	switch {
+	case h.Schema != nil && other.Schema != nil:
+		schema := min(*h.Schema, *other.Schema)
+		h.downscale(schema)
+		if h.Positive == nil {
+			h.Positive = make(map[int32]uint64)
+		}
+		shift := *other.Schema - schema
+		for i, n := range other.Positive {
+			h.Positive[downscaleIndex(i, shift)] += n
+		}
+		h.ZeroCount += other.ZeroCount
+	case h.Schema == nil && other.Schema == nil:
+		if !slices.Equal(h.Bounds, other.Bounds) {
+			return ErrBadRequest
+		}
+		for i, n := range other.Buckets {
+			h.Buckets[i] += n
+		}
+	default:
+		return ErrBadRequest
+	}
+	h.Count += other.Count
+	h.Sum += other.Sum
+	return nil
+}
+
+// Quantile оценивает квантиль q (от 0 до 1) линейной интерполяцией внутри корзины.
+// Для значений, попавших в последнюю явную корзину, возвращается последняя граница.
+// Возвращает ErrBadRequest для q вне диапазона и ErrNotFound для гистограммы без наблюдений.
+func (h *HistogramValue) Quantile(q float64) (float64, error) {
+	if math.IsNaN(q) || q < 0 || q > 1 {
+		return 0, ErrBadRequest
+	}
+	if h.Count == 0 {
+		return 0, ErrNotFound
+	}
+	rank := q * float64(h.Count)
+	if h.Schema != nil {
+		return h.exponentialQuantile(rank), nil
+	}
+
+	var cumulative uint64
+	for i, n := range h.Buckets {
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		if i == len(h.Bounds) {
+			return h.Bounds[len(h.Bounds)-1], nil
+		}
+		upper := h.Bounds[i]
+		lower := 0.0
+		switch {
+		case i > 0:
+			lower = h.Bounds[i-1]
+		case upper <= 0:
+			return upper, nil
+		}
+		return interpolate(lower, upper, rank-float64(cumulative), n), nil
+	}
+	return h.Bounds[len(h.Bounds)-1], nil
+}
+
+// Clone возвращает копию гистограммы, не разделяющую с ней память.
+func (h *HistogramValue) Clone() *HistogramValue {
+	c := *h
+	c.Bounds = slices.Clone(h.Bounds)
+	c.Buckets = slices.Clone(h.Buckets)
+	if h.Schema != nil {
+		schema := *h.Schema
+		c.Schema = &schema
+	}
+	if h.Positive != nil {
+		c.Positive = make(map[int32]uint64, len(h.Positive))
+		for i, n := range h.Positive {
+			c.Positive[i] = n
+		}
+	}
+	return &c
+}
+
+// CumulativeBuckets возвращает верхние границы корзин по возрастанию и накопительные счетчики
+// наблюдений, не превышающих каждую границу, как в формате Prometheus. Последняя граница - +Inf.
+func (h *HistogramValue) CumulativeBuckets() ([]float64, []uint64) {
+	var bounds []float64
+	var counts []uint64
+	var total uint64
+	if h.Schema == nil {
+		for i, bound := range h.Bounds {
+			total += h.Buckets[i]
+			bounds = append(bounds, bound)
+			counts = append(counts, total)
+		}
+	} else {
+		if h.ZeroCount != 0 {
+			total = h.ZeroCount
+			bounds = append(bounds, 0)
+			counts = append(counts, total)
+		}
+		indexes := make([]int32, 0, len(h.Positive))
+		for i := range h.Positive {
+			indexes = append(indexes, i)
+		}
+		slices.Sort(indexes)
+		for _, i := range indexes {
+			total += h.Positive[i]
+			bounds = append(bounds, exponentialBound(i, *h.Schema))
+			counts = append(counts, total)
+		}
+	}
+	return append(bounds, math.Inf(1)), append(counts, h.Count)
+}
+
+// String возвращает гистограмму в формате JSON, используется как текстовое значение метрики.
+func (h *HistogramValue) String() string {
+	data, err := json.Marshal(h)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// exponentialQuantile оценивает квантиль экспоненциальной гистограммы по рангу наблюдения.
+func (h *HistogramValue) exponentialQuantile(rank float64) float64 {
+	if rank <= float64(h.ZeroCount) {
+		return 0
+	}
+	cumulative := h.ZeroCount
+	indexes := make([]int32, 0, len(h.Positive))
+	for i := range h.Positive {
+		indexes = append(indexes, i)
+	}
+	slices.Sort(indexes)
+	for _, i := range indexes {
+		n := h.Positive[i]
+		if n == 0 || float64(cumulative+n) < rank {
+			cumulative += n
+			continue
+		}
+		return interpolate(exponentialBound(i-1, *h.Schema), exponentialBound(i, *h.Schema), rank-float64(cumulative), n)
+	}
+	return exponentialBound(indexes[len(indexes)-1], *h.Schema)
+}
+
+// downscale приводит экспоненциальные корзины к меньшей схеме, объединяя соседние корзины.
+func (h *HistogramValue) downscale(schema int32) {
+	shift := *h.Schema - schema
+	if shift <= 0 {
+		return
+	}
+	positive := make(map[int32]uint64, len(h.Positive))
+	for i, n := range h.Positive {
+		positive[downscaleIndex(i, shift)] += n
+	}
+	h.Positive = positive
+	h.Schema = &schema
+}
+
+// exponentialIndex возвращает индекс экспоненциальной корзины для положительного значения.
+func exponentialIndex(v float64, schema int32) int32 {
+	return int32(math.Ceil(math.Log2(v) * math.Exp2(float64(schema))))
+}
+
+// exponentialBound возвращает верхнюю границу экспоненциальной корзины с индексом i.
+func exponentialBound(i int32, schema int32) float64 {
+	return math.Exp2(float64(i) * math.Exp2(-float64(schema)))
+}
+
+// interpolate возвращает значение внутри корзины (lower, upper] для ранга rank из count наблюдений корзины.
+func interpolate(lower, upper, rank float64, count uint64) float64 {
+	return lower + (upper-lower)*rank/float64(count)
+}
+
+// downscaleIndex возвращает индекс корзины, в которую попадает корзина i при уменьшении схемы на shift.
+func downscaleIndex(i, shift int32) int32 {
+	return (i + 1<<shift - 1) >> shift
+}
@@ -0,0 +1,104 @@
+package collector
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math"
+	"testing"
+)
+
+func ptrInt32(i int32) *int32 {
+	return &i
+}
+
+func TestHistogramValue_Validate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		histogram     HistogramValue
+		expectedError error
+	}{
+		{name: "explicit", histogram: HistogramValue{Count: 3, Sum: 4, Bounds: []float64{1, 2}, Buckets: []uint64{1, 1, 1}}},
+		{name: "exponential", histogram: HistogramValue{Count: 3, Sum: 4, Schema: ptrInt32(0), ZeroCount: 1, Positive: map[int32]uint64{1: 2}}},
+		{name: "negative: no buckets", histogram: HistogramValue{Count: 1, Sum: 1}, expectedError: ErrBadRequest},
+		{name: "negative: buckets count", histogram: HistogramValue{Count: 2, Bounds: []float64{1, 2}, Buckets: []uint64{1, 1}}, expectedError: ErrBadRequest},
+		{name: "negative: bounds order", histogram: HistogramValue{Count: 3, Bounds: []float64{2, 1}, Buckets: []uint64{1, 1, 1}}, expectedError: ErrBadRequest},
+		{name: "negative: count mismatch", histogram: HistogramValue{Count: 5, Bounds: []float64{1, 2}, Buckets: []uint64{1, 1, 1}}, expectedError: ErrBadRequest},
+		{name: "negative: both bucket kinds", histogram: HistogramValue{Count: 1, Bounds: []float64{1}, Buckets: []uint64{1, 0}, Schema: ptrInt32(0)}, expectedError: ErrBadRequest},
+		{name: "negative: schema out of range", histogram: HistogramValue{Count: 1, Schema: ptrInt32(9), ZeroCount: 1}, expectedError: ErrBadRequest},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, tt.histogram.Validate(), tt.expectedError)
+		})
+	}
+}
+
+func TestHistogramValue_Observe(t *testing.T) {
+	h := NewHistogram([]float64{1, 2, 5})
+	for _, v := range []float64{0.5, 1, 1.5, 3, 10} {
+		require.NoError(t, h.Observe(v))
+	}
+	assert.Equal(t, &HistogramValue{Count: 5, Sum: 16, Bounds: []float64{1, 2, 5}, Buckets: []uint64{2, 1, 1, 1}}, h)
+
+	e := NewExponentialHistogram(0)
+	for _, v := range []float64{0, 1, 3, 4} {
+		require.NoError(t, e.Observe(v))
+	}
+	assert.ErrorIs(t, e.Observe(-1), ErrBadRequest)
+	assert.Equal(t, &HistogramValue{Count: 4, Sum: 8, Schema: ptrInt32(0), ZeroCount: 1, Positive: map[int32]uint64{0: 1, 2: 2}}, e)
+}
+
+func TestHistogramValue_Merge(t *testing.T) {
+	h := NewHistogram([]float64{1, 2})
+	require.NoError(t, h.Merge(&HistogramValue{Count: 3, Sum: 4, Bounds: []float64{1, 2}, Buckets: []uint64{1, 1, 1}}))
+	require.NoError(t, h.Merge(&HistogramValue{Count: 1, Sum: 0.5, Bounds: []float64{1, 2}, Buckets: []uint64{1, 0, 0}}))
+	assert.Equal(t, &HistogramValue{Count: 4, Sum: 4.5, Bounds: []float64{1, 2}, Buckets: []uint64{2, 1, 1}}, h)
+	assert.ErrorIs(t, h.Merge(&HistogramValue{Count: 1, Bounds: []float64{1, 3}, Buckets: []uint64{1, 0, 0}}), ErrBadRequest)
+	assert.ErrorIs(t, h.Merge(NewExponentialHistogram(0)), ErrBadRequest)
+
+	// корзины схемы 1 приводятся к схеме 0: индексы 1,2 -> 1, индексы 3,4 -> 2
+	e := &HistogramValue{Count: 2, Sum: 3, Schema: ptrInt32(1), Positive: map[int32]uint64{1: 1, 4: 1}}
+	require.NoError(t, e.Merge(&HistogramValue{Count: 3, Sum: 5, Schema: ptrInt32(0), ZeroCount: 1, Positive: map[int32]uint64{1: 2}}))
+	assert.Equal(t, &HistogramValue{Count: 5, Sum: 8, Schema: ptrInt32(0), ZeroCount: 1, Positive: map[int32]uint64{1: 3, 2: 1}}, e)
+}
+
+func TestHistogramValue_Quantile(t *testing.T) {
+	h := &HistogramValue{Count: 10, Sum: 20, Bounds: []float64{1, 2, 4}, Buckets: []uint64{2, 4, 2, 2}}
+	testCases := []struct {
+		name          string
+		histogram     *HistogramValue
+		q             float64
+		expected      float64
+		expectedError error
+	}{
+		{name: "first bucket", histogram: h, q: 0.1, expected: 0.5},
+		{name: "median", histogram: h, q: 0.5, expected: 1.75},
+		{name: "last finite bucket", histogram: h, q: 0.8, expected: 4},
+		{name: "overflow bucket", histogram: h, q: 0.99, expected: 4},
+		{name: "exponential", histogram: &HistogramValue{Count: 4, Schema: ptrInt32(0), ZeroCount: 1, Positive: map[int32]uint64{2: 2, 3: 1}}, q: 0.5, expected: 3},
+		{name: "exponential zero", histogram: &HistogramValue{Count: 4, Schema: ptrInt32(0), ZeroCount: 1, Positive: map[int32]uint64{2: 3}}, q: 0.25, expected: 0},
+		{name: "negative: q out of range", histogram: h, q: 1.5, expectedError: ErrBadRequest},
+		{name: "negative: empty histogram", histogram: NewHistogram([]float64{1}), q: 0.5, expectedError: ErrNotFound},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.histogram.Quantile(tt.q)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.InDelta(t, tt.expected, v, 1e-9)
+		})
+	}
+}
+
+func TestHistogramValue_CumulativeBuckets(t *testing.T) {
+	bounds, counts := (&HistogramValue{Count: 5, Sum: 16, Bounds: []float64{1, 2, 5}, Buckets: []uint64{2, 1, 1, 1}}).CumulativeBuckets()
+	assert.Equal(t, []float64{1, 2, 5, math.Inf(1)}, bounds)
+	assert.Equal(t, []uint64{2, 3, 4, 5}, counts)
+
+	bounds, counts = (&HistogramValue{Count: 4, Sum: 8, Schema: ptrInt32(0), ZeroCount: 1, Positive: map[int32]uint64{2: 2, 0: 1}}).CumulativeBuckets()
+	assert.Equal(t, []float64{0, 1, 4, math.Inf(1)}, bounds)
+	assert.Equal(t, []uint64{1, 2, 4, 4}, counts)
+}
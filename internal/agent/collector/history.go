@@ -0,0 +1,81 @@
+package collector
+
+import "time"
+
+// maxHistorySamples - максимальное число значений в истории одной метрики.
+// Значения, пришедшие чаще, чем раз в historyRetention/maxHistorySamples, заменяют последнее значение.
+const maxHistorySamples = 1024
+
+// Sample - значение метрики в момент времени.
+type Sample struct {
+	Time  time.Time // время записи значения
+	Value float64   // значение counter или gauge
+}
+
+var (
+	// history - история значений метрик типа counter и gauge, доступ защищен collectMu.
+	history = make(map[string][]Sample)
+	// historyRetention - время хранения истории; нулевое значение отключает историю.
+	historyRetention time.Duration
+)
+
+// SetHistoryRetention задает время хранения истории значений метрик типа counter и gauge.
+// Нулевое значение отключает историю и удаляет накопленные значения.
+func (c *collector) SetHistoryRetention(retention time.Duration) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	historyRetention = retention
+	if retention <= 0 {
+		history = make(map[string][]Sample)
+	}
+}
+
+// History возвращает копию истории значений метрики по возрастанию времени.
+// Для counter значения накопительные, сброс счетчика виден как уменьшение значения.
+func (c *collector) History(metricName string) []Sample {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	samples := make([]Sample, len(history[metricName]))
+	copy(samples, history[metricName])
+	return samples
+}
+
+// NumericValue возвращает текущее значение метрики типа counter или gauge.
+func (c *collector) NumericValue(metricName string) (float64, bool) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	m, err := c.getMetric(metricName)
+	if err != nil {
+		return 0, false
+	}
+	switch {
+	case m.MType == Counter && m.CounterValue != nil:
+		return float64(*m.CounterValue), true
+	case m.MType == Gauge && m.GaugeValue != nil:
+		return *m.GaugeValue, true
+	default:
+		return 0, false
+	}
+}
+
+// recordSample добавляет значение в историю метрики и удаляет значения старше historyRetention,
+// кроме последнего значения перед началом окна хранения, вызывается под collectMu.
+func recordSample(metricName string, value float64, now time.Time) {
+	if historyRetention <= 0 {
+		return
+	}
+	samples := history[metricName]
+	spacing := historyRetention / maxHistorySamples
+	if n := len(samples); n >= 2 && now.Sub(samples[n-2].Time) < spacing {
+		samples[n-1] = Sample{Time: now, Value: value}
+	} else {
+		samples = append(samples, Sample{Time: now, Value: value})
+	}
+	// последнее значение до начала окна хранения сохраняется как начальное значение для расчетов за окно
+	cutoff := now.Add(-historyRetention)
+	drop := 0
+	for drop < len(samples)-1 && (!samples[drop+1].Time.After(cutoff) || len(samples)-drop > maxHistorySamples+1) {
+		drop++
+	}
+	history[metricName] = samples[drop:]
+}
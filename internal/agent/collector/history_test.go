@@ -0,0 +1,58 @@
+package collector
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestCollector_History(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	c.SetHistoryRetention(time.Hour)
+	defer c.SetHistoryRetention(0)
+
+	assert.NoError(t, c.Collect(MetricRequest{ID: "HistoryCounter", MType: "counter", Delta: PtrInt64(2)}, "2"))
+	assert.NoError(t, c.Collect(MetricRequest{ID: "HistoryCounter", MType: "counter", Delta: PtrInt64(3)}, "3"))
+	assert.NoError(t, c.ResetMetric("HistoryCounter", "counter"))
+	assert.NoError(t, c.Collect(MetricRequest{ID: "HistoryGauge", MType: "gauge", Value: PtrFloat64(1.5)}, "1.5"))
+
+	var values []float64
+	for _, s := range c.History("HistoryCounter") {
+		values = append(values, s.Value)
+	}
+	// частые записи заменяют последнее значение: сохраняются первое и последнее
+	assert.Equal(t, []float64{2, 0}, values)
+	value, ok := c.NumericValue("HistoryGauge")
+	assert.True(t, ok)
+	assert.Equal(t, 1.5, value)
+	_, ok = c.NumericValue("MissingGauge")
+	assert.False(t, ok)
+
+	assert.NoError(t, c.DeleteMetric("HistoryGauge", "gauge"))
+	assert.Empty(t, c.History("HistoryGauge"))
+	c.DrainTombstones()
+}
+
+func TestRecordSample(t *testing.T) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	historyRetention = 1024 * time.Second
+	defer func() {
+		historyRetention = 0
+		delete(history, "RecordedGauge")
+	}()
+
+	start := time.Now()
+	at := func(seconds float64) time.Time {
+		return start.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	// значения чаще раза в секунду заменяют последнее значение
+	for i, s := range []float64{0, 1, 1.2, 1.5, 2.1} {
+		recordSample("RecordedGauge", float64(i), at(s))
+	}
+	assert.Equal(t, []Sample{{at(0), 0}, {at(1), 1}, {at(1.5), 3}, {at(2.1), 4}}, history["RecordedGauge"])
+
+	// из значений старше времени хранения остается только последнее
+	recordSample("RecordedGauge", 5, at(1026))
+	assert.Equal(t, []Sample{{at(1.5), 3}, {at(2.1), 4}, {at(1026), 5}}, history["RecordedGauge"])
+}
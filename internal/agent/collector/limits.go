@@ -0,0 +1,249 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrLimitExceeded представляет ошибку для записи, превысившей лимит числа серий или скорости записи.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// Имена собственных счетчиков сервера с числом отклоненных записей.
+const (
+	RejectedSeriesMetric = ReservedPrefix + "rejected_writes_series" // записи, отклоненные из-за лимита числа серий
+	RejectedRateMetric   = ReservedPrefix + "rejected_writes_rate"   // записи, отклоненные из-за лимита скорости
+)
+
+// Limits - лимиты на число серий и скорость записи, глобальные и для отдельного агента.
+// Нулевое значение отключает соответствующий лимит.
+type Limits struct {
+	MaxSeries         int     // максимальное число серий
+	MaxSeriesPerAgent int     // максимальное число серий, созданных одним агентом
+	Rate              float64 // максимальное число записей в секунду
+	RatePerAgent      float64 // максимальное число записей в секунду от одного агента
+}
+
+// bucket - ограничитель скорости по алгоритму token bucket с емкостью, равной скорости за секунду.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// allow списывает один токен, если он есть, пополнив запас за время с предыдущего вызова.
+func (b *bucket) allow(rate float64, now time.Time) bool {
+	return b.allowN(rate, 1, now)
+}
+
+// allowN списывает n токенов, пополнив запас за время с предыдущего вызова. Пакет больше емкости
+// допускается при полном запасе, недостающие токены списываются в долг и замедляют следующие записи.
+func (b *bucket) allowN(rate float64, n int, now time.Time) bool {
+	if !b.refill(rate, n, now) {
+		return false
+	}
+	b.take(n)
+	return true
+}
+
+// refill пополняет запас за время с предыдущего вызова и сообщает, можно ли списать n токенов.
+func (b *bucket) refill(rate float64, n int, now time.Time) bool {
+	capacity := math.Max(rate, 1)
+	if b.last.IsZero() {
+		b.tokens = capacity
+	} else {
+		b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+	return b.tokens >= math.Min(float64(n), capacity)
+}
+
+// take списывает n токенов, проверенных refill.
+func (b *bucket) take(n int) {
+	b.tokens -= float64(n)
+}
+
+// idle сообщает, простаивал ли ограничитель дольше ttl и успел ли за это время пополниться полностью,
+// то есть не отличается ли он от нового.
+func (b *bucket) idle(rate float64, ttl time.Duration, now time.Time) bool {
+	elapsed := now.Sub(b.last)
+	return elapsed > ttl && b.tokens+elapsed.Seconds()*rate >= math.Max(rate, 1)
+}
+
+// agentIdleTTL - время без записей, после которого ограничитель скорости агента удаляется.
+// Проверка выполняется не чаще одного раза за этот интервал.
+const agentIdleTTL = 10 * time.Minute
+
+var (
+	// limits - действующие лимиты, доступ к ним и к состоянию ниже защищен collectMu.
+	limits Limits
+	// globalBucket и agentBuckets - ограничители скорости записи.
+	globalBucket bucket
+	agentBuckets = make(map[string]*bucket)
+	// lastSweep - время последнего удаления ограничителей простаивающих агентов.
+	lastSweep time.Time
+	// seriesAgent - агент, создавший серию, и seriesPerAgent - число серий каждого агента.
+	seriesAgent    = make(map[string]string)
+	seriesPerAgent = make(map[string]int)
+)
+
+// SetLimits задает лимиты на число серий и скорость записи.
+func (c *collector) SetLimits(l Limits) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	limits = l
+	agentBuckets = make(map[string]*bucket)
+	globalBucket = bucket{}
+}
+
+// CollectFrom сохраняет метрику, полученную от агента agent, с проверкой лимитов.
+// Агент определяется транспортом (например, по адресу клиента); пустое значение проверяет только глобальные лимиты.
+// Запись сверх лимита отклоняется с ErrLimitExceeded и учитывается в собственных счетчиках сервера.
+func (c *collector) CollectFrom(agent string, metric MetricRequest, metricValue string) error {
+	return c.CollectAllFrom(agent, []MetricRequest{metric}, []string{metricValue})
+}
+
+// CollectAllFrom сохраняет пакет метрик, полученный от агента agent; values[i] - значение metrics[i].
+// Пакет записывается целиком или не записывается совсем: значения всех метрик вычисляются и проверяются,
+// в том числе на конфликт с зарегистрированным типом, затем проверяются лимиты числа серий и скорости,
+// и только после этого списываются токены скорости и записываются метрики.
+func (c *collector) CollectAllFrom(agent string, metrics []MetricRequest, values []string) error {
+	if len(metrics) != len(values) {
+		return ErrBadRequest
+	}
+	ids := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		if err := validateRequest(metric); err != nil {
+			return err
+		}
+		ids = append(ids, metric.ID)
+	}
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	// staged - значения, вычисленные для предыдущих метрик пакета, чтобы повторы одной метрики складывались
+	staged := make(map[string]StoredMetric, len(metrics))
+	// registered - типы, которые зарегистрируют описания из предыдущих метрик пакета
+	registered := make(map[string]string)
+	prepared := make([]StoredMetric, len(metrics))
+	for i, metric := range metrics {
+		if mtype, ok := registered[metric.ID]; ok && mtype != metric.MType {
+			return ErrConflict
+		}
+		prev, ok := staged[metric.ID]
+		if !ok {
+			prev, _ = c.getMetric(metric.ID)
+		}
+		m, err := prepare(metric, values[i], prev)
+		if err != nil {
+			return err
+		}
+		staged[metric.ID] = m
+		prepared[i] = m
+		if metric.Metadata != nil {
+			registered[metric.ID] = metric.MType
+		}
+	}
+	now := time.Now()
+	if err := c.checkLimits(agent, ids, now); err != nil {
+		return err
+	}
+	for i, metric := range metrics {
+		if err := c.apply(metric, prepared[i], now); err != nil {
+			return err
+		}
+		if agent == "" {
+			continue
+		}
+		touchAgent(agent)
+		if _, ok := seriesAgent[metric.ID]; !ok {
+			seriesAgent[metric.ID] = agent
+			seriesPerAgent[agent]++
+		}
+	}
+	return nil
+}
+
+// checkLimits проверяет лимиты числа серий и скорости для записи метрик ids и списывает токены скорости,
+// только если пакет проходит все лимиты; вызывается под collectMu.
+func (c *collector) checkLimits(agent string, ids []string, now time.Time) error {
+	n := len(ids)
+	created := make(map[string]struct{})
+	for _, id := range ids {
+		if _, err := c.getMetric(id); err != nil {
+			created[id] = struct{}{}
+		}
+	}
+	if len(created) != 0 {
+		if limits.MaxSeries > 0 && c.seriesCount()+len(created) > limits.MaxSeries {
+			c.addCounter(RejectedSeriesMetric, int64(n))
+			return fmt.Errorf("%w: %d series", ErrLimitExceeded, limits.MaxSeries)
+		}
+		if limits.MaxSeriesPerAgent > 0 && agent != "" && seriesPerAgent[agent]+len(created) > limits.MaxSeriesPerAgent {
+			c.addCounter(RejectedSeriesMetric, int64(n))
+			return fmt.Errorf("%w: %d series for agent %q", ErrLimitExceeded, limits.MaxSeriesPerAgent, agent)
+		}
+	}
+
+	sweepAgentBuckets(now)
+	if limits.Rate > 0 && !globalBucket.refill(limits.Rate, n, now) {
+		c.addCounter(RejectedRateMetric, int64(n))
+		return fmt.Errorf("%w: ingest rate %g/s", ErrLimitExceeded, limits.Rate)
+	}
+	var b *bucket
+	if limits.RatePerAgent > 0 && agent != "" {
+		var ok bool
+		if b, ok = agentBuckets[agent]; !ok {
+			b = &bucket{}
+			agentBuckets[agent] = b
+		}
+		if !b.refill(limits.RatePerAgent, n, now) {
+			c.addCounter(RejectedRateMetric, int64(n))
+			return fmt.Errorf("%w: ingest rate %g/s for agent %q", ErrLimitExceeded, limits.RatePerAgent, agent)
+		}
+	}
+	if limits.Rate > 0 {
+		globalBucket.take(n)
+	}
+	if b != nil {
+		b.take(n)
+	}
+	return nil
+}
+
+// sweepAgentBuckets удаляет ограничители скорости агентов, простаивающих дольше agentIdleTTL,
+// чтобы их число не росло с каждым новым адресом клиента; вызывается под collectMu.
+// Учет серий агентов очищается при удалении серий (см. forgetSeries).
+func sweepAgentBuckets(now time.Time) {
+	if now.Sub(lastSweep) < agentIdleTTL {
+		return
+	}
+	lastSweep = now
+	for agent, b := range agentBuckets {
+		if b.idle(limits.RatePerAgent, agentIdleTTL, now) {
+			delete(agentBuckets, agent)
+		}
+	}
+}
+
+// seriesCount возвращает число серий без учета собственных метрик сервера.
+func (c *collector) seriesCount() int {
+	n := 0
+	for _, m := range c.Metrics {
+		if !IsReserved(m.ID) {
+			n++
+		}
+	}
+	return n
+}
+
+// forgetSeries удаляет сведения об удаленной серии, вызывается под collectMu.
+func forgetSeries(metricName string) {
+	delete(lastUpdated, metricName)
+	delete(history, metricName)
+	if agent, ok := seriesAgent[metricName]; ok {
+		delete(seriesAgent, metricName)
+		if seriesPerAgent[agent]--; seriesPerAgent[agent] <= 0 {
+			delete(seriesPerAgent, agent)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package collector
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBucket_Allow(t *testing.T) {
+	now := time.Now()
+	var b bucket
+	assert.True(t, b.allow(2, now))
+	assert.True(t, b.allow(2, now))
+	assert.False(t, b.allow(2, now))
+	assert.True(t, b.allow(2, now.Add(500*time.Millisecond)))
+	assert.False(t, b.allow(2, now.Add(500*time.Millisecond)))
+
+	// пакет больше емкости допускается при полном запасе и уходит в долг
+	var batch bucket
+	assert.True(t, batch.allowN(2, 5, now))
+	assert.False(t, batch.allowN(2, 1, now.Add(time.Second)))
+	assert.True(t, batch.allowN(2, 1, now.Add(2*time.Second)))
+}
+
+func TestCollector_CollectFrom(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	c.SetLimits(Limits{MaxSeries: 3, MaxSeriesPerAgent: 2})
+	defer c.SetLimits(Limits{})
+
+	assert.NoError(t, c.CollectFrom("agent1", MetricRequest{ID: "Limited1", MType: "counter", Delta: PtrInt64(1)}, "1"))
+	assert.NoError(t, c.CollectFrom("agent1", MetricRequest{ID: "Limited2", MType: "counter", Delta: PtrInt64(1)}, "1"))
+	// существующая серия обновляется и сверх лимита агента
+	assert.NoError(t, c.CollectFrom("agent1", MetricRequest{ID: "Limited2", MType: "counter", Delta: PtrInt64(1)}, "1"))
+	assert.ErrorIs(t, c.CollectFrom("agent1", MetricRequest{ID: "Limited3", MType: "counter", Delta: PtrInt64(1)}, "1"), ErrLimitExceeded)
+
+	assert.NoError(t, c.CollectFrom("agent2", MetricRequest{ID: "Limited3", MType: "counter", Delta: PtrInt64(1)}, "1"))
+	assert.ErrorIs(t, c.CollectFrom("agent2", MetricRequest{ID: "Limited4", MType: "counter", Delta: PtrInt64(1)}, "1"), ErrLimitExceeded)
+
+	// собственные счетчики сервера не учитываются в лимите числа серий
+	rejected, err := c.GetMetric(RejectedSeriesMetric)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *rejected.CounterValue)
+
+	// удаленная серия освобождает место в лимитах
+	assert.NoError(t, c.DeleteMetric("Limited1", "counter"))
+	assert.NoError(t, c.CollectFrom("agent1", MetricRequest{ID: "Limited4", MType: "counter", Delta: PtrInt64(1)}, "1"))
+	c.DrainTombstones()
+}
+
+func TestCollector_CollectFromRate(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	c.SetLimits(Limits{RatePerAgent: 1})
+	defer c.SetLimits(Limits{})
+
+	assert.NoError(t, c.CollectFrom("agent1", MetricRequest{ID: "RateLimited", MType: "gauge", Value: PtrFloat64(1)}, "1"))
+	assert.ErrorIs(t, c.CollectFrom("agent1", MetricRequest{ID: "RateLimited", MType: "gauge", Value: PtrFloat64(2)}, "2"), ErrLimitExceeded)
+	assert.NoError(t, c.CollectFrom("agent2", MetricRequest{ID: "RateLimited", MType: "gauge", Value: PtrFloat64(3)}, "3"))
+
+	rejected, err := c.GetMetric(RejectedRateMetric)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), *rejected.CounterValue)
+}
+
+func TestCollector_CollectAllFrom(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	c.SetLimits(Limits{MaxSeriesPerAgent: 2})
+	defer c.SetLimits(Limits{})
+
+	batch := []MetricRequest{
+		{ID: "Batch1", MType: "counter", Delta: PtrInt64(1)},
+		{ID: "Batch2", MType: "counter", Delta: PtrInt64(1)},
+		{ID: "Batch3", MType: "counter", Delta: PtrInt64(1)},
+	}
+	// пакет сверх лимита отклоняется целиком
+	assert.ErrorIs(t, c.CollectAllFrom("batch-agent", batch, []string{"1", "1", "1"}), ErrLimitExceeded)
+	_, err := c.GetMetric("Batch1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.NoError(t, c.CollectAllFrom("batch-agent", batch[:2], []string{"1", "1"}))
+	_, err = c.GetMetric("Batch2")
+	assert.NoError(t, err)
+	assert.ErrorIs(t, c.CollectAllFrom("batch-agent", batch[:2], []string{"1"}), ErrBadRequest)
+}
+
+func TestCollector_CollectAllFromAtomic(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	c.SetLimits(Limits{RatePerAgent: 2, MaxSeriesPerAgent: 2})
+	defer c.SetLimits(Limits{})
+	assert.NoError(t, c.RegisterMetadata(MetricMetadata{ID: "AtomicTyped", Type: Gauge}))
+
+	testCases := []struct {
+		name          string
+		batch         []MetricRequest
+		values        []string
+		expectedError error
+	}{
+		{
+			name: "type conflict",
+			batch: []MetricRequest{
+				{ID: "Atomic1", MType: "counter", Delta: PtrInt64(1)},
+				{ID: "AtomicTyped", MType: "counter", Delta: PtrInt64(1)},
+			},
+			values:        []string{"1", "1"},
+			expectedError: ErrConflict,
+		},
+		{
+			name: "type conflict with metadata of the same batch",
+			batch: []MetricRequest{
+				{ID: "Atomic1", MType: "counter", Delta: PtrInt64(1), Metadata: &MetricMetadata{Unit: "requests"}},
+				{ID: "Atomic1", MType: "gauge", Value: PtrFloat64(1)},
+			},
+			values:        []string{"1", "1"},
+			expectedError: ErrConflict,
+		},
+		{
+			name: "bad value",
+			batch: []MetricRequest{
+				{ID: "Atomic1", MType: "counter", Delta: PtrInt64(1)},
+				{ID: "Atomic2", MType: "gauge", Value: PtrFloat64(1)},
+			},
+			values:        []string{"1", "one"},
+			expectedError: ErrBadRequest,
+		},
+		{
+			name: "series limit",
+			batch: []MetricRequest{
+				{ID: "Atomic1", MType: "counter", Delta: PtrInt64(1)},
+				{ID: "Atomic2", MType: "counter", Delta: PtrInt64(1)},
+				{ID: "Atomic3", MType: "counter", Delta: PtrInt64(1)},
+			},
+			values:        []string{"1", "1", "1"},
+			expectedError: ErrLimitExceeded,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, c.CollectAllFrom("atomic-agent", tt.batch, tt.values), tt.expectedError)
+			_, err := c.GetMetric("Atomic1")
+			assert.ErrorIs(t, err, ErrNotFound)
+		})
+	}
+
+	// отклоненные пакеты не расходуют токены скорости агента
+	batch := []MetricRequest{
+		{ID: "Atomic1", MType: "counter", Delta: PtrInt64(1)},
+		{ID: "Atomic1", MType: "counter", Delta: PtrInt64(2)},
+	}
+	assert.NoError(t, c.CollectAllFrom("atomic-agent", batch, []string{"1", "2"}))
+	m, err := c.GetMetric("Atomic1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), *m.CounterValue)
+}
+
+func TestCollector_ExpireFreesAgentSeries(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	c.SetLimits(Limits{MaxSeriesPerAgent: 1})
+	defer c.SetLimits(Limits{})
+	c.SetExpiry(0, time.Hour)
+	defer c.SetExpiry(0, 0)
+
+	assert.NoError(t, c.CollectFrom("expiring-agent", MetricRequest{ID: "AgentSeries1", MType: "gauge", Value: PtrFloat64(1)}, "1"))
+	assert.ErrorIs(t, c.CollectFrom("expiring-agent", MetricRequest{ID: "AgentSeries2", MType: "gauge", Value: PtrFloat64(1)}, "1"), ErrLimitExceeded)
+
+	// устаревшая серия удаляется и больше не учитывается в лимите агента
+	assert.Contains(t, c.Expire(time.Now().Add(2*time.Hour)), "AgentSeries1")
+	c.DrainTombstones()
+	assert.NoError(t, c.CollectFrom("expiring-agent", MetricRequest{ID: "AgentSeries2", MType: "gauge", Value: PtrFloat64(1)}, "1"))
+}
+
+func TestSweepAgentBuckets(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	c.SetLimits(Limits{RatePerAgent: 1})
+	defer c.SetLimits(Limits{})
+	now := time.Now()
+
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	agentBuckets["idle-agent"] = &bucket{tokens: 0, last: now.Add(-2 * agentIdleTTL)}
+	agentBuckets["busy-agent"] = &bucket{tokens: 0, last: now.Add(-time.Second)}
+	lastSweep = time.Time{}
+	sweepAgentBuckets(now)
+	assert.NotContains(t, agentBuckets, "idle-agent")
+	assert.Contains(t, agentBuckets, "busy-agent")
+
+	// повторная проверка до истечения интервала ничего не удаляет
+	agentBuckets["idle-agent"] = &bucket{tokens: 0, last: now.Add(-2 * agentIdleTTL)}
+	sweepAgentBuckets(now.Add(time.Minute))
+	assert.Contains(t, agentBuckets, "idle-agent")
+}
@@ -0,0 +1,100 @@
+package collector
+
+import "sort"
+
+// MetricMetadata - описание метрики: единица измерения, назначение, ожидаемый тип и владелец.
+type MetricMetadata struct {
+	ID          string `json:"id,omitempty"`          // имя метрики
+	Unit        string `json:"unit,omitempty"`        // единица измерения, например "bytes" или "seconds"
+	Description string `json:"description,omitempty"` // описание метрики
+	Type        string `json:"type,omitempty"`        // ожидаемый тип метрики: counter, gauge или histogram
+	Owner       string `json:"owner,omitempty"`       // владелец метрики (команда или сервис)
+}
+
+// metadataRegistry хранит зарегистрированные описания метрик по имени, доступ защищен collectMu.
+var metadataRegistry = make(map[string]MetricMetadata)
+
+// RegisterMetadata регистрирует или дополняет описание метрики: непустые поля md заменяют сохраненные.
+// Возвращает ErrBadRequest для пустого имени или неизвестного типа и ErrConflict, если тип расходится
+// с ранее зарегистрированным или с типом уже сохраненной метрики.
+func (c *collector) RegisterMetadata(md MetricMetadata) error {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	return c.registerMetadata(md)
+}
+
+// GetMetadata возвращает описание метрики по имени.
+func (c *collector) GetMetadata(metricName string) (MetricMetadata, bool) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	md, ok := metadataRegistry[metricName]
+	return md, ok
+}
+
+// GetAllMetadata возвращает все зарегистрированные описания, отсортированные по имени метрики.
+func (c *collector) GetAllMetadata() []MetricMetadata {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	result := make([]MetricMetadata, 0, len(metadataRegistry))
+	for _, md := range metadataRegistry {
+		result = append(result, md)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// RestoreMetadata заменяет зарегистрированные описания метрик описаниями, восстановленными из хранилища.
+func (c *collector) RestoreMetadata(mds []MetricMetadata) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	metadataRegistry = make(map[string]MetricMetadata, len(mds))
+	for _, md := range mds {
+		if md.ID != "" {
+			metadataRegistry[md.ID] = md
+		}
+	}
+}
+
+// registerMetadata регистрирует описание метрики, вызывается под collectMu.
+func (c *collector) registerMetadata(md MetricMetadata) error {
+	if md.ID == "" {
+		return ErrBadRequest
+	}
+	switch md.Type {
+	case "", Counter, Gauge, Histogram:
+	default:
+		return ErrBadRequest
+	}
+	stored, ok := metadataRegistry[md.ID]
+	if md.Type != "" {
+		if ok && stored.Type != "" && stored.Type != md.Type {
+			return ErrConflict
+		}
+		if m, err := c.getMetric(md.ID); err == nil && m.MType != md.Type {
+			return ErrConflict
+		}
+		stored.Type = md.Type
+	}
+	stored.ID = md.ID
+	if md.Unit != "" {
+		stored.Unit = md.Unit
+	}
+	if md.Description != "" {
+		stored.Description = md.Description
+	}
+	if md.Owner != "" {
+		stored.Owner = md.Owner
+	}
+	metadataRegistry[md.ID] = stored
+	return nil
+}
+
+// checkRegisteredType проверяет, что тип метрики совпадает с зарегистрированным, вызывается под collectMu.
+func checkRegisteredType(metricName, mtype string) error {
+	if md, ok := metadataRegistry[metricName]; ok && md.Type != "" && md.Type != mtype {
+		return ErrConflict
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCollector_RegisterMetadata(t *testing.T) {
+	c := collector{[]StoredMetric{
+		{ID: "StoredCounter", MType: "counter", CounterValue: PtrInt64(1), TextValue: PtrString("1")},
+	}}
+	testCases := []struct {
+		name          string
+		metadata      MetricMetadata
+		expected      MetricMetadata
+		expectedError error
+	}{
+		{
+			name:     "register",
+			metadata: MetricMetadata{ID: "HeapBytes", Unit: "bytes", Type: "gauge"},
+			expected: MetricMetadata{ID: "HeapBytes", Unit: "bytes", Type: "gauge"},
+		},
+		{
+			name:     "update keeps other fields",
+			metadata: MetricMetadata{ID: "HeapBytes", Description: "heap in use", Owner: "runtime"},
+			expected: MetricMetadata{ID: "HeapBytes", Unit: "bytes", Description: "heap in use", Type: "gauge", Owner: "runtime"},
+		},
+		{
+			name:          "negative: registered type conflict",
+			metadata:      MetricMetadata{ID: "HeapBytes", Type: "counter"},
+			expected:      MetricMetadata{ID: "HeapBytes", Unit: "bytes", Description: "heap in use", Type: "gauge", Owner: "runtime"},
+			expectedError: ErrConflict,
+		},
+		{
+			name:          "negative: stored metric type conflict",
+			metadata:      MetricMetadata{ID: "StoredCounter", Type: "gauge"},
+			expectedError: ErrConflict,
+		},
+		{
+			name:          "negative: unknown type",
+			metadata:      MetricMetadata{ID: "Unknown", Type: "summary"},
+			expectedError: ErrBadRequest,
+		},
+		{
+			name:          "negative: empty name",
+			metadata:      MetricMetadata{Unit: "bytes"},
+			expectedError: ErrBadRequest,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.ErrorIs(t, c.RegisterMetadata(tt.metadata), tt.expectedError)
+			md, _ := c.GetMetadata(tt.metadata.ID)
+			assert.Equal(t, tt.expected, md)
+		})
+	}
+}
+
+func TestCollector_CollectRegisteredType(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	assert.NoError(t, c.RegisterMetadata(MetricMetadata{ID: "Requests", Type: "counter"}))
+
+	assert.ErrorIs(t, c.Collect(MetricRequest{ID: "Requests", MType: "gauge", Value: PtrFloat64(1)}, "1"), ErrConflict)
+	assert.NoError(t, c.Collect(MetricRequest{ID: "Requests", MType: "counter", Delta: PtrInt64(1)}, "1"))
+
+	// описание из отчета агента регистрируется с типом метрики
+	assert.NoError(t, c.Collect(MetricRequest{
+		ID:       "QueueDepth",
+		MType:    "gauge",
+		Value:    PtrFloat64(-2),
+		Metadata: &MetricMetadata{Unit: "items", Owner: "worker"},
+	}, "-2"))
+	md, ok := c.GetMetadata("QueueDepth")
+	assert.True(t, ok)
+	assert.Equal(t, MetricMetadata{ID: "QueueDepth", Unit: "items", Type: "gauge", Owner: "worker"}, md)
+
+	assert.ErrorIs(t, c.Collect(MetricRequest{
+		ID:       "Latency",
+		MType:    "gauge",
+		Value:    PtrFloat64(1),
+		Metadata: &MetricMetadata{Type: "histogram"},
+	}, "1"), ErrConflict)
+}
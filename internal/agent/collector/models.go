@@ -1,26 +1,42 @@
 package collector
 
+import "strconv"
+
+// Типы метрик. Тип summary (квантили, посчитанные агентом) не поддерживается и отклоняется
+// с ErrNotImplemented: квантили из разных отчетов нельзя объединить на сервере, поэтому
+// распределения передаются гистограммой, а квантили оцениваются сервером по ее корзинам.
+const (
+	Counter   = "counter"   // тип метрики для счетчика
+	Gauge     = "gauge"     // тип метрики для датчика
+	Histogram = "histogram" // тип метрики для гистограммы
+)
+
 const (
-	Counter = "counter" // тип метрики для счетчика
-	Gauge   = "gauge"   // тип метрики для датчика
+	GaugeSet = "set" // операция gauge: установить значение (по умолчанию)
+	GaugeAdd = "add" // операция gauge: прибавить значение к сохраненному
+	GaugeSub = "sub" // операция gauge: вычесть значение из сохраненного
 )
 
 type (
 	// MetricRequest - структура запроса метрики для вставки из HTTP-запроса.
 	MetricRequest struct {
-		ID    string   `json:"id"`              // имя метрики
-		MType string   `json:"type"`            // параметр, принимающий значение gauge или counter
-		Delta *int64   `json:"delta,omitempty"` // значение метрики в случае передачи counter
-		Value *float64 `json:"value,omitempty"` // значение метрики в случае передачи gauge
+		ID        string          `json:"id"`                  // имя метрики
+		MType     string          `json:"type"`                // параметр, принимающий значение gauge, counter или histogram
+		Delta     *int64          `json:"delta,omitempty"`     // значение метрики в случае передачи counter
+		Value     *float64        `json:"value,omitempty"`     // значение метрики в случае передачи gauge
+		Histogram *HistogramValue `json:"histogram,omitempty"` // значение метрики в случае передачи histogram
+		Op        string          `json:"op,omitempty"`        // операция для gauge: set, add или sub
+		Metadata  *MetricMetadata `json:"metadata,omitempty"`  // описание метрики, регистрируемое вместе со значением
 	}
 
 	// StoredMetric - структура для хранения метрик на сервере.
 	StoredMetric struct {
-		ID           string   `json:"id"`                      // имя метрики
-		MType        string   `json:"type"`                    // параметр, принимающий значение gauge или counter
-		CounterValue *int64   `json:"counter_value,omitempty"` // значение метрики в случае передачи counter
-		GaugeValue   *float64 `json:"gauge_value,omitempty"`   // значение метрики в случае передачи gauge
-		TextValue    *string  `json:"text_value,omitempty"`    // значение метрики в случае передачи текста
+		ID             string          `json:"id"`                        // имя метрики
+		MType          string          `json:"type"`                      // параметр, принимающий значение gauge, counter или histogram
+		CounterValue   *int64          `json:"counter_value,omitempty"`   // значение метрики в случае передачи counter
+		GaugeValue     *float64        `json:"gauge_value,omitempty"`     // значение метрики в случае передачи gauge
+		HistogramValue *HistogramValue `json:"histogram_value,omitempty"` // значение метрики в случае передачи histogram
+		TextValue      *string         `json:"text_value,omitempty"`      // значение метрики в случае передачи текста
 	}
 
 	// collector - структура для сбора метрик.
@@ -28,3 +44,25 @@ type (
 		Metrics []StoredMetric
 	}
 )
+
+// Text возвращает текстовое представление значения метрики. Для метрик без сохраненного текста
+// (например, восстановленных из БД) оно строится по значению соответствующего типа.
+func (m StoredMetric) Text() string {
+	switch {
+	case m.TextValue != nil:
+		return *m.TextValue
+	case m.CounterValue != nil:
+		return strconv.FormatInt(*m.CounterValue, 10)
+	case m.GaugeValue != nil:
+		return FormatGauge(*m.GaugeValue)
+	case m.HistogramValue != nil:
+		return m.HistogramValue.String()
+	}
+	return ""
+}
+
+// FormatGauge возвращает текстовое представление значения gauge: кратчайшую десятичную запись без экспоненты.
+// Используется для всех способов записи gauge, чтобы одно и то же значение всегда выглядело одинаково.
+func FormatGauge(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
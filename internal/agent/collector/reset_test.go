@@ -0,0 +1,46 @@
+package collector
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCollector_DeleteMetric(t *testing.T) {
+	c := collector{[]StoredMetric{
+		{ID: "Mistake", MType: "gauge", GaugeValue: PtrFloat64(1), TextValue: PtrString("1")},
+		{ID: "PollCount", MType: "counter", CounterValue: PtrInt64(5), TextValue: PtrString("5")},
+	}}
+	c.DrainTombstones()
+
+	assert.ErrorIs(t, c.DeleteMetric("Mistake", "counter"), ErrNotFound)
+	assert.ErrorIs(t, c.DeleteMetric("Unknown", "gauge"), ErrNotFound)
+	assert.NoError(t, c.DeleteMetric("Mistake", "gauge"))
+	assert.Equal(t, []StoredMetric{
+		{ID: "PollCount", MType: "counter", CounterValue: PtrInt64(5), TextValue: PtrString("5")},
+	}, c.Metrics)
+	assert.Equal(t, []string{"Mistake"}, c.DrainTombstones())
+}
+
+func TestCollector_ResetMetric(t *testing.T) {
+	c := collector{[]StoredMetric{
+		{ID: "PollCount", MType: "counter", CounterValue: PtrInt64(5), TextValue: PtrString("5")},
+		{ID: "Temperature", MType: "gauge", GaugeValue: PtrFloat64(-3), TextValue: PtrString("-3")},
+		{ID: "Latency", MType: "histogram", HistogramValue: &HistogramValue{Count: 1, Sum: 0.5, Bounds: []float64{1}, Buckets: []uint64{1, 0}}},
+	}}
+
+	assert.ErrorIs(t, c.ResetMetric("PollCount", "gauge"), ErrNotFound)
+	assert.ErrorIs(t, c.ResetMetric("Unknown", "counter"), ErrNotFound)
+	for _, m := range c.Metrics {
+		assert.NoError(t, c.ResetMetric(m.ID, m.MType))
+	}
+	assert.Equal(t, []StoredMetric{
+		{ID: "PollCount", MType: "counter", CounterValue: PtrInt64(0), TextValue: PtrString("0")},
+		{ID: "Temperature", MType: "gauge", GaugeValue: PtrFloat64(0), TextValue: PtrString("0")},
+		{
+			ID:             "Latency",
+			MType:          "histogram",
+			HistogramValue: &HistogramValue{Bounds: []float64{1}, Buckets: []uint64{0, 0}},
+			TextValue:      PtrString(`{"count":0,"sum":0,"bounds":[1],"buckets":[0,0]}`),
+		},
+	}, c.Metrics)
+}
@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReservedPrefix - префикс имен собственных метрик сервера.
+// Агенты не могут записывать метрики с этим префиксом, такие метрики не учитываются в лимите числа серий.
+const ReservedPrefix = "server_"
+
+// IsReserved сообщает, относится ли метрика к собственным метрикам сервера.
+func IsReserved(metricName string) bool {
+	return strings.HasPrefix(metricName, ReservedPrefix)
+}
+
+// AddCounter увеличивает счетчик на delta без проверки лимитов.
+// Используется для собственных счетчиков сервера и для счетчиков, накапливаемых агентом до отправки.
+func (c *collector) AddCounter(metricName string, delta int64) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	c.addCounter(metricName, delta)
+}
+
+// SetGauge устанавливает значение собственной метрики сервера типа gauge без проверки лимитов.
+func (c *collector) SetGauge(metricName string, value float64) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	c.upsertMetric(StoredMetric{
+		ID:         metricName,
+		MType:      Gauge,
+		GaugeValue: PtrFloat64(value),
+		TextValue:  PtrString(FormatGauge(value)),
+	})
+	recordSample(metricName, value, time.Now())
+	touch(metricName)
+}
+
+// ObserveHistogram добавляет наблюдение в собственную гистограмму сервера с корзинами по умолчанию
+// без проверки лимитов.
+func (c *collector) ObserveHistogram(metricName string, v float64) {
+	collectMu.Lock()
+	defer collectMu.Unlock()
+	m, _ := c.getMetric(metricName)
+	var value *HistogramValue
+	if m.MType == Histogram && m.HistogramValue != nil {
+		value = m.HistogramValue.Clone()
+	} else {
+		value = NewHistogram(DefaultBuckets)
+	}
+	if err := value.Observe(v); err != nil {
+		return
+	}
+	c.upsertMetric(StoredMetric{
+		ID:             metricName,
+		MType:          Histogram,
+		HistogramValue: value,
+		TextValue:      PtrString(value.String()),
+	})
+	touch(metricName)
+}
+
+// Snapshot возвращает копию списка метрик, согласованную с одновременными записями.
+func (c *collector) Snapshot() []StoredMetric {
+	collectMu.RLock()
+	defer collectMu.RUnlock()
+	metrics := make([]StoredMetric, len(c.Metrics))
+	copy(metrics, c.Metrics)
+	return metrics
+}
+
+// addCounter увеличивает собственный счетчик сервера, вызывается под collectMu.
+func (c *collector) addCounter(metricName string, delta int64) {
+	m, _ := c.getMetric(metricName)
+	value := delta
+	if m.MType == Counter && m.CounterValue != nil {
+		value += *m.CounterValue
+	}
+	c.upsertMetric(StoredMetric{
+		ID:           metricName,
+		MType:        Counter,
+		CounterValue: PtrInt64(value),
+		TextValue:    PtrString(strconv.FormatInt(value, 10)),
+	})
+	recordSample(metricName, float64(value), time.Now())
+	touch(metricName)
+}
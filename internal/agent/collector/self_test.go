@@ -0,0 +1,30 @@
+package collector
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCollector_SelfMetrics(t *testing.T) {
+	c := collector{[]StoredMetric{}}
+	assert.ErrorIs(t, c.Collect(MetricRequest{ID: "server_fake", MType: "counter", Delta: PtrInt64(1)}, "1"), ErrBadRequest)
+
+	c.AddCounter("server_requests", 2)
+	c.AddCounter("server_requests", 3)
+	c.SetGauge("server_series", 1.5)
+	c.ObserveHistogram("server_duration", 0.2)
+	c.ObserveHistogram("server_duration", 3)
+
+	assert.Equal(t, []StoredMetric{
+		{ID: "server_requests", MType: "counter", CounterValue: PtrInt64(5), TextValue: PtrString("5")},
+		{ID: "server_series", MType: "gauge", GaugeValue: PtrFloat64(1.5), TextValue: PtrString("1.5")},
+	}, c.Snapshot()[:2])
+	h, err := c.GetMetric("server_duration")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), h.HistogramValue.Count)
+	assert.Equal(t, 3.2, h.HistogramValue.Sum)
+
+	// собственные метрики сервера не учитываются в лимите числа серий
+	assert.Zero(t, c.seriesCount())
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package logtail
+
+import "os"
+
+// fileID возвращает 0: на этих платформах ротация определяется только по уменьшению размера файла.
+func fileID(fi os.FileInfo) uint64 {
+	return 0
+}
@@ -0,0 +1,328 @@
+// Package logtail читает файлы логов по мере их дописывания и извлекает из строк метрики
+// по правилам с регулярными выражениями. Ротация (замена файла) и усечение файла
+// обрабатываются, а позиции чтения сохраняются в файл, чтобы после перезапуска агента
+// уже учтенные строки не учитывались повторно.
+package logtail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"go.uber.org/zap"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Интервал проверки файлов на новые строки
+const pollInterval = time.Second
+
+// rule - подготовленное правило извлечения метрики.
+type rule struct {
+	re     *regexp.Regexp
+	metric string
+	mtype  string
+	group  int // индекс группы со значением, -1 если группа не задана
+}
+
+// position - позиция чтения файла: идентификатор (inode) и смещение после последней полной строки.
+type position struct {
+	ID     uint64 `json:"id"`
+	Offset int64  `json:"offset"`
+}
+
+// file - читаемый файл лога.
+type file struct {
+	path    string
+	rules   []rule
+	handle  *os.File
+	id      uint64
+	offset  int64
+	partial []byte // незавершенная последняя строка
+	opened  bool   // файл уже открывался с момента запуска агента
+}
+
+// Tailer читает файлы логов и накапливает извлеченные метрики до следующего опроса агента.
+type Tailer struct {
+	files          []*file
+	checkpointPath string
+	checkpoint     map[string]position
+	buffer         *collector.Buffer
+	log            *zap.SugaredLogger
+}
+
+// New проверяет описания файлов и правил и создает Tailer.
+// checkpointPath - файл с позициями чтения; пустое значение отключает сохранение позиций.
+func New(cfg []flags.LogFile, checkpointPath string, log *zap.SugaredLogger) (*Tailer, error) {
+	files := make([]*file, 0, len(cfg))
+	for i, c := range cfg {
+		if c.Path == "" {
+			return nil, fmt.Errorf("log file #%d: path is empty", i)
+		}
+		f := &file{path: c.Path}
+		for j, r := range c.Rules {
+			parsed, err := newRule(r)
+			if err != nil {
+				return nil, fmt.Errorf("log file %q, rule #%d: %w", c.Path, j, err)
+			}
+			f.rules = append(f.rules, parsed)
+		}
+		files = append(files, f)
+	}
+	return &Tailer{
+		files:          files,
+		checkpointPath: checkpointPath,
+		checkpoint:     make(map[string]position),
+		buffer:         collector.NewBuffer(),
+		log:            log,
+	}, nil
+}
+
+// newRule проверяет правило и компилирует его регулярное выражение.
+func newRule(r flags.LogRule) (rule, error) {
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return rule{}, fmt.Errorf("error while compiling pattern: %w", err)
+	}
+	if r.Metric == "" {
+		return rule{}, fmt.Errorf("metric name is empty")
+	}
+	parsed := rule{re: re, metric: r.Metric, mtype: r.Type, group: -1}
+	if r.ValueGroup != "" {
+		if parsed.group = re.SubexpIndex(r.ValueGroup); parsed.group < 0 {
+			return rule{}, fmt.Errorf("pattern has no group %q", r.ValueGroup)
+		}
+	}
+	switch r.Type {
+	case collector.Counter:
+	case collector.Gauge:
+		if parsed.group < 0 {
+			return rule{}, fmt.Errorf("gauge rule requires value_group")
+		}
+	default:
+		return rule{}, fmt.Errorf("unsupported metric type %q", r.Type)
+	}
+	return parsed, nil
+}
+
+// Start загружает сохраненные позиции и читает файлы до отмены контекста.
+// Файл без сохраненной позиции (или замененный с момента сохранения) читается с конца.
+func (t *Tailer) Start(ctx context.Context) error {
+	if len(t.files) == 0 {
+		return nil
+	}
+	if err := t.loadCheckpoint(); err != nil {
+		return err
+	}
+	go func() {
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			t.poll()
+			select {
+			case <-ctx.Done():
+				t.close()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Drain возвращает метрики, накопленные с предыдущего вызова.
+func (t *Tailer) Drain() []collector.MetricRequest {
+	return t.buffer.Drain()
+}
+
+// poll читает новые строки всех файлов и сохраняет позиции чтения.
+func (t *Tailer) poll() {
+	for _, f := range t.files {
+		if err := t.pollFile(f); err != nil {
+			t.log.Errorf("error while reading log %q: %s", f.path, err.Error())
+		}
+	}
+	if err := t.saveCheckpoint(); err != nil {
+		t.log.Errorf("error while saving log checkpoint: %s", err.Error())
+	}
+}
+
+// pollFile обрабатывает ротацию и усечение файла и читает его новые строки.
+func (t *Tailer) pollFile(f *file) error {
+	fi, err := os.Stat(f.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// файл переименован при ротации, а новый еще не создан: дочитываем старый
+			if f.handle != nil {
+				return t.read(f)
+			}
+			return nil
+		}
+		return err
+	}
+	if f.handle != nil && fileID(fi) != f.id {
+		// ротация: дочитываем старый файл и переходим к новому с начала
+		if err = t.read(f); err != nil {
+			return err
+		}
+		f.handle.Close()
+		f.handle = nil
+	}
+	if f.handle == nil {
+		if err = t.open(f, fi); err != nil {
+			return err
+		}
+	}
+	if fi.Size() < f.offset {
+		// файл усечен: читаем с начала
+		f.offset = 0
+		f.partial = nil
+	}
+	return t.read(f)
+}
+
+// open открывает файл и определяет начальную позицию чтения.
+func (t *Tailer) open(f *file, fi os.FileInfo) error {
+	handle, err := os.Open(f.path)
+	if err != nil {
+		return err
+	}
+	f.handle = handle
+	f.id = fileID(fi)
+	f.partial = nil
+	switch cp, ok := t.checkpoint[f.path]; {
+	case f.opened:
+		// новый файл после ротации
+		f.offset = 0
+	case ok && cp.ID == f.id && cp.Offset <= fi.Size():
+		f.offset = cp.Offset
+	default:
+		f.offset = fi.Size()
+	}
+	f.opened = true
+	return nil
+}
+
+// read читает данные файла от текущей позиции и применяет правила к полным строкам.
+func (t *Tailer) read(f *file) error {
+	if _, err := f.handle.Seek(f.offset, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := io.ReadAll(f.handle)
+	if err != nil {
+		return err
+	}
+	f.offset += int64(len(data))
+	data = append(f.partial, data...)
+	last := bytes.LastIndexByte(data, '\n')
+	f.partial = append([]byte(nil), data[last+1:]...)
+	if last < 0 {
+		return nil
+	}
+	for _, line := range bytes.Split(data[:last], []byte("\n")) {
+		t.apply(f, string(bytes.TrimSuffix(line, []byte("\r"))))
+	}
+	return nil
+}
+
+// apply применяет правила файла к строке и добавляет полученные метрики в буфер.
+func (t *Tailer) apply(f *file, line string) {
+	for _, r := range f.rules {
+		match := r.re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		metric := collector.MetricRequest{ID: r.metric, MType: r.mtype}
+		switch r.mtype {
+		case collector.Counter:
+			delta := int64(1)
+			if r.group >= 0 {
+				v, err := strconv.ParseInt(match[r.group], 10, 64)
+				if err != nil || v < 0 {
+					t.log.Warnf("log %q: bad counter value %q for %q", f.path, match[r.group], r.metric)
+					continue
+				}
+				delta = v
+			}
+			metric.Delta = &delta
+		case collector.Gauge:
+			v, err := strconv.ParseFloat(match[r.group], 64)
+			if err != nil {
+				t.log.Warnf("log %q: bad gauge value %q for %q", f.path, match[r.group], r.metric)
+				continue
+			}
+			metric.Value = &v
+		}
+		if err := t.buffer.Add(metric); err != nil {
+			t.log.Errorf("error while adding metric %q: %s", r.metric, err.Error())
+		}
+	}
+}
+
+// loadCheckpoint загружает позиции чтения, сохраненные при предыдущем запуске.
+func (t *Tailer) loadCheckpoint() error {
+	if t.checkpointPath == "" {
+		return nil
+	}
+	data, err := os.ReadFile(t.checkpointPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("error while reading log checkpoint: %w", err)
+	}
+	if err = json.Unmarshal(data, &t.checkpoint); err != nil {
+		return fmt.Errorf("error while parsing log checkpoint: %w", err)
+	}
+	return nil
+}
+
+// saveCheckpoint сохраняет позиции чтения открытых файлов.
+// Файл записывается через временный файл и переименование, чтобы не оставить его поврежденным.
+func (t *Tailer) saveCheckpoint() error {
+	if t.checkpointPath == "" {
+		return nil
+	}
+	for _, f := range t.files {
+		if f.opened {
+			t.checkpoint[f.path] = position{ID: f.id, Offset: f.offset - int64(len(f.partial))}
+		}
+	}
+	data, err := json.Marshal(t.checkpoint)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(t.checkpointPath), filepath.Base(t.checkpointPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), t.checkpointPath)
+}
+
+// close сохраняет позиции чтения и закрывает файлы.
+func (t *Tailer) close() {
+	if err := t.saveCheckpoint(); err != nil {
+		t.log.Errorf("error while saving log checkpoint: %s", err.Error())
+	}
+	for _, f := range t.files {
+		if f.handle != nil {
+			f.handle.Close()
+			f.handle = nil
+		}
+	}
+}
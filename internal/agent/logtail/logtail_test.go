@@ -0,0 +1,112 @@
+package logtail
+
+import (
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var testRules = []flags.LogRule{
+	{Pattern: `level=error`, Metric: "log_errors", Type: collector.Counter},
+	{Pattern: `latency=(?P<ms>[0-9.]+)`, Metric: "log_latency", Type: collector.Gauge, ValueGroup: "ms"},
+}
+
+func appendLog(t *testing.T, path, data string) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(data)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		name string
+		cfg  flags.LogFile
+	}{
+		{name: "negative: empty path", cfg: flags.LogFile{}},
+		{name: "negative: bad pattern", cfg: flags.LogFile{Path: "a.log", Rules: []flags.LogRule{{Pattern: "(", Metric: "m", Type: collector.Counter}}}},
+		{name: "negative: empty metric", cfg: flags.LogFile{Path: "a.log", Rules: []flags.LogRule{{Pattern: "x", Type: collector.Counter}}}},
+		{name: "negative: unknown group", cfg: flags.LogFile{Path: "a.log", Rules: []flags.LogRule{{Pattern: "x", Metric: "m", Type: collector.Counter, ValueGroup: "v"}}}},
+		{name: "negative: gauge without group", cfg: flags.LogFile{Path: "a.log", Rules: []flags.LogRule{{Pattern: "x", Metric: "m", Type: collector.Gauge}}}},
+		{name: "negative: bad type", cfg: flags.LogFile{Path: "a.log", Rules: []flags.LogRule{{Pattern: "x", Metric: "m", Type: "histogram"}}}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]flags.LogFile{tt.cfg}, "", zap.NewNop().Sugar())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestTailer_poll(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	checkpoint := filepath.Join(dir, "offsets.json")
+	appendLog(t, path, "level=error old line\n")
+
+	tailer, err := New([]flags.LogFile{{Path: path, Rules: testRules}}, checkpoint, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	require.NoError(t, tailer.loadCheckpoint())
+
+	// существующее содержимое файла без сохраненной позиции пропускается
+	tailer.poll()
+	assert.Empty(t, tailer.Drain())
+
+	appendLog(t, path, "level=error a\nlevel=info latency=12.5\nlevel=error latency=")
+	tailer.poll()
+	assert.Equal(t, []collector.MetricRequest{
+		{ID: "log_errors", MType: collector.Counter, Delta: collector.PtrInt64(1)},
+		{ID: "log_latency", MType: collector.Gauge, Value: collector.PtrFloat64(12.5)},
+	}, tailer.Drain())
+
+	// незавершенная строка учитывается после перезапуска с сохраненной позиции
+	tailer.close()
+	appendLog(t, path, "30\n")
+	tailer, err = New([]flags.LogFile{{Path: path, Rules: testRules}}, checkpoint, zap.NewNop().Sugar())
+	require.NoError(t, err)
+	require.NoError(t, tailer.loadCheckpoint())
+	tailer.poll()
+	assert.Equal(t, []collector.MetricRequest{
+		{ID: "log_errors", MType: collector.Counter, Delta: collector.PtrInt64(1)},
+		{ID: "log_latency", MType: collector.Gauge, Value: collector.PtrFloat64(30)},
+	}, tailer.Drain())
+
+	// усечение: файл читается с начала
+	require.NoError(t, os.Truncate(path, 0))
+	tailer.poll()
+	appendLog(t, path, "level=error b\n")
+	tailer.poll()
+	assert.Equal(t, []collector.MetricRequest{
+		{ID: "log_errors", MType: collector.Counter, Delta: collector.PtrInt64(1)},
+	}, tailer.Drain())
+
+	// ротация: старый файл дочитывается, новый читается с начала
+	appendLog(t, path, "level=error c\n")
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendLog(t, path, "level=error d\n")
+	tailer.poll()
+	assert.Equal(t, []collector.MetricRequest{
+		{ID: "log_errors", MType: collector.Counter, Delta: collector.PtrInt64(2)},
+	}, tailer.Drain())
+	tailer.close()
+}
+
+func TestTailer_applyCounterGroup(t *testing.T) {
+	rules := []flags.LogRule{{Pattern: `bytes=(?P<n>-?[0-9]+)`, Metric: "log_bytes", Type: collector.Counter, ValueGroup: "n"}}
+	tailer, err := New([]flags.LogFile{{Path: "app.log", Rules: rules}}, "", zap.NewNop().Sugar())
+	require.NoError(t, err)
+
+	// отрицательное приращение counter отклоняется сервером, поэтому строка пропускается
+	for _, line := range []string{"bytes=10", "bytes=-5", "bytes=7"} {
+		tailer.apply(tailer.files[0], line)
+	}
+	assert.Equal(t, []collector.MetricRequest{
+		{ID: "log_bytes", MType: collector.Counter, Delta: collector.PtrInt64(17)},
+	}, tailer.Drain())
+}
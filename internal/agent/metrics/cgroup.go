@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Счетчики из cpu.stat и соответствующие им метрики.
+var cgroupCPUStatMetrics = map[string]string{
+	"usage_usec":     "CgroupCPUUsageUsec",
+	"user_usec":      "CgroupCPUUserUsec",
+	"system_usec":    "CgroupCPUSystemUsec",
+	"nr_periods":     "CgroupCPUPeriods",
+	"nr_throttled":   "CgroupCPUThrottledPeriods",
+	"throttled_usec": "CgroupCPUThrottledUsec",
+}
+
+// Счетчики из io.stat и соответствующие им метрики (суммируются по всем устройствам).
+var cgroupIOStatMetrics = map[string]string{
+	"rbytes": "CgroupIOReadBytes",
+	"wbytes": "CgroupIOWriteBytes",
+	"rios":   "CgroupIOReads",
+	"wios":   "CgroupIOWrites",
+}
+
+// WithCgroupPath задает каталог cgroup v2, метрики которого собирает CgroupMetricStore.
+// Пустой путь отключает сбор метрик контейнера.
+func WithCgroupPath(path string) Option {
+	return func(st *Storage) {
+		st.cgroupPath = path
+	}
+}
+
+// CgroupMetricStore метод для сбора и сохранения метрик контейнера из файлов cgroup v2:
+// memory.current, memory.max, cpu.stat, io.stat, pids.current и pids.max.
+// Если каталог не является cgroup v2 (нет cgroup.controllers), метод ничего не делает.
+// Отсутствующие файлы (например, в корневой cgroup) пропускаются, лимит "max" не сохраняется.
+func (st *Storage) CgroupMetricStore() error {
+	if st.cgroupPath == "" {
+		return nil
+	}
+	if _, err := os.Stat(filepath.Join(st.cgroupPath, "cgroup.controllers")); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("error while checking cgroup v2: %w", err)
+	}
+	return errors.Join(
+		st.storeCgroupMemory(),
+		st.storeCgroupCPU(),
+		st.storeCgroupIO(),
+		st.storeCgroupPids(),
+	)
+}
+
+// storeCgroupMemory сохраняет текущее потребление памяти контейнером и его лимит.
+func (st *Storage) storeCgroupMemory() error {
+	current, ok, err := st.readCgroupValue("memory.current")
+	if err != nil || !ok {
+		return err
+	}
+	st.upsertGauge("CgroupMemoryCurrent", float64(current))
+
+	limit, ok, err := st.readCgroupValue("memory.max")
+	if err != nil || !ok {
+		return err
+	}
+	st.upsertGauge("CgroupMemoryMax", float64(limit))
+	if limit > 0 {
+		st.upsertGauge("CgroupMemoryUsedPercent", float64(current)/float64(limit)*100)
+	}
+	return nil
+}
+
+// storeCgroupCPU сохраняет время CPU контейнера и статистику троттлинга.
+func (st *Storage) storeCgroupCPU() error {
+	data, err := st.readCgroupFile("cpu.stat")
+	if err != nil || data == nil {
+		return err
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		id, ok := cgroupCPUStatMetrics[fields[0]]
+		if !ok {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("error while parsing cpu.stat %q: %w", fields[0], err)
+		}
+		st.upsertCumulative(id, value)
+	}
+	return scanner.Err()
+}
+
+// storeCgroupIO сохраняет счетчики ввода-вывода контейнера, суммированные по всем устройствам.
+func (st *Storage) storeCgroupIO() error {
+	data, err := st.readCgroupFile("io.stat")
+	if err != nil || data == nil {
+		return err
+	}
+	totals := make(map[string]uint64, len(cgroupIOStatMetrics))
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// формат строки: "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		for _, field := range fields[1:] {
+			key, rawValue, found := strings.Cut(field, "=")
+			if _, ok := cgroupIOStatMetrics[key]; !found || !ok {
+				continue
+			}
+			value, err := strconv.ParseUint(rawValue, 10, 64)
+			if err != nil {
+				return fmt.Errorf("error while parsing io.stat %q: %w", field, err)
+			}
+			totals[key] += value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for key, id := range cgroupIOStatMetrics {
+		st.upsertCumulative(id, totals[key])
+	}
+	return nil
+}
+
+// storeCgroupPids сохраняет количество процессов контейнера и их лимит.
+func (st *Storage) storeCgroupPids() error {
+	current, ok, err := st.readCgroupValue("pids.current")
+	if err != nil || !ok {
+		return err
+	}
+	st.upsertGauge("CgroupPidsCurrent", float64(current))
+
+	limit, ok, err := st.readCgroupValue("pids.max")
+	if err != nil || !ok {
+		return err
+	}
+	st.upsertGauge("CgroupPidsMax", float64(limit))
+	return nil
+}
+
+// readCgroupValue читает файл cgroup с одним числовым значением.
+// ok равен false, если файла нет или значение равно "max" (лимит не установлен).
+func (st *Storage) readCgroupValue(name string) (value uint64, ok bool, err error) {
+	data, err := st.readCgroupFile(name)
+	if err != nil || data == nil {
+		return 0, false, err
+	}
+	raw := strings.TrimSpace(string(data))
+	if raw == "max" {
+		return 0, false, nil
+	}
+	value, err = strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("error while parsing %s: %w", name, err)
+	}
+	return value, true, nil
+}
+
+// readCgroupFile читает файл из каталога cgroup, возвращая nil без ошибки для отсутствующего файла.
+func (st *Storage) readCgroupFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(st.cgroupPath, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while reading %s: %w", name, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	collector2 "github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyCgroupFixture копирует каталог testdata/cgroup, заменяющий /sys/fs/cgroup, во временный каталог.
+func copyCgroupFixture(t *testing.T) string {
+	dir := t.TempDir()
+	entries, err := os.ReadDir("testdata/cgroup")
+	assert.NoError(t, err)
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join("testdata/cgroup", e.Name()))
+		assert.NoError(t, err)
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, e.Name()), data, 0644))
+	}
+	return dir
+}
+
+func TestStorage_CgroupMetricStore(t *testing.T) {
+	dir := copyCgroupFixture(t)
+	metricsCollector := collector2.Collector()
+	metricsCollector.Metrics = []collector2.StoredMetric{}
+	metricsStore := New(metricsCollector, WithCgroupPath(dir))
+
+	assert.NoError(t, metricsStore.CgroupMetricStore())
+	gauges := map[string]float64{
+		"CgroupMemoryCurrent":     268435456,
+		"CgroupMemoryMax":         536870912,
+		"CgroupMemoryUsedPercent": 50,
+		"CgroupPidsCurrent":       12,
+	}
+	for id, expected := range gauges {
+		m, err := metricsCollector.GetMetric(id)
+		assert.NoError(t, err, id)
+		assert.Equal(t, expected, *m.GaugeValue, id)
+	}
+	// лимит "max" не сохраняется
+	_, err := metricsCollector.GetMetric("CgroupPidsMax")
+	assert.ErrorIs(t, err, collector2.ErrNotFound)
+
+	// счетчики растут на прирост между опросами
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "cpu.stat"),
+		[]byte("usage_usec 2500000\nuser_usec 1600000\nsystem_usec 900000\nnr_periods 110\nnr_throttled 9\nthrottled_usec 45000\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "io.stat"),
+		[]byte("8:0 rbytes=8192 wbytes=8192 rios=2 wios=2 dbytes=0 dios=0\n253:0 rbytes=1024 wbytes=0 rios=1 wios=0 dbytes=0 dios=0\n"), 0644))
+	assert.NoError(t, metricsStore.CgroupMetricStore())
+	counters := map[string]int64{
+		"CgroupCPUUsageUsec":        1000000,
+		"CgroupCPUThrottledPeriods": 2,
+		"CgroupCPUThrottledUsec":    10000,
+		"CgroupIOReadBytes":         4096,
+		"CgroupIOWriteBytes":        0,
+		"CgroupIOReads":             1,
+	}
+	for id, expected := range counters {
+		m, err := metricsCollector.GetMetric(id)
+		assert.NoError(t, err, id)
+		assert.Equal(t, collector2.Counter, m.MType, id)
+		assert.Equal(t, expected, *m.CounterValue, id)
+	}
+}
+
+func TestStorage_CgroupMetricStoreNotCgroupV2(t *testing.T) {
+	metricsCollector := collector2.Collector()
+	metricsCollector.Metrics = []collector2.StoredMetric{}
+	metricsStore := New(metricsCollector, WithCgroupPath(t.TempDir()))
+	assert.NoError(t, metricsStore.CgroupMetricStore())
+	assert.Empty(t, metricsCollector.GetAvailableMetrics())
+}
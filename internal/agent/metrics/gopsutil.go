@@ -0,0 +1,150 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/net"
+	"strconv"
+	"strings"
+)
+
+// GopsutilMetricStore метод для сбора и сохранения системных метрик gopsutil:
+// память и swap, загрузка каждого CPU, средняя загрузка, количество процессов,
+// использование дисков по точкам монтирования, счетчики дискового и сетевого ввода-вывода.
+// Ошибка одного источника не мешает сбору остальных, все ошибки возвращаются вместе.
+func (st *Storage) GopsutilMetricStore() error {
+	return errors.Join(
+		st.storeMemory(),
+		st.storeCPU(),
+		st.storeLoad(),
+		st.storeDiskUsage(),
+		st.storeDiskIO(),
+		st.storeNetIO(),
+	)
+}
+
+// storeMemory сохраняет метрики оперативной памяти и swap.
+func (st *Storage) storeMemory() error {
+	v, err := mem.VirtualMemory()
+	if err != nil {
+		return fmt.Errorf("error while reading virtual memory: %w", err)
+	}
+	st.upsertGauge("FreeMemory", float64(v.Free))
+	st.upsertGauge("TotalMemory", float64(v.Total))
+
+	s, err := mem.SwapMemory()
+	if err != nil {
+		return fmt.Errorf("error while reading swap memory: %w", err)
+	}
+	st.upsertGauge("SwapTotal", float64(s.Total))
+	st.upsertGauge("SwapUsed", float64(s.Used))
+	st.upsertGauge("SwapFree", float64(s.Free))
+	return nil
+}
+
+// storeCPU сохраняет загрузку каждого CPU в метриках CPUutilization1..N.
+func (st *Storage) storeCPU() error {
+	percents, err := cpu.Percent(0, true)
+	if err != nil {
+		return fmt.Errorf("error while reading cpu utilization: %w", err)
+	}
+	for i, p := range percents {
+		st.upsertGauge("CPUutilization"+strconv.Itoa(i+1), p)
+	}
+	return nil
+}
+
+// storeLoad сохраняет среднюю загрузку системы и количество процессов.
+func (st *Storage) storeLoad() error {
+	avg, err := load.Avg()
+	if err != nil {
+		return fmt.Errorf("error while reading load average: %w", err)
+	}
+	st.upsertGauge("Load1", avg.Load1)
+	st.upsertGauge("Load5", avg.Load5)
+	st.upsertGauge("Load15", avg.Load15)
+
+	misc, err := load.Misc()
+	if err != nil {
+		return fmt.Errorf("error while reading process counts: %w", err)
+	}
+	st.upsertGauge("ProcsTotal", float64(misc.ProcsTotal))
+	st.upsertGauge("ProcsRunning", float64(misc.ProcsRunning))
+	st.upsertGauge("ProcsBlocked", float64(misc.ProcsBlocked))
+	return nil
+}
+
+// storeDiskUsage сохраняет использование диска для каждой физической точки монтирования.
+func (st *Storage) storeDiskUsage() error {
+	partitions, err := disk.Partitions(false)
+	if err != nil {
+		return fmt.Errorf("error while reading disk partitions: %w", err)
+	}
+	var errs []error
+	for _, p := range partitions {
+		usage, err := disk.Usage(p.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error while reading disk usage of %q: %w", p.Mountpoint, err))
+			continue
+		}
+		suffix := metricSuffix(p.Mountpoint)
+		st.upsertGauge("DiskTotal_"+suffix, float64(usage.Total))
+		st.upsertGauge("DiskUsed_"+suffix, float64(usage.Used))
+		st.upsertGauge("DiskFree_"+suffix, float64(usage.Free))
+		st.upsertGauge("DiskUsedPercent_"+suffix, usage.UsedPercent)
+	}
+	return errors.Join(errs...)
+}
+
+// storeDiskIO сохраняет счетчики ввода-вывода для каждого блочного устройства.
+func (st *Storage) storeDiskIO() error {
+	counters, err := disk.IOCounters()
+	if err != nil {
+		return fmt.Errorf("error while reading disk io counters: %w", err)
+	}
+	for name, c := range counters {
+		suffix := metricSuffix(name)
+		st.upsertCumulative("DiskReadBytes_"+suffix, c.ReadBytes)
+		st.upsertCumulative("DiskWriteBytes_"+suffix, c.WriteBytes)
+		st.upsertCumulative("DiskReadCount_"+suffix, c.ReadCount)
+		st.upsertCumulative("DiskWriteCount_"+suffix, c.WriteCount)
+	}
+	return nil
+}
+
+// storeNetIO сохраняет счетчики трафика для каждого сетевого интерфейса.
+func (st *Storage) storeNetIO() error {
+	counters, err := net.IOCounters(true)
+	if err != nil {
+		return fmt.Errorf("error while reading network io counters: %w", err)
+	}
+	for _, c := range counters {
+		suffix := metricSuffix(c.Name)
+		st.upsertCumulative("NetBytesSent_"+suffix, c.BytesSent)
+		st.upsertCumulative("NetBytesRecv_"+suffix, c.BytesRecv)
+		st.upsertCumulative("NetPacketsSent_"+suffix, c.PacketsSent)
+		st.upsertCumulative("NetPacketsRecv_"+suffix, c.PacketsRecv)
+		st.upsertCumulative("NetErrIn_"+suffix, c.Errin)
+		st.upsertCumulative("NetErrOut_"+suffix, c.Errout)
+	}
+	return nil
+}
+
+// metricSuffix приводит имя устройства или точки монтирования к виду,
+// допустимому в имени метрики: "/" превращается в "root", "/var/lib" в "var_lib".
+func metricSuffix(name string) string {
+	suffix := strings.Trim(strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name), "_")
+	if suffix == "" {
+		return "root"
+	}
+	return suffix
+}
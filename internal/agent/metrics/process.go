@@ -0,0 +1,242 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"github.com/shirou/gopsutil/v3/process"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ProcessTarget - процесс, метрики которого собирает агент, с подготовленными условиями поиска.
+type ProcessTarget struct {
+	label   string
+	name    string
+	pidFile string
+	cmdline *regexp.Regexp
+}
+
+// trackedProcess - найденный процесс целевой группы и его счетчики ввода-вывода на момент предыдущего опроса.
+type trackedProcess struct {
+	proc       *process.Process
+	createTime int64
+	readBytes  uint64
+	writeBytes uint64
+}
+
+// processStats - суммарные показатели процессов одной целевой группы за опрос.
+type processStats struct {
+	count      int
+	cpuPercent float64
+	rss        uint64
+	fds        int32
+	threads    int32
+	readBytes  int64
+	writeBytes int64
+}
+
+// NewProcessTargets проверяет описания процессов из конфигурации агента и подготавливает их к поиску.
+// Для каждого процесса должен быть задан ровно один признак поиска: имя, PID-файл или cmdline.
+// Если метка не указана, используется имя процесса.
+func NewProcessTargets(cfg []flags.ProcessTarget) ([]ProcessTarget, error) {
+	targets := make([]ProcessTarget, 0, len(cfg))
+	labels := make(map[string]struct{}, len(cfg))
+	for i, c := range cfg {
+		matchers := 0
+		for _, m := range []string{c.Name, c.PidFile, c.Cmdline} {
+			if m != "" {
+				matchers++
+			}
+		}
+		if matchers != 1 {
+			return nil, fmt.Errorf("process #%d: exactly one of name, pid_file or cmdline must be set", i)
+		}
+		label := c.Label
+		if label == "" {
+			label = c.Name
+		}
+		if label == "" {
+			return nil, fmt.Errorf("process #%d: label is required when matching by pid_file or cmdline", i)
+		}
+		label = metricSuffix(label)
+		if _, ok := labels[label]; ok {
+			return nil, fmt.Errorf("process #%d: duplicate label %q", i, label)
+		}
+		labels[label] = struct{}{}
+
+		target := ProcessTarget{label: label, name: c.Name, pidFile: c.PidFile}
+		if c.Cmdline != "" {
+			re, err := regexp.Compile(c.Cmdline)
+			if err != nil {
+				return nil, fmt.Errorf("process #%d: error while compiling cmdline regexp: %w", i, err)
+			}
+			target.cmdline = re
+		}
+		targets = append(targets, target)
+	}
+	return targets, nil
+}
+
+// WithProcesses задает процессы, метрики которых собирает ProcessMetricStore.
+func WithProcesses(targets []ProcessTarget) Option {
+	return func(st *Storage) {
+		st.processTargets = targets
+	}
+}
+
+// ProcessMetricStore метод для сбора и сохранения метрик целевых процессов.
+// Процессы ищутся заново при каждом опросе, поэтому перезапуск процесса или смена PID
+// подхватываются автоматически. Метрики группы суммируются по всем найденным процессам
+// и сохраняются с меткой группы в имени, например ProcessRSS_nginx.
+func (st *Storage) ProcessMetricStore() error {
+	if len(st.processTargets) == 0 {
+		return nil
+	}
+	var (
+		errs    []error
+		running []*process.Process
+		scanned bool
+	)
+	for _, t := range st.processTargets {
+		var procs []*process.Process
+		if t.pidFile != "" {
+			p, err := findByPidFile(t.pidFile)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("process %q: %w", t.label, err))
+				continue
+			}
+			if p != nil {
+				procs = append(procs, p)
+			}
+		} else {
+			if !scanned {
+				var err error
+				if running, err = process.Processes(); err != nil {
+					return fmt.Errorf("error while listing processes: %w", err)
+				}
+				scanned = true
+			}
+			procs = t.match(running)
+		}
+		if err := st.storeProcessGroup(t.label, procs); err != nil {
+			errs = append(errs, fmt.Errorf("process %q: %w", t.label, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// match отбирает процессы, подходящие по имени или по регулярному выражению для командной строки.
+func (t ProcessTarget) match(running []*process.Process) []*process.Process {
+	result := make([]*process.Process, 0)
+	for _, p := range running {
+		if t.name != "" {
+			if name, err := p.Name(); err == nil && name == t.name {
+				result = append(result, p)
+			}
+			continue
+		}
+		if cmdline, err := p.Cmdline(); err == nil && cmdline != "" && t.cmdline.MatchString(cmdline) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// findByPidFile возвращает процесс, PID которого записан в файле.
+// Если файла нет или процесс не запущен, возвращается nil без ошибки.
+func findByPidFile(path string) (*process.Process, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error while reading pid file: %w", err)
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing pid file %q: %w", path, err)
+	}
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return nil, nil
+	}
+	return p, nil
+}
+
+// storeProcessGroup сохраняет суммарные метрики группы процессов.
+// Счетчики ввода-вывода считаются по приросту каждого процесса: у процесса, появившегося
+// после первого опроса группы (например, после перезапуска), учитывается весь его ввод-вывод.
+func (st *Storage) storeProcessGroup(label string, procs []*process.Process) error {
+	prev, seen := st.trackedProcesses[label]
+	current := make(map[int32]*trackedProcess, len(procs))
+	var (
+		stats processStats
+		errs  []error
+	)
+	for _, p := range procs {
+		createTime, err := p.CreateTime()
+		if err != nil {
+			// процесс завершился между поиском и опросом
+			continue
+		}
+		tp, ok := prev[p.Pid]
+		if !ok || tp.createTime != createTime {
+			tp = &trackedProcess{proc: p, createTime: createTime}
+			if !seen {
+				tp.readBytes, tp.writeBytes = ioBytes(p)
+			}
+		}
+		current[p.Pid] = tp
+		stats.count++
+
+		if cpuPercent, err := tp.proc.Percent(0); err == nil {
+			stats.cpuPercent += cpuPercent
+		}
+		if memInfo, err := tp.proc.MemoryInfo(); err == nil {
+			stats.rss += memInfo.RSS
+		} else {
+			errs = append(errs, fmt.Errorf("pid %d: error while reading memory info: %w", p.Pid, err))
+		}
+		if fds, err := tp.proc.NumFDs(); err == nil {
+			stats.fds += fds
+		} else {
+			errs = append(errs, fmt.Errorf("pid %d: error while reading open fds: %w", p.Pid, err))
+		}
+		if threads, err := tp.proc.NumThreads(); err == nil {
+			stats.threads += threads
+		}
+		if io, err := tp.proc.IOCounters(); err == nil {
+			if io.ReadBytes >= tp.readBytes {
+				stats.readBytes += int64(io.ReadBytes - tp.readBytes)
+			}
+			if io.WriteBytes >= tp.writeBytes {
+				stats.writeBytes += int64(io.WriteBytes - tp.writeBytes)
+			}
+			tp.readBytes, tp.writeBytes = io.ReadBytes, io.WriteBytes
+		} else {
+			errs = append(errs, fmt.Errorf("pid %d: error while reading io counters: %w", p.Pid, err))
+		}
+	}
+	st.trackedProcesses[label] = current
+
+	st.upsertGauge("ProcessCount_"+label, float64(stats.count))
+	st.upsertGauge("ProcessCPUPercent_"+label, stats.cpuPercent)
+	st.upsertGauge("ProcessRSS_"+label, float64(stats.rss))
+	st.upsertGauge("ProcessOpenFDs_"+label, float64(stats.fds))
+	st.upsertGauge("ProcessThreads_"+label, float64(stats.threads))
+	st.addCounter("ProcessReadBytes_"+label, stats.readBytes)
+	st.addCounter("ProcessWriteBytes_"+label, stats.writeBytes)
+	return errors.Join(errs...)
+}
+
+// ioBytes возвращает прочитанные и записанные процессом байты или нули, если счетчики недоступны.
+func ioBytes(p *process.Process) (uint64, uint64) {
+	io, err := p.IOCounters()
+	if err != nil {
+		return 0, 0
+	}
+	return io.ReadBytes, io.WriteBytes
+}
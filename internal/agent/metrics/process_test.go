@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	collector2 "github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestNewProcessTargets(t *testing.T) {
+	testCases := []struct {
+		name          string
+		cfg           []flags.ProcessTarget
+		expectedError bool
+	}{
+		{
+			name: "positive",
+			cfg: []flags.ProcessTarget{
+				{Name: "nginx"},
+				{Label: "api", PidFile: "/run/api.pid"},
+				{Label: "worker", Cmdline: "worker --queue=\\w+"},
+			},
+		},
+		{
+			name:          "negative: no matcher",
+			cfg:           []flags.ProcessTarget{{Label: "api"}},
+			expectedError: true,
+		},
+		{
+			name:          "negative: several matchers",
+			cfg:           []flags.ProcessTarget{{Name: "api", PidFile: "/run/api.pid"}},
+			expectedError: true,
+		},
+		{
+			name:          "negative: no label",
+			cfg:           []flags.ProcessTarget{{PidFile: "/run/api.pid"}},
+			expectedError: true,
+		},
+		{
+			name:          "negative: bad regexp",
+			cfg:           []flags.ProcessTarget{{Label: "api", Cmdline: "(api"}},
+			expectedError: true,
+		},
+		{
+			name:          "negative: duplicate label",
+			cfg:           []flags.ProcessTarget{{Name: "api"}, {Label: "api", PidFile: "/run/api.pid"}},
+			expectedError: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			targets, err := NewProcessTargets(tt.cfg)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, targets, len(tt.cfg))
+		})
+	}
+}
+
+func TestStorage_ProcessMetricStore(t *testing.T) {
+	pidFile := filepath.Join(t.TempDir(), "self.pid")
+	assert.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644))
+
+	targets, err := NewProcessTargets([]flags.ProcessTarget{
+		{Label: "self", PidFile: pidFile},
+		{Label: "missing", PidFile: filepath.Join(t.TempDir(), "missing.pid")},
+	})
+	assert.NoError(t, err)
+
+	metricsCollector := collector2.Collector()
+	metricsCollector.Metrics = []collector2.StoredMetric{}
+	metricsStore := New(metricsCollector, WithProcesses(targets))
+	for i := 0; i < 2; i++ {
+		if err := metricsStore.ProcessMetricStore(); err != nil {
+			t.Logf("some process metrics are unavailable: %s", err.Error())
+		}
+	}
+
+	count, err := metricsCollector.GetMetric("ProcessCount_self")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), *count.GaugeValue)
+	rss, err := metricsCollector.GetMetric("ProcessRSS_self")
+	assert.NoError(t, err)
+	assert.Greater(t, *rss.GaugeValue, float64(0))
+	threads, err := metricsCollector.GetMetric("ProcessThreads_self")
+	assert.NoError(t, err)
+	assert.Greater(t, *threads.GaugeValue, float64(0))
+	read, err := metricsCollector.GetMetric("ProcessReadBytes_self")
+	assert.NoError(t, err)
+	assert.Equal(t, collector2.Counter, read.MType)
+
+	missing, err := metricsCollector.GetMetric("ProcessCount_missing")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(0), *missing.GaugeValue)
+}
@@ -0,0 +1,237 @@
+package metrics
+
+import (
+	"math"
+	"math/rand"
+	"runtime/debug"
+	rtmetrics "runtime/metrics"
+)
+
+// Квантили, сохраняемые для метрик-гистограмм runtime/metrics.
+var histogramQuantiles = []struct {
+	suffix string
+	q      float64
+}{
+	{"_p50", 0.5},
+	{"_p90", 0.9},
+	{"_p99", 0.99},
+}
+
+// runtimeAlias описывает метрику со старым именем из runtime.MemStats,
+// значение которой вычисляется из метрик runtime/metrics.
+type runtimeAlias struct {
+	id      string
+	sources []string
+	value   func(v map[string]rtmetrics.Value) float64
+}
+
+// sumOf возвращает функцию, суммирующую значения перечисленных метрик runtime/metrics.
+func sumOf(names ...string) func(v map[string]rtmetrics.Value) float64 {
+	return func(v map[string]rtmetrics.Value) float64 {
+		var sum float64
+		for _, name := range names {
+			sum += scalar(v[name])
+		}
+		return sum
+	}
+}
+
+// alias создает совместимое имя, равное сумме перечисленных метрик runtime/metrics.
+func alias(id string, names ...string) runtimeAlias {
+	return runtimeAlias{id: id, sources: names, value: sumOf(names...)}
+}
+
+// runtimeAliases - совместимые имена метрик, которые агент отправлял на основе runtime.MemStats.
+// Соответствие полей MemStats и runtime/metrics взято из реализации runtime.ReadMemStats.
+var runtimeAliases = []runtimeAlias{
+	alias("Alloc", "/memory/classes/heap/objects:bytes"),
+	alias("BuckHashSys", "/memory/classes/profiling/buckets:bytes"),
+	alias("Frees", "/gc/heap/frees:objects", "/gc/heap/tiny/allocs:objects"),
+	{
+		id:      "GCCPUFraction",
+		sources: []string{"/cpu/classes/gc/total:cpu-seconds", "/cpu/classes/total:cpu-seconds"},
+		value: func(v map[string]rtmetrics.Value) float64 {
+			total := scalar(v["/cpu/classes/total:cpu-seconds"])
+			if total == 0 {
+				return 0
+			}
+			return scalar(v["/cpu/classes/gc/total:cpu-seconds"]) / total
+		},
+	},
+	alias("GCSys", "/memory/classes/metadata/other:bytes"),
+	alias("HeapAlloc", "/memory/classes/heap/objects:bytes"),
+	alias("HeapIdle", "/memory/classes/heap/released:bytes", "/memory/classes/heap/free:bytes"),
+	alias("HeapInuse", "/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes"),
+	alias("HeapObjects", "/gc/heap/objects:objects"),
+	alias("HeapReleased", "/memory/classes/heap/released:bytes"),
+	alias("HeapSys", "/memory/classes/heap/objects:bytes", "/memory/classes/heap/unused:bytes",
+		"/memory/classes/heap/free:bytes", "/memory/classes/heap/released:bytes"),
+	// поиск указателей рантаймом больше не выполняется, MemStats всегда возвращает 0
+	alias("Lookups"),
+	alias("MCacheInuse", "/memory/classes/metadata/mcache/inuse:bytes"),
+	alias("MCacheSys", "/memory/classes/metadata/mcache/inuse:bytes", "/memory/classes/metadata/mcache/free:bytes"),
+	alias("MSpanInuse", "/memory/classes/metadata/mspan/inuse:bytes"),
+	alias("MSpanSys", "/memory/classes/metadata/mspan/inuse:bytes", "/memory/classes/metadata/mspan/free:bytes"),
+	alias("Mallocs", "/gc/heap/allocs:objects", "/gc/heap/tiny/allocs:objects"),
+	alias("NextGC", "/gc/heap/goal:bytes"),
+	alias("NumForcedGC", "/gc/cycles/forced:gc-cycles"),
+	alias("NumGC", "/gc/cycles/total:gc-cycles"),
+	alias("OtherSys", "/memory/classes/other:bytes"),
+	{
+		id:      "PauseTotalNs",
+		sources: []string{"/sched/pauses/total/gc:seconds"},
+		value: func(v map[string]rtmetrics.Value) float64 {
+			return histogramSum(v["/sched/pauses/total/gc:seconds"]) * float64(1e9)
+		},
+	},
+	alias("StackInuse", "/memory/classes/heap/stacks:bytes"),
+	alias("StackSys", "/memory/classes/heap/stacks:bytes", "/memory/classes/os-stacks:bytes"),
+	alias("Sys", "/memory/classes/total:bytes"),
+	alias("TotalAlloc", "/gc/heap/allocs:bytes"),
+}
+
+// aliasSources - имена метрик runtime/metrics, необходимые для вычисления совместимых имен.
+var aliasSources = func() []string {
+	names := make([]string, 0)
+	for _, a := range runtimeAliases {
+		names = append(names, a.sources...)
+	}
+	return names
+}()
+
+// RuntimeMetricStore метод используется для сбора метрик runtime/metrics и сохранения их в хранилище.
+// Метрики из allow-list сохраняются под именами вида gc_heap_allocs_bytes, накопительные
+// целочисленные метрики - как counter, гистограммы - как квантили за интервал опроса.
+// Дополнительно сохраняются прежние имена runtime.MemStats, RandomValue и PollCount.
+func (st *Storage) RuntimeMetricStore() {
+	rtmetrics.Read(st.runtimeSamples)
+
+	values := make(map[string]rtmetrics.Value, len(st.runtimeSamples))
+	for _, sample := range st.runtimeSamples {
+		values[sample.Name] = sample.Value
+		if st.isAllowed(sample.Name) {
+			st.storeRuntimeSample(sample)
+		}
+	}
+
+	for _, a := range runtimeAliases {
+		st.upsertGauge(a.id, a.value(values))
+	}
+	var gcStats debug.GCStats
+	debug.ReadGCStats(&gcStats)
+	st.upsertGauge("LastGC", float64(gcStats.LastGC.UnixNano()))
+	st.upsertGauge("RandomValue", float64(rand.Int()))
+
+	st.addCounter("PollCount", 1)
+}
+
+// storeRuntimeSample сохраняет одну метрику runtime/metrics в зависимости от ее вида.
+func (st *Storage) storeRuntimeSample(sample rtmetrics.Sample) {
+	id := metricSuffix(sample.Name)
+	switch sample.Value.Kind() {
+	case rtmetrics.KindUint64:
+		if isCumulative(sample.Name) {
+			st.upsertCumulative(id, sample.Value.Uint64())
+		} else {
+			st.upsertGauge(id, float64(sample.Value.Uint64()))
+		}
+	case rtmetrics.KindFloat64:
+		st.upsertGauge(id, sample.Value.Float64())
+	case rtmetrics.KindFloat64Histogram:
+		st.storeRuntimeHistogram(id, sample.Value.Float64Histogram())
+	}
+}
+
+// storeRuntimeHistogram сохраняет количество наблюдений гистограммы как counter и
+// квантили распределения наблюдений, добавившихся с предыдущего опроса.
+// Если новых наблюдений не было, квантили сохраняют прежние значения.
+func (st *Storage) storeRuntimeHistogram(id string, h *rtmetrics.Float64Histogram) {
+	prev := st.prevHistograms[id]
+	delta := make([]uint64, len(h.Counts))
+	var total, interval uint64
+	for i, c := range h.Counts {
+		total += c
+		delta[i] = c
+		if len(prev) == len(h.Counts) && c >= prev[i] {
+			delta[i] = c - prev[i]
+		}
+		interval += delta[i]
+	}
+	st.prevHistograms[id] = append(prev[:0], h.Counts...)
+	st.upsertCumulative(id+"_count", total)
+
+	if interval == 0 {
+		return
+	}
+	for _, q := range histogramQuantiles {
+		st.upsertGauge(id+q.suffix, histogramQuantile(q.q, delta, h.Buckets))
+	}
+}
+
+// histogramQuantile оценивает квантиль q по счетчикам корзин гистограммы,
+// возвращая верхнюю границу корзины (нижнюю для корзины с бесконечной границей).
+func histogramQuantile(q float64, counts []uint64, buckets []float64) float64 {
+	var total uint64
+	for _, c := range counts {
+		total += c
+	}
+	rank := uint64(math.Ceil(q * float64(total)))
+	var cumulative uint64
+	for i, c := range counts {
+		cumulative += c
+		if cumulative >= rank && c > 0 {
+			if math.IsInf(buckets[i+1], 1) {
+				return buckets[i]
+			}
+			return buckets[i+1]
+		}
+	}
+	return 0
+}
+
+// histogramSum оценивает сумму наблюдений гистограммы по серединам корзин.
+func histogramSum(v rtmetrics.Value) float64 {
+	if v.Kind() != rtmetrics.KindFloat64Histogram {
+		return 0
+	}
+	h := v.Float64Histogram()
+	var sum float64
+	for i, c := range h.Counts {
+		lower, upper := h.Buckets[i], h.Buckets[i+1]
+		switch {
+		case math.IsInf(lower, -1):
+			lower = upper
+		case math.IsInf(upper, 1):
+			upper = lower
+		}
+		sum += float64(c) * (lower + upper) / 2
+	}
+	return sum
+}
+
+// scalar возвращает числовое значение метрики runtime/metrics или 0 для гистограмм и неподдерживаемых метрик.
+func scalar(v rtmetrics.Value) float64 {
+	switch v.Kind() {
+	case rtmetrics.KindUint64:
+		return float64(v.Uint64())
+	case rtmetrics.KindFloat64:
+		return v.Float64()
+	}
+	return 0
+}
+
+// isCumulative проверяет по описанию runtime/metrics, является ли метрика накопительной.
+func isCumulative(name string) bool {
+	return cumulativeMetrics[name]
+}
+
+// cumulativeMetrics - множество накопительных метрик runtime/metrics.
+var cumulativeMetrics = func() map[string]bool {
+	result := make(map[string]bool)
+	for _, d := range rtmetrics.All() {
+		if d.Cumulative {
+			result[d.Name] = true
+		}
+	}
+	return result
+}()
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+)
+
+// Source - внешний источник метрик агента (скрипты, локальные эндпоинты и т.п.),
+// который собирает метрики вне цикла опроса и отдает накопленное при вызове Drain.
+type Source interface {
+	Drain() []collector.MetricRequest
+}
+
+// WithSources задает внешние источники, метрики которых сохраняет SourceMetricStore.
+func WithSources(sources ...Source) Option {
+	return func(st *Storage) {
+		st.sources = append(st.sources, sources...)
+	}
+}
+
+// SourceMetricStore метод для сохранения метрик, накопленных внешними источниками с предыдущего опроса.
+// Приращения counter добавляются к сохраненному значению, gauge перезаписываются.
+// Некорректные метрики пропускаются, не мешая сохранению остальных, и возвращаются одной ошибкой.
+func (st *Storage) SourceMetricStore() error {
+	var errs []error
+	for _, src := range st.sources {
+		for _, m := range src.Drain() {
+			switch m.MType {
+			case collector.Counter:
+				if m.Delta == nil || *m.Delta < 0 {
+					errs = append(errs, fmt.Errorf("metric %q: %w", m.ID, collector.ErrBadRequest))
+					continue
+				}
+				st.addCounter(m.ID, *m.Delta)
+			case collector.Gauge:
+				if m.Value == nil {
+					errs = append(errs, fmt.Errorf("metric %q: %w", m.ID, collector.ErrBadRequest))
+					continue
+				}
+				st.upsertGauge(m.ID, *m.Value)
+			default:
+				errs = append(errs, fmt.Errorf("metric %q: %w", m.ID, collector.ErrNotImplemented))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -4,6 +4,8 @@ import (
 	collector2 "github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
 	"github.com/stretchr/testify/assert"
 	"log"
+	"math"
+	"strings"
 	"testing"
 )
 
@@ -12,8 +14,43 @@ func TestStorage_GopsutilMetricStore(t *testing.T) {
 	metricsCollector := collector2.Collector()
 	metricsCollector.Metrics = []collector2.StoredMetric{}
 	metricsStore := New(metricsCollector)
-	metricsStore.GopsutilMetricStore()
-	assert.Equal(t, metricsCollector.GetAvailableMetrics(), []string{"FreeMemory", "TotalMemory", "CPUutilization1"})
+	if err := metricsStore.GopsutilMetricStore(); err != nil {
+		log.Printf("some gopsutil sources are unavailable: %s", err.Error())
+	}
+	available := metricsCollector.GetAvailableMetrics()
+	for _, name := range []string{"FreeMemory", "TotalMemory", "SwapTotal", "CPUutilization1", "Load1", "ProcsTotal"} {
+		assert.Contains(t, available, name)
+	}
+	for _, id := range available {
+		m, err := metricsCollector.GetMetric(id)
+		assert.NoError(t, err)
+		if strings.HasPrefix(id, "Net") || strings.HasPrefix(id, "DiskRead") || strings.HasPrefix(id, "DiskWrite") {
+			assert.Equal(t, collector2.Counter, m.MType, id)
+		} else {
+			assert.Equal(t, collector2.Gauge, m.MType, id)
+		}
+	}
+}
+
+func TestStorage_upsertCumulative(t *testing.T) {
+	metricsCollector := collector2.Collector()
+	metricsCollector.Metrics = []collector2.StoredMetric{}
+	metricsStore := New(metricsCollector)
+
+	for _, v := range []uint64{100, 150, 170, 20, 30} {
+		metricsStore.upsertCumulative("NetBytesSent_eth0", v)
+	}
+	m, err := metricsCollector.GetMetric("NetBytesSent_eth0")
+	assert.NoError(t, err)
+	assert.Equal(t, collector2.Counter, m.MType)
+	// 50 + 20 до сброса источника и 10 после него
+	assert.Equal(t, int64(80), *m.CounterValue)
+}
+
+func TestMetricSuffix(t *testing.T) {
+	assert.Equal(t, "root", metricSuffix("/"))
+	assert.Equal(t, "var_lib", metricSuffix("/var/lib"))
+	assert.Equal(t, "sda1", metricSuffix("sda1"))
 
 }
 
@@ -23,6 +60,94 @@ func TestStorage_RuntimeMetricStore(t *testing.T) {
 	metricsCollector.Metrics = []collector2.StoredMetric{}
 	metricsStore := New(metricsCollector)
 	metricsStore.RuntimeMetricStore()
-	assert.Equal(t, metricsCollector.GetAvailableMetrics(), []string{"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys", "HeapAlloc", "HeapIdle", "HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "Lookups", "MCacheInuse", "MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC", "OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc", "RandomValue", "LastGC", "PollCount"})
+	available := metricsCollector.GetAvailableMetrics()
+	for _, name := range []string{"Alloc", "BuckHashSys", "Frees", "GCCPUFraction", "GCSys", "HeapAlloc", "HeapIdle", "HeapInuse", "HeapObjects", "HeapReleased", "HeapSys", "Lookups", "MCacheInuse", "MCacheSys", "MSpanInuse", "MSpanSys", "Mallocs", "NextGC", "NumForcedGC", "NumGC", "OtherSys", "PauseTotalNs", "StackInuse", "StackSys", "Sys", "TotalAlloc", "RandomValue", "LastGC", "PollCount"} {
+		assert.Contains(t, available, name)
+	}
+	assert.Contains(t, available, "gc_heap_allocs_bytes")
+	assert.Contains(t, available, "sched_latencies_seconds_count")
+
+	m, err := metricsCollector.GetMetric("gc_heap_allocs_bytes")
+	assert.NoError(t, err)
+	assert.Equal(t, collector2.Counter, m.MType)
+	m, err = metricsCollector.GetMetric("HeapAlloc")
+	assert.NoError(t, err)
+	assert.Equal(t, collector2.Gauge, m.MType)
+	assert.Greater(t, *m.GaugeValue, float64(0))
+}
+
+func TestStorage_RuntimeMetricStoreAllowList(t *testing.T) {
+	metricsCollector := collector2.Collector()
+	metricsCollector.Metrics = []collector2.StoredMetric{}
+	metricsStore := New(metricsCollector, WithRuntimeAllowList([]string{"/sched/"}))
+	metricsStore.RuntimeMetricStore()
+	available := metricsCollector.GetAvailableMetrics()
+	assert.Contains(t, available, "sched_goroutines_goroutines")
+	assert.Contains(t, available, "sched_latencies_seconds_count")
+	assert.NotContains(t, available, "gc_heap_allocs_bytes")
+	assert.NotContains(t, available, "memory_classes_total_bytes")
+	// совместимые имена не зависят от allow-list
+	assert.Contains(t, available, "HeapAlloc")
+	assert.Contains(t, available, "Sys")
+}
+
+func TestHistogramQuantile(t *testing.T) {
+	buckets := []float64{0, 1, 2, 3, math.Inf(1)}
+	counts := []uint64{50, 40, 9, 1}
+	assert.Equal(t, float64(1), histogramQuantile(0.5, counts, buckets))
+	assert.Equal(t, float64(2), histogramQuantile(0.9, counts, buckets))
+	assert.Equal(t, float64(3), histogramQuantile(0.99, counts, buckets))
+	assert.Equal(t, float64(3), histogramQuantile(1, counts, buckets))
+}
+
+func TestStorage_SourceMetricStore(t *testing.T) {
+	metricsCollector := collector2.Collector()
+	metricsCollector.Metrics = []collector2.StoredMetric{}
+	buffer := collector2.NewBuffer()
+	metricsStore := New(metricsCollector, WithSources(buffer))
+
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, buffer.Add(collector2.MetricRequest{ID: "JobsDone", MType: collector2.Counter, Delta: collector2.PtrInt64(3)}))
+		assert.NoError(t, buffer.Add(collector2.MetricRequest{ID: "QueueLength", MType: collector2.Gauge, Value: collector2.PtrFloat64(float64(i))}))
+		assert.NoError(t, metricsStore.SourceMetricStore())
+	}
+	jobs, err := metricsCollector.GetMetric("JobsDone")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(6), *jobs.CounterValue)
+	queue, err := metricsCollector.GetMetric("QueueLength")
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), *queue.GaugeValue)
+}
+
+// rawSource отдает метрики как есть, без проверок буфера.
+type rawSource []collector2.MetricRequest
+
+func (s rawSource) Drain() []collector2.MetricRequest {
+	return s
+}
+
+func TestStorage_SourceMetricStoreBadMetrics(t *testing.T) {
+	metricsCollector := collector2.Collector()
+	metricsCollector.Metrics = []collector2.StoredMetric{}
+	metricsStore := New(metricsCollector, WithSources(
+		rawSource{
+			{ID: "NoDelta", MType: collector2.Counter},
+			{ID: "Text", MType: "text"},
+			{ID: "NoValue", MType: collector2.Gauge},
+			{ID: "SourceGood", MType: collector2.Counter, Delta: collector2.PtrInt64(2)},
+		},
+		rawSource{{ID: "OtherSource", MType: collector2.Gauge, Value: collector2.PtrFloat64(1)}},
+	))
 
+	err := metricsStore.SourceMetricStore()
+	assert.ErrorIs(t, err, collector2.ErrBadRequest)
+	assert.ErrorIs(t, err, collector2.ErrNotImplemented)
+	// некорректные метрики не мешают сохранению остальных метрик того же и следующих источников
+	good, err := metricsCollector.GetMetric("SourceGood")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), *good.CounterValue)
+	_, err = metricsCollector.GetMetric("OtherSource")
+	assert.NoError(t, err)
+	_, err = metricsCollector.GetMetric("NoDelta")
+	assert.ErrorIs(t, err, collector2.ErrNotFound)
 }
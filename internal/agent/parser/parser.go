@@ -0,0 +1,100 @@
+// Package parser разбирает текстовые форматы метрик, которые агент получает от внешних источников:
+// строки вида "name type value", JSON-массивы MetricRequest и текстовый формат Prometheus.
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"strconv"
+	"strings"
+)
+
+// Поддерживаемые форматы вывода.
+const (
+	FormatLines      = "lines"      // строки "name type value"
+	FormatJSON       = "json"       // JSON-массив MetricRequest
+	FormatPrometheus = "prometheus" // текстовый формат Prometheus
+)
+
+// ParseLines разбирает строки вида "name type value".
+// Пустые строки и строки, начинающиеся с '#', пропускаются.
+// Значение counter - целое приращение, значение gauge - число с плавающей точкой.
+func ParseLines(data []byte) ([]collector.MetricRequest, error) {
+	result := make([]collector.MetricRequest, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected \"name type value\", got %q", n, line)
+		}
+		metric, err := newMetricRequest(fields[0], fields[1], fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		result = append(result, metric)
+	}
+	return result, scanner.Err()
+}
+
+// ParseJSON разбирает JSON-массив MetricRequest, проверяя наличие значения нужного типа.
+func ParseJSON(data []byte) ([]collector.MetricRequest, error) {
+	var metrics []collector.MetricRequest
+	if err := json.Unmarshal(data, &metrics); err != nil {
+		return nil, fmt.Errorf("error while parsing json metrics: %w", err)
+	}
+	for i, m := range metrics {
+		if err := validate(m); err != nil {
+			return nil, fmt.Errorf("metric #%d: %w", i, err)
+		}
+	}
+	return metrics, nil
+}
+
+// newMetricRequest создает MetricRequest из текстовых имени, типа и значения.
+func newMetricRequest(id, mtype, value string) (collector.MetricRequest, error) {
+	metric := collector.MetricRequest{ID: id, MType: mtype}
+	switch mtype {
+	case collector.Counter:
+		delta, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return metric, fmt.Errorf("bad counter value %q: %w", value, err)
+		}
+		metric.Delta = &delta
+	case collector.Gauge:
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return metric, fmt.Errorf("bad gauge value %q: %w", value, err)
+		}
+		metric.Value = &v
+	default:
+		return metric, fmt.Errorf("unsupported metric type %q", mtype)
+	}
+	return metric, validate(metric)
+}
+
+// validate проверяет, что у метрики есть имя и значение, соответствующее ее типу.
+func validate(m collector.MetricRequest) error {
+	if m.ID == "" {
+		return fmt.Errorf("metric id is empty")
+	}
+	switch m.MType {
+	case collector.Counter:
+		if m.Delta == nil {
+			return fmt.Errorf("counter %q has no delta", m.ID)
+		}
+	case collector.Gauge:
+		if m.Value == nil {
+			return fmt.Errorf("gauge %q has no value", m.ID)
+		}
+	default:
+		return fmt.Errorf("metric %q has unsupported type %q", m.ID, m.MType)
+	}
+	return nil
+}
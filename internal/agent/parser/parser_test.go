@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseLines(t *testing.T) {
+	testCases := []struct {
+		name          string
+		input         string
+		expected      []collector.MetricRequest
+		expectedError bool
+	}{
+		{
+			name:  "positive",
+			input: "# queue check\nQueueLength gauge 12.5\n\nJobsDone counter 3\n",
+			expected: []collector.MetricRequest{
+				{ID: "QueueLength", MType: collector.Gauge, Value: collector.PtrFloat64(12.5)},
+				{ID: "JobsDone", MType: collector.Counter, Delta: collector.PtrInt64(3)},
+			},
+		},
+		{
+			name:          "negative: wrong number of fields",
+			input:         "QueueLength gauge\n",
+			expectedError: true,
+		},
+		{
+			name:          "negative: fractional counter",
+			input:         "JobsDone counter 1.5\n",
+			expectedError: true,
+		},
+		{
+			name:          "negative: unsupported type",
+			input:         "Latency histogram 1\n",
+			expectedError: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := ParseLines([]byte(tt.input))
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, metrics)
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	metrics, err := ParseJSON([]byte(`[{"id":"QueueLength","type":"gauge","value":1.5},{"id":"JobsDone","type":"counter","delta":2}]`))
+	assert.NoError(t, err)
+	assert.Equal(t, []collector.MetricRequest{
+		{ID: "QueueLength", MType: collector.Gauge, Value: collector.PtrFloat64(1.5)},
+		{ID: "JobsDone", MType: collector.Counter, Delta: collector.PtrInt64(2)},
+	}, metrics)
+
+	_, err = ParseJSON([]byte(`[{"id":"JobsDone","type":"counter","value":2}]`))
+	assert.Error(t, err)
+	_, err = ParseJSON([]byte(`{"id":"JobsDone"}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,235 @@
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Типы семейств метрик Prometheus.
+const (
+	PrometheusCounter   = "counter"
+	PrometheusGauge     = "gauge"
+	PrometheusHistogram = "histogram"
+	PrometheusSummary   = "summary"
+	PrometheusUntyped   = "untyped"
+)
+
+// Суффиксы, с которыми значения семейства могут встречаться в тексте.
+var familySuffixes = []string{"_total", "_bucket", "_sum", "_count", "_created"}
+
+type (
+	// Label - метка значения Prometheus.
+	Label struct {
+		Name  string
+		Value string
+	}
+
+	// Sample - одно значение метрики в текстовом формате Prometheus.
+	Sample struct {
+		Name   string  // имя значения, например http_requests_total
+		Labels []Label // метки, отсортированные по имени
+		Type   string  // тип семейства из строки # TYPE, по умолчанию untyped
+		Value  float64 // значение
+	}
+)
+
+// ParsePrometheus разбирает текстовый формат Prometheus (exposition format 0.0.4).
+// Строки # HELP и прочие комментарии пропускаются, временные метки значений игнорируются.
+func ParsePrometheus(data []byte) ([]Sample, error) {
+	types := make(map[string]string)
+	result := make([]Sample, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			fields := strings.Fields(line)
+			if len(fields) >= 4 && fields[1] == "TYPE" {
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+		sample, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		sample.Type = familyType(types, sample.Name)
+		result = append(result, sample)
+	}
+	return result, scanner.Err()
+}
+
+// familyType возвращает тип семейства, к которому относится значение с указанным именем.
+func familyType(types map[string]string, name string) string {
+	if t, ok := types[name]; ok {
+		return t
+	}
+	for _, suffix := range familySuffixes {
+		if t, ok := types[strings.TrimSuffix(name, suffix)]; ok && strings.HasSuffix(name, suffix) {
+			return t
+		}
+	}
+	return PrometheusUntyped
+}
+
+// parseSample разбирает строку значения: name{label="value",...} value [timestamp].
+func parseSample(line string) (Sample, error) {
+	var sample Sample
+	nameEnd := strings.IndexAny(line, "{ \t")
+	if nameEnd <= 0 {
+		return sample, fmt.Errorf("bad sample %q", line)
+	}
+	sample.Name = line[:nameEnd]
+	rest := line[nameEnd:]
+	if strings.HasPrefix(rest, "{") {
+		labels, tail, err := parseLabels(rest[1:])
+		if err != nil {
+			return sample, err
+		}
+		sample.Labels = labels
+		rest = tail
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 || len(fields) > 2 {
+		return sample, fmt.Errorf("bad sample value in %q", line)
+	}
+	value, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return sample, fmt.Errorf("bad sample value %q: %w", fields[0], err)
+	}
+	sample.Value = value
+	return sample, nil
+}
+
+// parseLabels разбирает метки после открывающей скобки и возвращает остаток строки после '}'.
+func parseLabels(s string) ([]Label, string, error) {
+	labels := make([]Label, 0)
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		if strings.HasPrefix(s, "}") {
+			sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+			return labels, s[1:], nil
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || len(s) < eq+2 || s[eq+1] != '"' {
+			return nil, "", fmt.Errorf("bad label in %q", s)
+		}
+		name := strings.TrimSpace(s[:eq])
+		var value strings.Builder
+		i := eq + 2
+		for ; i < len(s) && s[i] != '"'; i++ {
+			if s[i] == '\\' && i+1 < len(s) {
+				i++
+				switch s[i] {
+				case 'n':
+					value.WriteByte('\n')
+				default:
+					value.WriteByte(s[i])
+				}
+				continue
+			}
+			value.WriteByte(s[i])
+		}
+		if i >= len(s) {
+			return nil, "", fmt.Errorf("unterminated label value for %q", name)
+		}
+		labels = append(labels, Label{Name: name, Value: value.String()})
+		s = s[i+1:]
+	}
+}
+
+// ID возвращает имя метрики для значения. При keepLabels метки сохраняются в имени
+// в каноническом виде name{a="1",b="2"}, иначе они добавляются к имени как name_a_1_b_2.
+func (s Sample) ID(keepLabels bool) string {
+	if len(s.Labels) == 0 {
+		return s.Name
+	}
+	var b strings.Builder
+	b.WriteString(s.Name)
+	if keepLabels {
+		b.WriteByte('{')
+		for i, l := range s.Labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			b.WriteString(l.Name)
+			b.WriteString("=")
+			b.WriteString(strconv.Quote(l.Value))
+		}
+		b.WriteByte('}')
+		return b.String()
+	}
+	for _, l := range s.Labels {
+		b.WriteByte('_')
+		b.WriteString(sanitize(l.Name))
+		b.WriteByte('_')
+		b.WriteString(sanitize(l.Value))
+	}
+	return b.String()
+}
+
+// sanitize заменяет символы, недопустимые в имени метрики, на '_'.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// Converter преобразует значения Prometheus в MetricRequest.
+// Counter Prometheus - накопительное значение, поэтому Converter запоминает предыдущие значения
+// и передает приращение: первое наблюдение только регистрирует метрику с нулевым приращением,
+// уменьшение значения считается перезапуском источника, и приращением становится новое значение.
+// Gauge и untyped передаются как gauge, значения histogram и summary пропускаются.
+type Converter struct {
+	keepLabels bool
+	prev       map[string]float64
+}
+
+// NewConverter создает Converter; keepLabels определяет способ передачи меток в имени метрики.
+func NewConverter(keepLabels bool) *Converter {
+	return &Converter{
+		keepLabels: keepLabels,
+		prev:       make(map[string]float64),
+	}
+}
+
+// Convert преобразует значения Prometheus в MetricRequest.
+func (c *Converter) Convert(samples []Sample) []collector.MetricRequest {
+	result := make([]collector.MetricRequest, 0, len(samples))
+	for _, s := range samples {
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			continue
+		}
+		id := s.ID(c.keepLabels)
+		switch s.Type {
+		case PrometheusCounter:
+			if strings.HasSuffix(s.Name, "_created") {
+				continue
+			}
+			var delta int64
+			if prev, ok := c.prev[id]; ok {
+				if s.Value >= prev {
+					delta = int64(math.Floor(s.Value)) - int64(math.Floor(prev))
+				} else {
+					delta = int64(math.Floor(s.Value))
+				}
+			}
+			c.prev[id] = s.Value
+			result = append(result, collector.MetricRequest{ID: id, MType: collector.Counter, Delta: collector.PtrInt64(delta)})
+		case PrometheusGauge, PrometheusUntyped:
+			result = append(result, collector.MetricRequest{ID: id, MType: collector.Gauge, Value: collector.PtrFloat64(s.Value)})
+		}
+	}
+	return result
+}
@@ -0,0 +1,71 @@
+package parser
+
+import (
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const prometheusText = `# HELP http_requests_total The total number of HTTP requests.
+# TYPE http_requests_total counter
+http_requests_total{method="post",code="200"} 1027 1395066363000
+http_requests_total{method="post",code="400"}    3 1395066363000
+# TYPE queue_length gauge
+queue_length 12
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 10
+request_duration_seconds_sum 1.5
+request_duration_seconds_count 12
+temperature{room="a \"big\" one"} -3.5
+`
+
+func TestParsePrometheus(t *testing.T) {
+	samples, err := ParsePrometheus([]byte(prometheusText))
+	assert.NoError(t, err)
+	assert.Len(t, samples, 7)
+	assert.Equal(t, Sample{
+		Name:   "http_requests_total",
+		Labels: []Label{{Name: "code", Value: "200"}, {Name: "method", Value: "post"}},
+		Type:   PrometheusCounter,
+		Value:  1027,
+	}, samples[0])
+	assert.Equal(t, PrometheusGauge, samples[2].Type)
+	assert.Equal(t, PrometheusHistogram, samples[3].Type)
+	assert.Equal(t, PrometheusHistogram, samples[5].Type)
+	assert.Equal(t, Sample{
+		Name:   "temperature",
+		Labels: []Label{{Name: "room", Value: `a "big" one`}},
+		Type:   PrometheusUntyped,
+		Value:  -3.5,
+	}, samples[6])
+
+	_, err = ParsePrometheus([]byte(`broken{label="value 1`))
+	assert.Error(t, err)
+}
+
+func TestSample_ID(t *testing.T) {
+	s := Sample{Name: "http_requests_total", Labels: []Label{{Name: "code", Value: "200"}, {Name: "path", Value: "/api/v1"}}}
+	assert.Equal(t, `http_requests_total{code="200",path="/api/v1"}`, s.ID(true))
+	assert.Equal(t, "http_requests_total_code_200_path__api_v1", s.ID(false))
+	assert.Equal(t, "up", Sample{Name: "up"}.ID(false))
+}
+
+func TestConverter_Convert(t *testing.T) {
+	c := NewConverter(false)
+	first := c.Convert([]Sample{
+		{Name: "jobs_total", Type: PrometheusCounter, Value: 10},
+		{Name: "queue_length", Type: PrometheusGauge, Value: 3},
+		{Name: "duration_seconds_sum", Type: PrometheusSummary, Value: 1},
+	})
+	assert.Equal(t, []collector.MetricRequest{
+		{ID: "jobs_total", MType: collector.Counter, Delta: collector.PtrInt64(0)},
+		{ID: "queue_length", MType: collector.Gauge, Value: collector.PtrFloat64(3)},
+	}, first)
+
+	second := c.Convert([]Sample{{Name: "jobs_total", Type: PrometheusCounter, Value: 15}})
+	assert.Equal(t, int64(5), *second[0].Delta)
+
+	// перезапуск источника: счетчик начался заново
+	third := c.Convert([]Sample{{Name: "jobs_total", Type: PrometheusCounter, Value: 2}})
+	assert.Equal(t, int64(2), *third[0].Delta)
+}
@@ -5,13 +5,18 @@ import (
 	"fmt"
 	metricagent "github.com/ZnNr/go-musthave-metrics.git/internal/agent"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/logtail"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/metrics"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/scraper"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/script"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/statsd"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
 	"go.uber.org/zap"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 )
 
 type Runner struct {
@@ -50,8 +55,50 @@ func (r *Runner) Run(ctx context.Context) {
 
 	var wg sync.WaitGroup
 
+	processTargets, err := metrics.NewProcessTargets(r.params.Processes)
+	if err != nil {
+		r.logger.Fatalw(err.Error(), "error", "parsing process targets")
+	}
+	scripts, err := script.New(r.params.Scripts, r.logger)
+	if err != nil {
+		r.logger.Fatalw(err.Error(), "error", "parsing scripts")
+	}
+	scripts.Start(runCtx)
+
+	scrapers, err := scraper.New(r.params.Scrape, r.logger)
+	if err != nil {
+		r.logger.Fatalw(err.Error(), "error", "parsing scrape targets")
+	}
+	scrapers.Start(runCtx)
+
+	statsdServer := statsd.New(
+		r.params.StatsdAddr,
+		r.params.StatsdSocket,
+		time.Duration(r.params.ReportInterval)*time.Second,
+		r.logger,
+	)
+	if err = statsdServer.Start(runCtx); err != nil {
+		r.logger.Fatalw(err.Error(), "error", "starting statsd listener")
+	}
+
+	tailer, err := logtail.New(r.params.LogFiles, r.params.LogCheckpoint, r.logger)
+	if err != nil {
+		r.logger.Fatalw(err.Error(), "error", "parsing log tailing rules")
+	}
+	if err = tailer.Start(runCtx); err != nil {
+		r.logger.Fatalw(err.Error(), "error", "starting log tailing")
+	}
+
+	storage := metrics.New(
+		collector.Collector(),
+		metrics.WithRuntimeAllowList(r.params.RuntimeMetrics),
+		metrics.WithProcesses(processTargets),
+		metrics.WithCgroupPath(r.params.CgroupPath),
+		metrics.WithSources(scripts, scrapers, statsdServer, tailer),
+	)
+
 	// Создание экземпляра metricagent.
-	agent, err := metricagent.New(r.params, metrics.New(collector.Collector()), r.logger)
+	agent, err := metricagent.New(r.params, storage, r.logger)
 	if err != nil {
 		r.logger.Fatalw(err.Error(), "error", "creating agent")
 	}
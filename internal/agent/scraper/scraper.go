@@ -0,0 +1,132 @@
+// Package scraper опрашивает локальные эндпоинты в текстовом формате Prometheus
+// и преобразует их counter и gauge в метрики агента.
+// Полученные метрики накапливаются до следующего опроса агента и отправляются вместе с остальными.
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/parser"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"github.com/go-resty/resty/v2"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// Интервал опроса эндпоинта по умолчанию (в секундах)
+	defaultInterval = 10
+	// Таймаут запроса по умолчанию (в секундах)
+	defaultTimeout = 5
+	// Заголовок Accept для текстового формата Prometheus
+	acceptHeader = "text/plain;version=0.0.4;q=1,*/*;q=0.1"
+)
+
+// target - подготовленный к опросу эндпоинт.
+type target struct {
+	url       string
+	interval  time.Duration
+	timeout   time.Duration
+	prefix    string
+	converter *parser.Converter
+}
+
+// Runner опрашивает эндпоинты на собственных интервалах и накапливает полученные метрики.
+type Runner struct {
+	targets []target
+	client  *resty.Client
+	buffer  *collector.Buffer
+	log     *zap.SugaredLogger
+}
+
+// New проверяет описания эндпоинтов и создает Runner.
+// Незаданные интервал и таймаут заменяются значениями по умолчанию.
+func New(cfg []flags.ScrapeTarget, log *zap.SugaredLogger) (*Runner, error) {
+	targets := make([]target, 0, len(cfg))
+	for i, c := range cfg {
+		u, err := url.Parse(c.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("scrape target #%d: bad url %q", i, c.URL)
+		}
+		t := target{
+			url:       c.URL,
+			interval:  time.Duration(c.Interval) * time.Second,
+			timeout:   time.Duration(c.Timeout) * time.Second,
+			prefix:    c.Prefix,
+			converter: parser.NewConverter(c.KeepLabels),
+		}
+		if t.interval <= 0 {
+			t.interval = defaultInterval * time.Second
+		}
+		if t.timeout <= 0 {
+			t.timeout = defaultTimeout * time.Second
+		}
+		targets = append(targets, t)
+	}
+	return &Runner{
+		targets: targets,
+		client:  resty.New(),
+		buffer:  collector.NewBuffer(),
+		log:     log,
+	}, nil
+}
+
+// Start опрашивает каждый эндпоинт сразу и далее с его интервалом до отмены контекста.
+func (r *Runner) Start(ctx context.Context) {
+	for _, t := range r.targets {
+		go r.loop(ctx, t)
+	}
+}
+
+// Drain возвращает метрики, накопленные с предыдущего вызова.
+func (r *Runner) Drain() []collector.MetricRequest {
+	return r.buffer.Drain()
+}
+
+// loop опрашивает эндпоинт по таймеру.
+func (r *Runner) loop(ctx context.Context, t target) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		if err := r.scrape(ctx, t); err != nil {
+			r.log.Errorf("error while scraping %s: %s", t.url, err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scrape запрашивает эндпоинт, разбирает ответ и добавляет метрики в буфер.
+func (r *Runner) scrape(ctx context.Context, t target) error {
+	scrapeCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	resp, err := r.client.R().
+		SetContext(scrapeCtx).
+		SetHeader("Accept", acceptHeader).
+		Get(t.url)
+	if err != nil {
+		return fmt.Errorf("error while requesting endpoint: %w", err)
+	}
+	if resp.StatusCode() != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode())
+	}
+
+	samples, err := parser.ParsePrometheus(resp.Body())
+	if err != nil {
+		return err
+	}
+	for _, m := range t.converter.Convert(samples) {
+		m.ID = t.prefix + m.ID
+		if err := r.buffer.Add(m); err != nil {
+			return fmt.Errorf("error while adding metric %q: %w", m.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package scraper
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	for _, u := range []string{"", "127.0.0.1:9100/metrics", "ftp://127.0.0.1/metrics"} {
+		_, err := New([]flags.ScrapeTarget{{URL: u}}, zap.NewNop().Sugar())
+		assert.Error(t, err, u)
+	}
+	r, err := New([]flags.ScrapeTarget{{URL: "http://127.0.0.1:9100/metrics"}}, zap.NewNop().Sugar())
+	assert.NoError(t, err)
+	assert.Equal(t, defaultInterval, int(r.targets[0].interval.Seconds()))
+	assert.Equal(t, defaultTimeout, int(r.targets[0].timeout.Seconds()))
+}
+
+func TestRunner_scrape(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/metrics" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		total := "100"
+		if requests > 1 {
+			total = "130"
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte("# TYPE jobs_total counter\njobs_total{queue=\"mail\"} " + total + "\n# TYPE queue_length gauge\nqueue_length 7\n"))
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		name     string
+		target   flags.ScrapeTarget
+		expected [][]collector.MetricRequest
+	}{
+		{
+			name:   "flatten labels",
+			target: flags.ScrapeTarget{URL: srv.URL + "/metrics"},
+			expected: [][]collector.MetricRequest{
+				{
+					{ID: "jobs_total_queue_mail", MType: collector.Counter, Delta: collector.PtrInt64(0)},
+					{ID: "queue_length", MType: collector.Gauge, Value: collector.PtrFloat64(7)},
+				},
+				{
+					{ID: "jobs_total_queue_mail", MType: collector.Counter, Delta: collector.PtrInt64(30)},
+					{ID: "queue_length", MType: collector.Gauge, Value: collector.PtrFloat64(7)},
+				},
+			},
+		},
+		{
+			name:   "keep labels with prefix",
+			target: flags.ScrapeTarget{URL: srv.URL + "/metrics", KeepLabels: true, Prefix: "app_"},
+			expected: [][]collector.MetricRequest{
+				{
+					{ID: `app_jobs_total{queue="mail"}`, MType: collector.Counter, Delta: collector.PtrInt64(0)},
+					{ID: "app_queue_length", MType: collector.Gauge, Value: collector.PtrFloat64(7)},
+				},
+				{
+					{ID: `app_jobs_total{queue="mail"}`, MType: collector.Counter, Delta: collector.PtrInt64(30)},
+					{ID: "app_queue_length", MType: collector.Gauge, Value: collector.PtrFloat64(7)},
+				},
+			},
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			r, err := New([]flags.ScrapeTarget{tt.target}, zap.NewNop().Sugar())
+			assert.NoError(t, err)
+			for _, expected := range tt.expected {
+				assert.NoError(t, r.scrape(context.Background(), r.targets[0]))
+				assert.Equal(t, expected, r.Drain())
+			}
+		})
+	}
+
+	r, err := New([]flags.ScrapeTarget{{URL: srv.URL + "/missing"}}, zap.NewNop().Sugar())
+	assert.NoError(t, err)
+	assert.Error(t, r.scrape(context.Background(), r.targets[0]))
+}
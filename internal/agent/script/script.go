@@ -0,0 +1,151 @@
+// Package script запускает пользовательские команды агента по расписанию и разбирает их вывод как метрики.
+// Поддерживаются форматы вывода пакета parser: строки "name type value", JSON-массив MetricRequest
+// и текстовый формат Prometheus. Полученные метрики накапливаются до следующего опроса агента.
+package script
+
+import (
+	"context"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/parser"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"go.uber.org/zap"
+	"os/exec"
+	"time"
+)
+
+const (
+	// Интервал запуска команды по умолчанию (в секундах)
+	defaultInterval = 10
+	// Таймаут выполнения команды по умолчанию (в секундах)
+	defaultTimeout = 5
+)
+
+// check - подготовленная к запуску команда.
+type check struct {
+	name      string
+	command   []string
+	interval  time.Duration
+	timeout   time.Duration
+	format    string
+	converter *parser.Converter
+}
+
+// Runner запускает команды на собственных интервалах и накапливает полученные метрики.
+type Runner struct {
+	checks []check
+	buffer *collector.Buffer
+	log    *zap.SugaredLogger
+}
+
+// New проверяет описания команд и создает Runner.
+// Незаданные интервал и таймаут заменяются значениями по умолчанию, формат по умолчанию - lines.
+func New(cfg []flags.ScriptCheck, log *zap.SugaredLogger) (*Runner, error) {
+	checks := make([]check, 0, len(cfg))
+	for i, c := range cfg {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("script #%d", i)
+		}
+		if len(c.Command) == 0 || c.Command[0] == "" {
+			return nil, fmt.Errorf("%s: command is empty", name)
+		}
+		ch := check{
+			name:     name,
+			command:  c.Command,
+			interval: time.Duration(c.Interval) * time.Second,
+			timeout:  time.Duration(c.Timeout) * time.Second,
+			format:   c.Format,
+		}
+		if ch.interval <= 0 {
+			ch.interval = defaultInterval * time.Second
+		}
+		if ch.timeout <= 0 {
+			ch.timeout = defaultTimeout * time.Second
+		}
+		switch ch.format {
+		case "":
+			ch.format = parser.FormatLines
+		case parser.FormatLines, parser.FormatJSON:
+		case parser.FormatPrometheus:
+			ch.converter = parser.NewConverter(false)
+		default:
+			return nil, fmt.Errorf("%s: unsupported output format %q", name, ch.format)
+		}
+		checks = append(checks, ch)
+	}
+	return &Runner{
+		checks: checks,
+		buffer: collector.NewBuffer(),
+		log:    log,
+	}, nil
+}
+
+// Start запускает каждую команду сразу и далее с ее интервалом до отмены контекста.
+func (r *Runner) Start(ctx context.Context) {
+	for _, ch := range r.checks {
+		go r.loop(ctx, ch)
+	}
+}
+
+// Drain возвращает метрики, накопленные с предыдущего вызова.
+func (r *Runner) Drain() []collector.MetricRequest {
+	return r.buffer.Drain()
+}
+
+// loop запускает команду по таймеру. Следующий запуск не начинается, пока не завершился предыдущий.
+func (r *Runner) loop(ctx context.Context, ch check) {
+	ticker := time.NewTicker(ch.interval)
+	defer ticker.Stop()
+	for {
+		if err := r.run(ctx, ch); err != nil {
+			r.log.Errorf("error while running %s: %s", ch.name, err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// run выполняет команду с таймаутом, разбирает ее вывод и добавляет метрики в буфер.
+func (r *Runner) run(ctx context.Context, ch check) error {
+	runCtx, cancel := context.WithTimeout(ctx, ch.timeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(runCtx, ch.command[0], ch.command[1:]...).Output()
+	if err != nil {
+		if runCtx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("command timed out after %s", ch.timeout)
+		}
+		return fmt.Errorf("error while executing command: %w", err)
+	}
+
+	metrics, err := parse(ch, output)
+	if err != nil {
+		return err
+	}
+	for _, m := range metrics {
+		if err := r.buffer.Add(m); err != nil {
+			return fmt.Errorf("error while adding metric %q: %w", m.ID, err)
+		}
+	}
+	return nil
+}
+
+// parse разбирает вывод команды в соответствии с ее форматом.
+func parse(ch check, output []byte) ([]collector.MetricRequest, error) {
+	switch ch.format {
+	case parser.FormatJSON:
+		return parser.ParseJSON(output)
+	case parser.FormatPrometheus:
+		samples, err := parser.ParsePrometheus(output)
+		if err != nil {
+			return nil, err
+		}
+		return ch.converter.Convert(samples), nil
+	default:
+		return parser.ParseLines(output)
+	}
+}
@@ -0,0 +1,83 @@
+package script
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	_, err := New([]flags.ScriptCheck{{Name: "empty"}}, zap.NewNop().Sugar())
+	assert.Error(t, err)
+	_, err = New([]flags.ScriptCheck{{Name: "bad format", Command: []string{"true"}, Format: "xml"}}, zap.NewNop().Sugar())
+	assert.Error(t, err)
+
+	r, err := New([]flags.ScriptCheck{{Command: []string{"true"}}}, zap.NewNop().Sugar())
+	assert.NoError(t, err)
+	assert.Equal(t, defaultInterval, int(r.checks[0].interval.Seconds()))
+	assert.Equal(t, defaultTimeout, int(r.checks[0].timeout.Seconds()))
+	assert.Equal(t, "lines", r.checks[0].format)
+}
+
+func TestRunner_run(t *testing.T) {
+	testCases := []struct {
+		name          string
+		check         flags.ScriptCheck
+		expected      []collector.MetricRequest
+		expectedError bool
+	}{
+		{
+			name:  "positive: lines",
+			check: flags.ScriptCheck{Command: []string{"sh", "-c", "echo 'QueueLength gauge 4'; echo 'JobsDone counter 2'"}},
+			expected: []collector.MetricRequest{
+				{ID: "QueueLength", MType: collector.Gauge, Value: collector.PtrFloat64(4)},
+				{ID: "JobsDone", MType: collector.Counter, Delta: collector.PtrInt64(2)},
+			},
+		},
+		{
+			name:  "positive: json",
+			check: flags.ScriptCheck{Format: "json", Command: []string{"echo", `[{"id":"QueueLength","type":"gauge","value":4}]`}},
+			expected: []collector.MetricRequest{
+				{ID: "QueueLength", MType: collector.Gauge, Value: collector.PtrFloat64(4)},
+			},
+		},
+		{
+			name:  "positive: prometheus",
+			check: flags.ScriptCheck{Format: "prometheus", Command: []string{"sh", "-c", "echo '# TYPE queue_length gauge'; echo 'queue_length{queue=\"mail\"} 4'"}},
+			expected: []collector.MetricRequest{
+				{ID: "queue_length_queue_mail", MType: collector.Gauge, Value: collector.PtrFloat64(4)},
+			},
+		},
+		{
+			name:          "negative: command failed",
+			check:         flags.ScriptCheck{Command: []string{"sh", "-c", "exit 2"}},
+			expectedError: true,
+		},
+		{
+			name:          "negative: timeout",
+			check:         flags.ScriptCheck{Timeout: 1, Command: []string{"sleep", "5"}},
+			expectedError: true,
+		},
+		{
+			name:          "negative: bad output",
+			check:         flags.ScriptCheck{Command: []string{"echo", "not a metric"}},
+			expectedError: true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := New([]flags.ScriptCheck{tt.check}, zap.NewNop().Sugar())
+			assert.NoError(t, err)
+			err = r.run(context.Background(), r.checks[0])
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, r.Drain())
+		})
+	}
+}
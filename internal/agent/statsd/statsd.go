@@ -0,0 +1,307 @@
+// Package statsd реализует встроенный в агент приемник StatsD по UDP и Unix-сокету.
+// Поддерживаются типы c (counter), g (gauge), ms (timer) и s (set) с частотой выборки @rate.
+// Значения агрегируются в течение интервала отчета и передаются агенту как обычные метрики.
+package statsd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"go.uber.org/zap"
+	"math"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Максимальный размер датаграммы StatsD
+const maxPacketSize = 65535
+
+// Типы метрик StatsD.
+const (
+	typeCounter = "c"
+	typeGauge   = "g"
+	typeTimer   = "ms"
+	typeSet     = "s"
+)
+
+// Квантили, сохраняемые для таймеров.
+var timerQuantiles = []struct {
+	suffix string
+	q      float64
+}{
+	{"_p50", 0.5},
+	{"_p90", 0.9},
+	{"_p99", 0.99},
+}
+
+// ErrBadLine представляет ошибку для строки, не соответствующей формату StatsD.
+var ErrBadLine = errors.New("bad statsd line")
+
+// line - разобранная строка StatsD: name:value|type[|@rate].
+type line struct {
+	name     string
+	value    string
+	mtype    string
+	rate     float64
+	relative bool // значение gauge со знаком: изменение текущего значения
+}
+
+// timer - наблюдения таймера за интервал и их суммарный вес с учетом частоты выборки.
+type timer struct {
+	values []float64
+	count  float64
+}
+
+// Server принимает метрики StatsD и агрегирует их до конца интервала отчета.
+type Server struct {
+	addr          string
+	socket        string
+	flushInterval time.Duration
+	log           *zap.SugaredLogger
+
+	mu       sync.Mutex
+	conns    []net.PacketConn
+	counters map[string]float64
+	gauges   map[string]float64
+	updated  map[string]struct{}
+	timers   map[string]*timer
+	sets     map[string]map[string]struct{}
+	buffer   *collector.Buffer
+}
+
+// New создает приемник StatsD. addr - UDP-адрес, socket - путь к Unix-сокету (datagram);
+// пустое значение отключает соответствующий приемник. flushInterval - интервал агрегации.
+func New(addr, socket string, flushInterval time.Duration, log *zap.SugaredLogger) *Server {
+	return &Server{
+		addr:          addr,
+		socket:        socket,
+		flushInterval: flushInterval,
+		log:           log,
+		counters:      make(map[string]float64),
+		gauges:        make(map[string]float64),
+		updated:       make(map[string]struct{}),
+		timers:        make(map[string]*timer),
+		sets:          make(map[string]map[string]struct{}),
+		buffer:        collector.NewBuffer(),
+	}
+}
+
+// Start открывает сокеты приемника и запускает чтение и агрегацию до отмены контекста.
+func (s *Server) Start(ctx context.Context) error {
+	if s.addr != "" {
+		conn, err := net.ListenPacket("udp", s.addr)
+		if err != nil {
+			return fmt.Errorf("error while listening statsd udp: %w", err)
+		}
+		s.conns = append(s.conns, conn)
+	}
+	if s.socket != "" {
+		// сокет мог остаться от предыдущего запуска
+		if err := os.Remove(s.socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error while removing stale statsd socket: %w", err)
+		}
+		conn, err := net.ListenPacket("unixgram", s.socket)
+		if err != nil {
+			return fmt.Errorf("error while listening statsd unix socket: %w", err)
+		}
+		s.conns = append(s.conns, conn)
+	}
+	if len(s.conns) == 0 {
+		return nil
+	}
+	for _, conn := range s.conns {
+		go s.serve(conn)
+	}
+	go func() {
+		ticker := time.NewTicker(s.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				for _, conn := range s.conns {
+					conn.Close()
+				}
+				s.flush()
+				return
+			case <-ticker.C:
+				s.flush()
+			}
+		}
+	}()
+	return nil
+}
+
+// Drain возвращает метрики, агрегированные за завершившиеся интервалы.
+func (s *Server) Drain() []collector.MetricRequest {
+	return s.buffer.Drain()
+}
+
+// serve читает датаграммы до закрытия соединения.
+func (s *Server) serve(conn net.PacketConn) {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.log.Errorf("error while reading statsd packet: %s", err.Error())
+			}
+			return
+		}
+		s.handlePacket(buf[:n])
+	}
+}
+
+// handlePacket разбирает датаграмму, в которой строки метрик разделены переводом строки.
+func (s *Server) handlePacket(packet []byte) {
+	for _, raw := range strings.Split(string(packet), "\n") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		l, err := parseLine(raw)
+		if err != nil {
+			s.log.Warnf("%s: %q", err.Error(), raw)
+			continue
+		}
+		s.add(l)
+	}
+}
+
+// parseLine разбирает строку вида name:value|type[|@rate][|#tags]. Теги игнорируются.
+func parseLine(raw string) (line, error) {
+	l := line{rate: 1}
+	name, rest, ok := strings.Cut(raw, ":")
+	if !ok || name == "" {
+		return l, ErrBadLine
+	}
+	l.name = name
+	parts := strings.Split(rest, "|")
+	if len(parts) < 2 || parts[0] == "" {
+		return l, ErrBadLine
+	}
+	l.value, l.mtype = parts[0], parts[1]
+	for _, p := range parts[2:] {
+		if !strings.HasPrefix(p, "@") {
+			continue
+		}
+		rate, err := strconv.ParseFloat(p[1:], 64)
+		if err != nil || rate <= 0 || rate > 1 {
+			return l, fmt.Errorf("%w: bad sample rate %q", ErrBadLine, p)
+		}
+		l.rate = rate
+	}
+	switch l.mtype {
+	case typeCounter, typeTimer:
+	case typeGauge:
+		l.relative = strings.HasPrefix(l.value, "+") || strings.HasPrefix(l.value, "-")
+	case typeSet:
+		return l, nil
+	default:
+		return l, fmt.Errorf("%w: unsupported type %q", ErrBadLine, l.mtype)
+	}
+	if _, err := strconv.ParseFloat(l.value, 64); err != nil {
+		return l, fmt.Errorf("%w: bad value %q", ErrBadLine, l.value)
+	}
+	return l, nil
+}
+
+// add учитывает значение в агрегатах текущего интервала.
+func (s *Server) add(l line) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if l.mtype == typeSet {
+		if s.sets[l.name] == nil {
+			s.sets[l.name] = make(map[string]struct{})
+		}
+		s.sets[l.name][l.value] = struct{}{}
+		return
+	}
+	value, _ := strconv.ParseFloat(l.value, 64)
+	switch l.mtype {
+	case typeCounter:
+		s.counters[l.name] += value / l.rate
+	case typeGauge:
+		if l.relative {
+			value += s.gauges[l.name]
+		}
+		s.gauges[l.name] = value
+		s.updated[l.name] = struct{}{}
+	case typeTimer:
+		t, ok := s.timers[l.name]
+		if !ok {
+			t = &timer{}
+			s.timers[l.name] = t
+		}
+		t.values = append(t.values, value)
+		t.count += 1 / l.rate
+	}
+}
+
+// flush переносит агрегаты завершившегося интервала в буфер и начинает новый интервал.
+// Counter передается как приращение, для таймера передаются количество (counter),
+// сумма, минимум, максимум, среднее и квантили, для set - число уникальных значений.
+// Значения gauge сохраняются между интервалами, чтобы к ним можно было применять изменения +N/-N.
+func (s *Server) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	add := func(m collector.MetricRequest) {
+		if err := s.buffer.Add(m); err != nil {
+			s.log.Errorf("error while adding statsd metric %q: %s", m.ID, err.Error())
+		}
+	}
+	gauge := func(id string, v float64) {
+		add(collector.MetricRequest{ID: id, MType: collector.Gauge, Value: collector.PtrFloat64(v)})
+	}
+	counter := func(id string, v float64) {
+		add(collector.MetricRequest{ID: id, MType: collector.Counter, Delta: collector.PtrInt64(int64(math.Round(v)))})
+	}
+
+	for _, name := range sortedKeys(s.counters) {
+		counter(name, s.counters[name])
+	}
+	for _, name := range sortedKeys(s.updated) {
+		gauge(name, s.gauges[name])
+	}
+	for _, name := range sortedKeys(s.timers) {
+		t := s.timers[name]
+		sort.Float64s(t.values)
+		var sum float64
+		for _, v := range t.values {
+			sum += v
+		}
+		counter(name+"_count", t.count)
+		gauge(name+"_sum", sum)
+		gauge(name+"_min", t.values[0])
+		gauge(name+"_max", t.values[len(t.values)-1])
+		gauge(name+"_mean", sum/float64(len(t.values)))
+		for _, q := range timerQuantiles {
+			idx := int(math.Ceil(q.q*float64(len(t.values)))) - 1
+			gauge(name+q.suffix, t.values[max(idx, 0)])
+		}
+	}
+	for _, name := range sortedKeys(s.sets) {
+		gauge(name, float64(len(s.sets[name])))
+	}
+
+	s.counters = make(map[string]float64)
+	s.updated = make(map[string]struct{})
+	s.timers = make(map[string]*timer)
+	s.sets = make(map[string]map[string]struct{})
+}
+
+// sortedKeys возвращает ключи отображения в отсортированном порядке.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,103 @@
+package statsd
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseLine(t *testing.T) {
+	testCases := []struct {
+		name          string
+		raw           string
+		expected      line
+		expectedError bool
+	}{
+		{name: "counter", raw: "requests:1|c", expected: line{name: "requests", value: "1", mtype: "c", rate: 1}},
+		{name: "counter with rate", raw: "requests:1|c|@0.1", expected: line{name: "requests", value: "1", mtype: "c", rate: 0.1}},
+		{name: "gauge", raw: "queue:12|g", expected: line{name: "queue", value: "12", mtype: "g", rate: 1}},
+		{name: "relative gauge", raw: "queue:-3|g", expected: line{name: "queue", value: "-3", mtype: "g", rate: 1, relative: true}},
+		{name: "timer with tags", raw: "latency:320|ms|#env:prod", expected: line{name: "latency", value: "320", mtype: "ms", rate: 1}},
+		{name: "set", raw: "users:alice|s", expected: line{name: "users", value: "alice", mtype: "s", rate: 1}},
+		{name: "negative: no value", raw: "requests", expectedError: true},
+		{name: "negative: no type", raw: "requests:1", expectedError: true},
+		{name: "negative: bad type", raw: "requests:1|h", expectedError: true},
+		{name: "negative: bad value", raw: "requests:one|c", expectedError: true},
+		{name: "negative: bad rate", raw: "requests:1|c|@2", expectedError: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := parseLine(tt.raw)
+			if tt.expectedError {
+				assert.ErrorIs(t, err, ErrBadLine)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, l)
+		})
+	}
+}
+
+func TestServer_flush(t *testing.T) {
+	s := New("", "", time.Second, zap.NewNop().Sugar())
+	s.handlePacket([]byte("requests:1|c\nrequests:2|c|@0.5\nqueue:10|g\nqueue:+5|g\nusers:alice|s\nusers:bob|s\nusers:alice|s"))
+	for _, v := range []string{"10", "20", "30", "40"} {
+		s.handlePacket([]byte("latency:" + v + "|ms"))
+	}
+	s.flush()
+	assert.Equal(t, []collector.MetricRequest{
+		{ID: "requests", MType: collector.Counter, Delta: collector.PtrInt64(5)},
+		{ID: "queue", MType: collector.Gauge, Value: collector.PtrFloat64(15)},
+		{ID: "latency_count", MType: collector.Counter, Delta: collector.PtrInt64(4)},
+		{ID: "latency_sum", MType: collector.Gauge, Value: collector.PtrFloat64(100)},
+		{ID: "latency_min", MType: collector.Gauge, Value: collector.PtrFloat64(10)},
+		{ID: "latency_max", MType: collector.Gauge, Value: collector.PtrFloat64(40)},
+		{ID: "latency_mean", MType: collector.Gauge, Value: collector.PtrFloat64(25)},
+		{ID: "latency_p50", MType: collector.Gauge, Value: collector.PtrFloat64(20)},
+		{ID: "latency_p90", MType: collector.Gauge, Value: collector.PtrFloat64(40)},
+		{ID: "latency_p99", MType: collector.Gauge, Value: collector.PtrFloat64(40)},
+		{ID: "users", MType: collector.Gauge, Value: collector.PtrFloat64(2)},
+	}, s.Drain())
+
+	// gauge сохраняется между интервалами, но передается только после обновления
+	s.flush()
+	assert.Empty(t, s.Drain())
+	s.handlePacket([]byte("queue:-20|g"))
+	s.flush()
+	assert.Equal(t, []collector.MetricRequest{
+		{ID: "queue", MType: collector.Gauge, Value: collector.PtrFloat64(-5)},
+	}, s.Drain())
+}
+
+func TestServer_Start(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "statsd.sock")
+	s := New("127.0.0.1:0", socket, 50*time.Millisecond, zap.NewNop().Sugar())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, s.Start(ctx))
+
+	udp, err := net.Dial("udp", s.conns[0].LocalAddr().String())
+	assert.NoError(t, err)
+	defer udp.Close()
+	_, err = udp.Write([]byte("udp.requests:3|c"))
+	assert.NoError(t, err)
+
+	unix, err := net.Dial("unixgram", socket)
+	assert.NoError(t, err)
+	defer unix.Close()
+	_, err = unix.Write([]byte("unix.requests:4|c"))
+	assert.NoError(t, err)
+
+	received := make(map[string]int64)
+	assert.Eventually(t, func() bool {
+		for _, m := range s.Drain() {
+			received[m.ID] += *m.Delta
+		}
+		return received["udp.requests"] == 3 && received["unix.requests"] == 4
+	}, 2*time.Second, 20*time.Millisecond)
+}
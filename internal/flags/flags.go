@@ -8,6 +8,7 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 )
 
 const (
@@ -25,6 +26,12 @@ const (
 	defaultFileStoragePath = "/tmp/metrics-db.json"
 	// Восстанавливать состояние по умолчанию или нет
 	defaultRestore = true
+	// Каталог cgroup v2 контейнера по умолчанию
+	defaultCgroupPath = "/sys/fs/cgroup"
+	// Путь к файлу позиций чтения логов по умолчанию
+	defaultLogCheckpoint = "/tmp/agent-log-offsets.json"
+	// Интервал вычисления правил оповещений по умолчанию (в секундах)
+	defaultAlertInterval = 10
 )
 
 // Option - функция, которая изменяет поля структуры параметров
@@ -121,6 +128,144 @@ func WithStoreInterval() Option {
 	}
 }
 
+// WithExpiry Опция устанавливает время без обновлений (в секундах), после которого метрика
+// считается устаревшей и после которого она удаляется. Нулевое значение отключает этап.
+func WithExpiry() Option {
+	return func(p *Params) {
+		flag.IntVar(&p.StaleTTL, "stale-ttl", p.StaleTTL, "seconds without updates after which metric is stale")
+		if envStaleTTL := os.Getenv("STALE_TTL"); envStaleTTL != "" {
+			staleTTL, err := strconv.Atoi(envStaleTTL)
+			if err == nil {
+				p.StaleTTL = staleTTL
+			}
+		}
+		flag.IntVar(&p.DeleteTTL, "delete-ttl", p.DeleteTTL, "seconds without updates after which metric is deleted")
+		if envDeleteTTL := os.Getenv("DELETE_TTL"); envDeleteTTL != "" {
+			deleteTTL, err := strconv.Atoi(envDeleteTTL)
+			if err == nil {
+				p.DeleteTTL = deleteTTL
+			}
+		}
+	}
+}
+
+// WithAdminToken Опция устанавливает токен администратора, которым подписываются запросы
+// к административным эндпоинтам (удаление и сброс метрик, регистрация описаний).
+// Без токена административные эндпоинты отклоняют все запросы. Флаг open-metadata явно разрешает
+// регистрацию описаний метрик без токена.
+func WithAdminToken() Option {
+	return func(p *Params) {
+		flag.StringVar(&p.AdminToken, "admin-token", p.AdminToken, "token for admin endpoints")
+		if envAdminToken := os.Getenv("ADMIN_TOKEN"); envAdminToken != "" {
+			p.AdminToken = envAdminToken
+		}
+		flag.BoolVar(&p.OpenMetadata, "open-metadata", p.OpenMetadata, "allow registering metric metadata without admin token")
+		if envOpenMetadata := os.Getenv("OPEN_METADATA"); envOpenMetadata != "" {
+			openMetadata, err := strconv.ParseBool(envOpenMetadata)
+			if err == nil {
+				p.OpenMetadata = openMetadata
+			}
+		}
+	}
+}
+
+// WithLimits Опция устанавливает лимиты на число серий и на скорость записи (записей в секунду),
+// глобальные и для одного агента. Нулевое значение отключает лимит.
+func WithLimits() Option {
+	return func(p *Params) {
+		flag.IntVar(&p.MaxSeries, "max-series", p.MaxSeries, "max number of series")
+		if envMaxSeries := os.Getenv("MAX_SERIES"); envMaxSeries != "" {
+			maxSeries, err := strconv.Atoi(envMaxSeries)
+			if err == nil {
+				p.MaxSeries = maxSeries
+			}
+		}
+		flag.IntVar(&p.MaxSeriesPerAgent, "max-series-per-agent", p.MaxSeriesPerAgent, "max number of series per agent")
+		if envMaxSeriesPerAgent := os.Getenv("MAX_SERIES_PER_AGENT"); envMaxSeriesPerAgent != "" {
+			maxSeriesPerAgent, err := strconv.Atoi(envMaxSeriesPerAgent)
+			if err == nil {
+				p.MaxSeriesPerAgent = maxSeriesPerAgent
+			}
+		}
+		flag.Float64Var(&p.IngestRate, "ingest-rate", p.IngestRate, "max writes per second")
+		if envIngestRate := os.Getenv("INGEST_RATE"); envIngestRate != "" {
+			ingestRate, err := strconv.ParseFloat(envIngestRate, 64)
+			if err == nil {
+				p.IngestRate = ingestRate
+			}
+		}
+		flag.Float64Var(&p.IngestRatePerAgent, "ingest-rate-per-agent", p.IngestRatePerAgent, "max writes per second per agent")
+		if envIngestRatePerAgent := os.Getenv("INGEST_RATE_PER_AGENT"); envIngestRatePerAgent != "" {
+			ingestRatePerAgent, err := strconv.ParseFloat(envIngestRatePerAgent, 64)
+			if err == nil {
+				p.IngestRatePerAgent = ingestRatePerAgent
+			}
+		}
+	}
+}
+
+// WithAlerting Опция устанавливает файл правил оповещений, интервал их вычисления (в секундах)
+// и адрес, на который отправляются уведомления. Без файла правил оповещения отключены.
+// Флаг -check-rules проверяет файл правил и завершает работу без запуска сервера.
+func WithAlerting() Option {
+	return func(p *Params) {
+		flag.StringVar(&p.AlertRules, "alert-rules", p.AlertRules, "path to JSON file with alert rules")
+		if envAlertRules := os.Getenv("ALERT_RULES"); envAlertRules != "" {
+			p.AlertRules = envAlertRules
+		}
+		flag.IntVar(&p.AlertInterval, "alert-interval", p.AlertInterval, "alert rules evaluation interval in seconds")
+		if envAlertInterval := os.Getenv("ALERT_INTERVAL"); envAlertInterval != "" {
+			alertInterval, err := strconv.Atoi(envAlertInterval)
+			if err == nil {
+				p.AlertInterval = alertInterval
+			}
+		}
+		flag.StringVar(&p.AlertWebhook, "alert-webhook", p.AlertWebhook, "URL to send alert notifications to")
+		if envAlertWebhook := os.Getenv("ALERT_WEBHOOK"); envAlertWebhook != "" {
+			p.AlertWebhook = envAlertWebhook
+		}
+		flag.StringVar(&p.CheckRules, "check-rules", p.CheckRules, "validate alert rules file and exit")
+	}
+}
+
+// WithAlertEmail Опция устанавливает SMTP сервер, отправителя, получателей (через запятую) и файл шаблонов
+// писем с уведомлениями об оповещениях. Без SMTP сервера уведомления по почте отключены.
+func WithAlertEmail() Option {
+	return func(p *Params) {
+		flag.StringVar(&p.AlertSMTPAddr, "alert-smtp-address", p.AlertSMTPAddr, "SMTP server host:port to send alert emails through")
+		if envAlertSMTPAddr := os.Getenv("ALERT_SMTP_ADDRESS"); envAlertSMTPAddr != "" {
+			p.AlertSMTPAddr = envAlertSMTPAddr
+		}
+		flag.StringVar(&p.AlertSMTPUsername, "alert-smtp-username", p.AlertSMTPUsername, "SMTP username for PLAIN authentication")
+		if envAlertSMTPUsername := os.Getenv("ALERT_SMTP_USERNAME"); envAlertSMTPUsername != "" {
+			p.AlertSMTPUsername = envAlertSMTPUsername
+		}
+		flag.StringVar(&p.AlertSMTPPassword, "alert-smtp-password", p.AlertSMTPPassword, "SMTP password for PLAIN authentication")
+		if envAlertSMTPPassword := os.Getenv("ALERT_SMTP_PASSWORD"); envAlertSMTPPassword != "" {
+			p.AlertSMTPPassword = envAlertSMTPPassword
+		}
+		flag.BoolVar(&p.AlertSMTPStartTLS, "alert-smtp-starttls", p.AlertSMTPStartTLS, "require STARTTLS for SMTP connection")
+		if envAlertSMTPStartTLS := os.Getenv("ALERT_SMTP_STARTTLS"); envAlertSMTPStartTLS != "" {
+			startTLS, err := strconv.ParseBool(envAlertSMTPStartTLS)
+			if err == nil {
+				p.AlertSMTPStartTLS = startTLS
+			}
+		}
+		flag.StringVar(&p.AlertEmailFrom, "alert-email-from", p.AlertEmailFrom, "sender address of alert emails")
+		if envAlertEmailFrom := os.Getenv("ALERT_EMAIL_FROM"); envAlertEmailFrom != "" {
+			p.AlertEmailFrom = envAlertEmailFrom
+		}
+		flag.StringVar(&p.AlertEmailTo, "alert-email-to", p.AlertEmailTo, "comma-separated recipient addresses of alert emails")
+		if envAlertEmailTo := os.Getenv("ALERT_EMAIL_TO"); envAlertEmailTo != "" {
+			p.AlertEmailTo = envAlertEmailTo
+		}
+		flag.StringVar(&p.AlertEmailTemplate, "alert-email-template", p.AlertEmailTemplate, "path to file with subject, text and html alert email templates")
+		if envAlertEmailTemplate := os.Getenv("ALERT_EMAIL_TEMPLATE"); envAlertEmailTemplate != "" {
+			p.AlertEmailTemplate = envAlertEmailTemplate
+		}
+	}
+}
+
 // WithFileStoragePath Опция для указания путя хранения файла
 func WithFileStoragePath() Option {
 	return func(p *Params) {
@@ -157,6 +302,68 @@ func WithTLSKeyPath() Option {
 	}
 }
 
+// WithRuntimeMetrics возвращает опцию для установки allow-list метрик runtime/metrics агента.
+// Список задается через запятую, элементы сравниваются с префиксом имени метрики, например "/gc/,/sched/".
+func WithRuntimeMetrics() Option {
+	return func(p *Params) {
+		flag.Func("runtime-metrics", "comma separated allow-list of runtime/metrics names or prefixes", func(s string) error {
+			p.RuntimeMetrics = splitList(s)
+			return nil
+		})
+		if envRuntimeMetrics := os.Getenv("RUNTIME_METRICS"); envRuntimeMetrics != "" {
+			p.RuntimeMetrics = splitList(envRuntimeMetrics)
+		}
+	}
+}
+
+// WithCgroupPath возвращает опцию для установки каталога cgroup v2, из которого агент читает метрики контейнера.
+// Пустое значение отключает сбор метрик контейнера.
+func WithCgroupPath() Option {
+	return func(p *Params) {
+		flag.StringVar(&p.CgroupPath, "cgroup-path", p.CgroupPath, "cgroup v2 directory for container metrics")
+		if envCgroupPath, ok := os.LookupEnv("CGROUP_PATH"); ok {
+			p.CgroupPath = envCgroupPath
+		}
+	}
+}
+
+// WithStatsd возвращает опцию для установки адресов встроенного StatsD-приемника агента:
+// UDP-адреса и пути к Unix-сокету (datagram). Пустое значение отключает соответствующий приемник.
+func WithStatsd() Option {
+	return func(p *Params) {
+		flag.StringVar(&p.StatsdAddr, "statsd-addr", p.StatsdAddr, "udp address for statsd listener")
+		if envStatsdAddr := os.Getenv("STATSD_ADDRESS"); envStatsdAddr != "" {
+			p.StatsdAddr = envStatsdAddr
+		}
+		flag.StringVar(&p.StatsdSocket, "statsd-socket", p.StatsdSocket, "unix datagram socket path for statsd listener")
+		if envStatsdSocket := os.Getenv("STATSD_SOCKET"); envStatsdSocket != "" {
+			p.StatsdSocket = envStatsdSocket
+		}
+	}
+}
+
+// WithLogCheckpoint возвращает опцию для установки файла, в котором агент сохраняет позиции чтения логов.
+// Пустое значение отключает сохранение позиций.
+func WithLogCheckpoint() Option {
+	return func(p *Params) {
+		flag.StringVar(&p.LogCheckpoint, "log-checkpoint", p.LogCheckpoint, "file for log tailing offsets")
+		if envLogCheckpoint, ok := os.LookupEnv("LOG_CHECKPOINT"); ok {
+			p.LogCheckpoint = envLogCheckpoint
+		}
+	}
+}
+
+// splitList разбивает строку со списком значений через запятую, отбрасывая пустые элементы.
+func splitList(s string) []string {
+	result := make([]string, 0)
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
 // WithGrpcAddr возвращает опцию для установки адреса сервера gRPC.
 // Функция позволяет установить адрес, по которому будет запущен сервер gRPC.
 func WithGrpcAddr() Option {
@@ -224,6 +431,9 @@ func Init(opts ...Option) *Params {
 		Restore:         defaultRestore,
 		GrpcRunAddr:     defaultGrpcAddr,
 		DisableGrpc:     true,
+		CgroupPath:      defaultCgroupPath,
+		LogCheckpoint:   defaultLogCheckpoint,
+		AlertInterval:   defaultAlertInterval,
 	}
 
 	for _, opt := range opts {
@@ -247,4 +457,89 @@ type Params struct {
 	CryptoKeyPath   string `json:"crypto_key"`      // Путь к криптографическому ключу
 	GrpcRunAddr     string `json:"grpc_address"`    // Адрес и порт для запуска сервера grpc
 	DisableGrpc     bool   `json:"disable_grpc"`    // Отключить сервер grpc
+	StaleTTL        int    `json:"stale_ttl"`       // Время без обновлений, после которого метрика устаревает
+	DeleteTTL       int    `json:"delete_ttl"`      // Время без обновлений, после которого метрика удаляется
+	AdminToken      string `json:"admin_token"`     // Токен администратора
+	OpenMetadata    bool   `json:"open_metadata"`   // Регистрация описаний метрик без токена администратора
+
+	MaxSeries          int     `json:"max_series"`            // Лимит числа серий
+	MaxSeriesPerAgent  int     `json:"max_series_per_agent"`  // Лимит числа серий одного агента
+	IngestRate         float64 `json:"ingest_rate"`           // Лимит записей в секунду
+	IngestRatePerAgent float64 `json:"ingest_rate_per_agent"` // Лимит записей в секунду от одного агента
+
+	AlertRules    string `json:"alert_rules"`    // Путь к файлу правил оповещений
+	AlertInterval int    `json:"alert_interval"` // Интервал вычисления правил оповещений
+	AlertWebhook  string `json:"alert_webhook"`  // Адрес для отправки уведомлений
+	CheckRules    string `json:"-"`              // Файл правил оповещений для проверки без запуска сервера
+
+	AlertSMTPAddr      string `json:"alert_smtp_address"`   // Адрес SMTP сервера для писем с уведомлениями
+	AlertSMTPUsername  string `json:"alert_smtp_username"`  // Имя пользователя SMTP
+	AlertSMTPPassword  string `json:"alert_smtp_password"`  // Пароль SMTP
+	AlertSMTPStartTLS  bool   `json:"alert_smtp_starttls"`  // Требовать STARTTLS
+	AlertEmailFrom     string `json:"alert_email_from"`     // Адрес отправителя писем
+	AlertEmailTo       string `json:"alert_email_to"`       // Адреса получателей писем через запятую
+	AlertEmailTemplate string `json:"alert_email_template"` // Путь к файлу шаблонов писем
+
+	RuntimeMetrics []string         `json:"runtime_metrics"` // Allow-list метрик runtime/metrics агента
+	Processes      []ProcessTarget  `json:"processes"`       // Процессы, метрики которых собирает агент
+	CgroupPath     string           `json:"cgroup_path"`     // Каталог cgroup v2 для метрик контейнера
+	Scripts        []ScriptCheck    `json:"scripts"`         // Команды, вывод которых агент разбирает как метрики
+	Scrape         []ScrapeTarget   `json:"scrape"`          // Локальные эндпоинты Prometheus, которые опрашивает агент
+	StatsdAddr     string           `json:"statsd_address"`  // UDP-адрес StatsD-приемника агента
+	StatsdSocket   string           `json:"statsd_socket"`   // Путь к Unix-сокету StatsD-приемника агента
+	LogFiles       []LogFile        `json:"log_files"`       // Файлы логов, из которых агент извлекает метрики
+	LogCheckpoint  string           `json:"log_checkpoint"`  // Файл с сохраненными позициями чтения логов
+	MetricMetadata []MetricMetadata `json:"metric_metadata"` // Описания метрик, которые агент передает вместе со значениями
+}
+
+// MetricMetadata описывает метрику: единицу измерения, назначение, ожидаемый тип и владельца.
+type MetricMetadata struct {
+	Name        string `json:"name"`        // Имя метрики
+	Unit        string `json:"unit"`        // Единица измерения
+	Description string `json:"description"` // Описание метрики
+	Type        string `json:"type"`        // Ожидаемый тип метрики: counter, gauge или histogram
+	Owner       string `json:"owner"`       // Владелец метрики
+}
+
+// LogFile описывает файл лога, который агент читает по мере дописывания, и правила извлечения метрик.
+type LogFile struct {
+	Path  string    `json:"path"`  // Путь к файлу лога
+	Rules []LogRule `json:"rules"` // Правила извлечения метрик из строк
+}
+
+// LogRule описывает правило извлечения метрики из строки лога.
+// Для counter каждая подходящая строка увеличивает метрику на 1 или на значение группы value_group
+// (строки с отрицательным значением пропускаются), для gauge значение метрики берется из группы value_group.
+type LogRule struct {
+	Pattern    string `json:"pattern"`     // Регулярное выражение для строки
+	Metric     string `json:"metric"`      // Имя метрики
+	Type       string `json:"type"`        // Тип метрики: counter или gauge
+	ValueGroup string `json:"value_group"` // Имя группы регулярного выражения со значением
+}
+
+// ScrapeTarget описывает эндпоинт в формате Prometheus, который агент опрашивает по расписанию.
+type ScrapeTarget struct {
+	URL        string `json:"url"`         // Адрес эндпоинта, например http://127.0.0.1:9100/metrics
+	Interval   int    `json:"interval"`    // Интервал опроса в секундах
+	Timeout    int    `json:"timeout"`     // Таймаут запроса в секундах
+	KeepLabels bool   `json:"keep_labels"` // Сохранять метки в имени как name{a="1"}, иначе name_a_1
+	Prefix     string `json:"prefix"`      // Префикс имен метрик эндпоинта
+}
+
+// ScriptCheck описывает команду, которую агент запускает по расписанию и вывод которой разбирает как метрики.
+type ScriptCheck struct {
+	Name     string   `json:"name"`     // Имя проверки для логов
+	Command  []string `json:"command"`  // Исполняемый файл и аргументы (без оболочки)
+	Interval int      `json:"interval"` // Интервал запуска в секундах
+	Timeout  int      `json:"timeout"`  // Таймаут выполнения в секундах
+	Format   string   `json:"format"`   // Формат вывода: lines, json или prometheus
+}
+
+// ProcessTarget описывает процесс, метрики которого собирает агент.
+// Процесс ищется по одному из признаков: имени, PID-файлу или регулярному выражению для командной строки.
+type ProcessTarget struct {
+	Label   string `json:"label"`    // Метка процесса в именах метрик
+	Name    string `json:"name"`     // Имя исполняемого файла
+	PidFile string `json:"pid_file"` // Путь к PID-файлу
+	Cmdline string `json:"cmdline"`  // Регулярное выражение для командной строки
 }
@@ -0,0 +1,47 @@
+// Package alerthistory описывает события истории оповещений и условия их выборки.
+// Пакет не зависит от движка оповещений, поэтому хранилища сохраняют историю, не импортируя alerting.
+package alerthistory
+
+import "time"
+
+// DefaultLimit - число событий истории, возвращаемое по умолчанию.
+const DefaultLimit = 1000
+
+// Event - переход оповещения в состояние firing или resolved.
+type Event struct {
+	ID       string            `json:"id,omitempty"`       // идентификатор оповещения
+	Time     time.Time         `json:"time"`               // время перехода
+	Rule     string            `json:"rule"`               // имя правила
+	Labels   map[string]string `json:"labels"`             // метки правила и экземпляра
+	Severity string            `json:"severity,omitempty"` // важность оповещения
+	State    string            `json:"state"`              // новое состояние
+	Value    float64           `json:"value"`              // значение на момент перехода
+	Summary  string            `json:"summary"`            // описание оповещения
+}
+
+// Query - условия выборки истории оповещений; нулевые значения не ограничивают выборку.
+type Query struct {
+	Rule  string    // имя правила
+	From  time.Time // начало периода включительно
+	To    time.Time // конец периода не включительно
+	Limit int       // наибольшее число последних событий, по умолчанию DefaultLimit
+}
+
+// Match сообщает, подходит ли событие под условия выборки без учета Limit.
+func (q Query) Match(e Event) bool {
+	if q.Rule != "" && e.Rule != q.Rule {
+		return false
+	}
+	if !q.From.IsZero() && e.Time.Before(q.From) {
+		return false
+	}
+	return q.To.IsZero() || e.Time.Before(q.To)
+}
+
+// Size возвращает наибольшее число событий в ответе.
+func (q Query) Size() int {
+	if q.Limit <= 0 {
+		return DefaultLimit
+	}
+	return q.Limit
+}
@@ -0,0 +1,34 @@
+package alerthistory
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestQuery_Match(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := Event{Time: at, Rule: "cpu"}
+	testCases := []struct {
+		name     string
+		query    Query
+		expected bool
+	}{
+		{name: "empty query", query: Query{}, expected: true},
+		{name: "same rule", query: Query{Rule: "cpu"}, expected: true},
+		{name: "other rule", query: Query{Rule: "memory"}},
+		{name: "from inclusive", query: Query{From: at}, expected: true},
+		{name: "to exclusive", query: Query{To: at}},
+		{name: "inside period", query: Query{From: at.Add(-time.Hour), To: at.Add(time.Hour)}, expected: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.query.Match(event))
+		})
+	}
+}
+
+func TestQuery_Size(t *testing.T) {
+	assert.Equal(t, DefaultLimit, Query{}.Size())
+	assert.Equal(t, 10, Query{Limit: 10}.Size())
+}
@@ -0,0 +1,186 @@
+package alerting
+
+import (
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
+	"math"
+	"time"
+)
+
+// Модели обнаружения аномалий.
+const (
+	// ModelEWMA - экспоненциально взвешенные среднее и дисперсия, аномалия определяется по z-оценке.
+	ModelEWMA = "ewma"
+	// ModelHoltWinters - аддитивная модель Холта-Винтерса с уровнем, трендом и сезонной составляющей.
+	ModelHoltWinters = "holt_winters"
+)
+
+// Значения параметров аномалий по умолчанию.
+const (
+	defaultAlpha          = 0.3
+	defaultBeta           = 0.1
+	defaultGamma          = 0.1
+	defaultSensitivity    = 3
+	defaultSeasonBuckets  = 24
+	minAnomalyObservation = 5    // минимальное число наблюдений до срабатывания
+	minDeviation          = 1e-9 // нижняя граница отклонения, чтобы z-оценка оставалась конечной
+)
+
+// Имена производных метрик, которые публикуют правила anomaly. Метрики записываются
+// с метками rule и metric, чтобы ожидаемое значение и границы можно было построить на графике.
+const (
+	AnomalyExpectedMetric = collector.ReservedPrefix + "anomaly_expected" // ожидаемое значение
+	AnomalyLowerMetric    = collector.ReservedPrefix + "anomaly_lower"    // нижняя граница нормы
+	AnomalyUpperMetric    = collector.ReservedPrefix + "anomaly_upper"    // верхняя граница нормы
+	AnomalyScoreMetric    = collector.ReservedPrefix + "anomaly_score"    // z-оценка последнего значения
+)
+
+// anomalyModel - состояние модели аномалий одного правила, обновляется при каждом новом значении метрики.
+// Модель одна на правило, поскольку правило anomaly следит за одной метрикой без меток. Значения
+// учитываются только при вычислении правил: если метрика обновилась несколько раз между вычислениями,
+// модель видит лишь последнее значение, поэтому интервал вычисления не должен превышать интервал отчетов.
+type anomalyModel struct {
+	Count    int       `json:"count"`              // число наблюдений
+	Started  time.Time `json:"started"`            // время первого наблюдения
+	Updated  time.Time `json:"updated"`            // время последнего учтенного значения
+	Level    float64   `json:"level"`              // среднее (EWMA) или уровень (Holt-Winters)
+	Trend    float64   `json:"trend,omitempty"`    // тренд Holt-Winters
+	Seasonal []float64 `json:"seasonal,omitempty"` // сезонные составляющие Holt-Winters
+	Variance float64   `json:"variance"`           // экспоненциально взвешенная дисперсия ошибки прогноза
+	Value    float64   `json:"value"`              // последнее значение
+	Expected float64   `json:"expected"`           // прогноз для последнего значения
+	Score    float64   `json:"score"`              // z-оценка последнего значения
+}
+
+// observe учитывает значение x, полученное в момент t, и вычисляет его z-оценку относительно прогноза,
+// сделанного до учета значения.
+func (m *anomalyModel) observe(rule Rule, x float64, t time.Time) {
+	alpha := param(rule.Alpha, defaultAlpha)
+	if m.Count == 0 {
+		m.Started = t
+		m.Level = x
+		if rule.Model == ModelHoltWinters {
+			m.Seasonal = make([]float64, seasonBuckets(rule))
+		}
+	}
+	m.Count++
+	m.Updated = t
+	m.Value = x
+
+	switch rule.Model {
+	case ModelHoltWinters:
+		beta, gamma := param(rule.Beta, defaultBeta), param(rule.Gamma, defaultGamma)
+		b := seasonBucket(rule, t)
+		m.Expected = m.Level + m.Trend + m.Seasonal[b]
+		level := alpha*(x-m.Seasonal[b]) + (1-alpha)*(m.Level+m.Trend)
+		m.Trend = beta*(level-m.Level) + (1-beta)*m.Trend
+		m.Level = level
+		m.Seasonal[b] = gamma*(x-level) + (1-gamma)*m.Seasonal[b]
+	default:
+		m.Expected = m.Level
+		m.Level += alpha * (x - m.Level)
+	}
+
+	err := x - m.Expected
+	m.Score = err / math.Max(math.Sqrt(m.Variance), minDeviation)
+	if m.Count == 1 {
+		m.Score = 0
+	}
+	m.Variance = (1 - alpha) * (m.Variance + alpha*err*err)
+}
+
+// deviation возвращает допустимое отклонение от прогноза при чувствительности правила.
+func (m *anomalyModel) deviation(rule Rule) float64 {
+	return param(rule.Sensitivity, defaultSensitivity) * math.Sqrt(m.Variance)
+}
+
+// fits сообщает, подходит ли сохраненная модель правилу rule: правило должно быть правилом anomaly,
+// а сезонные составляющие должны соответствовать его модели и числу сезонных корзин.
+func (m *anomalyModel) fits(rule Rule) bool {
+	if rule.Condition != ConditionAnomaly {
+		return false
+	}
+	if rule.Model == ModelHoltWinters {
+		return m.Count == 0 || len(m.Seasonal) == seasonBuckets(rule)
+	}
+	return len(m.Seasonal) == 0
+}
+
+// warmedUp сообщает, завершился ли период прогрева модели.
+func (m *anomalyModel) warmedUp(rule Rule, now time.Time) bool {
+	warmUp := time.Duration(rule.WarmUp)
+	if warmUp == 0 && rule.Model == ModelHoltWinters {
+		warmUp = time.Duration(rule.Season)
+	}
+	return m.Count >= minAnomalyObservation && now.Sub(m.Started) >= warmUp
+}
+
+// evaluateAnomaly учитывает новое значение метрики в модели правила, публикует ожидаемое значение
+// и границы нормы и проверяет, выходит ли z-оценка за чувствительность правила после прогрева.
+func (e *Engine) evaluateAnomaly(rule Rule, now time.Time) []result {
+	c := collector.Collector()
+	res := result{labels: ruleLabels(rule, "metric", rule.Metric)}
+	m, ok := e.models[rule.Name]
+	if !ok {
+		m = &anomalyModel{}
+		e.models[rule.Name] = m
+	}
+	updated, seen := c.MetricLastSeen(rule.Metric)
+	value, numeric := c.NumericValue(rule.Metric)
+	if seen && numeric && updated.After(m.Updated) {
+		m.observe(rule, value, updated)
+		deviation := m.deviation(rule)
+		c.SetGauge(anomalyMetricID(AnomalyExpectedMetric, rule), m.Expected)
+		c.SetGauge(anomalyMetricID(AnomalyLowerMetric, rule), m.Expected-deviation)
+		c.SetGauge(anomalyMetricID(AnomalyUpperMetric, rule), m.Expected+deviation)
+		c.SetGauge(anomalyMetricID(AnomalyScoreMetric, rule), m.Score)
+	}
+	if m.Count == 0 || !m.warmedUp(rule, now) {
+		res.summary = fmt.Sprintf("anomaly model of metric %q is warming up", rule.Metric)
+		return []result{res}
+	}
+	res.value = m.Score
+	res.firing = math.Abs(m.Score) > param(rule.Sensitivity, defaultSensitivity)
+	res.summary = fmt.Sprintf("metric %q is %g, expected %g, z-score %.2f", rule.Metric, m.Value, m.Expected, m.Score)
+	return []result{res}
+}
+
+// anomalyMetricID возвращает имя производной метрики name правила rule с метками rule и metric.
+func anomalyMetricID(name string, rule Rule) string {
+	return selfmetrics.ID(name, "metric", rule.Metric, "rule", rule.Name)
+}
+
+// deleteAnomalyMetrics удаляет производные метрики правила rule, чтобы после удаления
+// или изменения правила их серии больше не публиковались.
+func deleteAnomalyMetrics(rule Rule) {
+	c := collector.Collector()
+	for _, name := range []string{AnomalyExpectedMetric, AnomalyLowerMetric, AnomalyUpperMetric, AnomalyScoreMetric} {
+		// метрики может не быть, если модель еще не получила ни одного значения
+		_ = c.DeleteMetric(anomalyMetricID(name, rule), collector.Gauge)
+	}
+}
+
+// param возвращает значение параметра правила или значение по умолчанию, если параметр не задан.
+func param(value, defaultValue float64) float64 {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// seasonBuckets возвращает число сезонных корзин правила.
+func seasonBuckets(rule Rule) int {
+	if rule.SeasonBuckets <= 0 {
+		return defaultSeasonBuckets
+	}
+	return rule.SeasonBuckets
+}
+
+// seasonBucket возвращает сезонную корзину, в которую попадает момент t.
+func seasonBucket(rule Rule, t time.Time) int {
+	season := time.Duration(rule.Season)
+	buckets := seasonBuckets(rule)
+	offset := time.Duration(t.UnixNano() % int64(season))
+	return int(offset * time.Duration(buckets) / season)
+}
@@ -0,0 +1,170 @@
+package alerting
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAnomalyModel_Observe(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name         string
+		rule         Rule
+		values       []float64
+		step         time.Duration
+		expectedLast float64 // ожидаемое значение перед последним наблюдением
+		anomalous    bool
+	}{
+		{
+			name:         "ewma: stable series",
+			rule:         Rule{Model: ModelEWMA},
+			values:       []float64{10, 11, 9, 10, 11, 9, 10, 10},
+			step:         time.Second,
+			expectedLast: 10,
+		},
+		{
+			name:         "ewma: spike",
+			rule:         Rule{Model: ModelEWMA},
+			values:       []float64{10, 11, 9, 10, 11, 9, 10, 50},
+			step:         time.Second,
+			expectedLast: 10,
+			anomalous:    true,
+		},
+		{
+			// два сезона по две корзины: днем 100, ночью 10; ночное значение 10 - норма
+			name:         "holt_winters: seasonal pattern",
+			rule:         Rule{Model: ModelHoltWinters, Season: Duration(2 * time.Hour), SeasonBuckets: 2, Alpha: 0.5, Gamma: 0.9},
+			values:       []float64{100, 10, 100, 10, 100, 10, 100, 10, 100, 10, 100, 10},
+			step:         time.Hour,
+			expectedLast: 10,
+		},
+		{
+			name:         "holt_winters: missing peak",
+			rule:         Rule{Model: ModelHoltWinters, Season: Duration(2 * time.Hour), SeasonBuckets: 2, Alpha: 0.5, Gamma: 0.9},
+			values:       []float64{100, 10, 100, 10, 100, 10, 100, 10, 100, 10, 10},
+			step:         time.Hour,
+			expectedLast: 100,
+			anomalous:    true,
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &anomalyModel{}
+			for i, v := range tt.values {
+				m.observe(tt.rule, v, start.Add(time.Duration(i)*tt.step))
+			}
+			assert.Equal(t, len(tt.values), m.Count)
+			assert.InDelta(t, tt.expectedLast, m.Expected, math.Abs(tt.expectedLast)*0.2)
+			assert.Equal(t, tt.anomalous, math.Abs(m.Score) > defaultSensitivity, "score %g", m.Score)
+		})
+	}
+}
+
+func TestAnomalyModel_WarmedUp(t *testing.T) {
+	start := time.Now()
+	m := &anomalyModel{Count: minAnomalyObservation, Started: start}
+	assert.True(t, m.warmedUp(Rule{Model: ModelEWMA}, start))
+	assert.False(t, m.warmedUp(Rule{Model: ModelEWMA, WarmUp: Duration(time.Hour)}, start.Add(time.Minute)))
+	assert.False(t, m.warmedUp(Rule{Model: ModelHoltWinters, Season: Duration(24 * time.Hour)}, start.Add(time.Hour)))
+	assert.True(t, m.warmedUp(Rule{Model: ModelHoltWinters, Season: Duration(24 * time.Hour)}, start.Add(24*time.Hour)))
+	m.Count--
+	assert.False(t, m.warmedUp(Rule{Model: ModelEWMA}, start))
+}
+
+func TestEngine_EvaluateAnomaly(t *testing.T) {
+	c := collector.Collector()
+	e := New([]Rule{
+		{Name: "latency-anomaly", Condition: ConditionAnomaly, Metric: "AnomalyLatency", Model: ModelEWMA},
+	}, nil, zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	for _, v := range []float64{20, 21, 19, 20, 21, 19, 20} {
+		require.NoError(t, c.Collect(collector.MetricRequest{ID: "AnomalyLatency", MType: "gauge", Value: collector.PtrFloat64(v)}, strconv.FormatFloat(v, 'f', -1, 64)))
+		e.Evaluate(ctx, time.Now())
+	}
+	assert.Empty(t, e.Alerts())
+	expected, err := c.GetMetric(selfmetrics.ID(AnomalyExpectedMetric, "metric", "AnomalyLatency", "rule", "latency-anomaly"))
+	require.NoError(t, err)
+	assert.InDelta(t, 20, *expected.GaugeValue, 1)
+	_, err = c.GetMetric(selfmetrics.ID(AnomalyUpperMetric, "metric", "AnomalyLatency", "rule", "latency-anomaly"))
+	assert.NoError(t, err)
+
+	// без нового значения модель не обновляется
+	e.Evaluate(ctx, time.Now())
+	assert.Equal(t, 7, e.models["latency-anomaly"].Count)
+
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "AnomalyLatency", MType: "gauge", Value: collector.PtrFloat64(200)}, "200"))
+	e.Evaluate(ctx, time.Now())
+	alerts := e.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, map[string]string{"metric": "AnomalyLatency"}, alerts[0].Labels)
+	assert.Greater(t, alerts[0].Value, float64(defaultSensitivity))
+
+	// модель сохраняется вместе с оповещениями
+	data, err := e.State()
+	require.NoError(t, err)
+	restored := New(e.rules, nil, zap.NewNop().Sugar())
+	require.NoError(t, restored.Restore(data))
+	assert.Equal(t, e.models["latency-anomaly"].Count, restored.models["latency-anomaly"].Count)
+	assert.Equal(t, e.models["latency-anomaly"].Level, restored.models["latency-anomaly"].Level)
+}
+
+func TestEngine_ReloadAnomalyMetrics(t *testing.T) {
+	c := collector.Collector()
+	kept := Rule{Name: "kept-anomaly", Condition: ConditionAnomaly, Metric: "ReloadAnomaly", Model: ModelEWMA}
+	changed := Rule{Name: "changed-anomaly", Condition: ConditionAnomaly, Metric: "ReloadAnomaly", Model: ModelEWMA}
+	e := New([]Rule{kept, changed}, nil, zap.NewNop().Sugar())
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "ReloadAnomaly", MType: "gauge", Value: collector.PtrFloat64(1)}, "1"))
+	e.Evaluate(context.Background(), time.Now())
+
+	changed.Sensitivity = 5
+	e.Reload(RuleFile{Rules: []Rule{kept, changed}}, nil)
+	_, err := c.GetMetric(anomalyMetricID(AnomalyScoreMetric, kept))
+	assert.NoError(t, err)
+	_, err = c.GetMetric(anomalyMetricID(AnomalyScoreMetric, changed))
+	assert.ErrorIs(t, err, collector.ErrNotFound)
+
+	e.Reload(RuleFile{}, nil)
+	for _, name := range []string{AnomalyExpectedMetric, AnomalyLowerMetric, AnomalyUpperMetric, AnomalyScoreMetric} {
+		_, err = c.GetMetric(anomalyMetricID(name, kept))
+		assert.ErrorIs(t, err, collector.ErrNotFound)
+	}
+}
+
+func TestEngine_RestoreAnomalyModels(t *testing.T) {
+	season := Duration(time.Hour)
+	saved := New([]Rule{
+		{Name: "ewma-to-hw", Condition: ConditionAnomaly, Metric: "RestoreAnomaly", Model: ModelEWMA},
+		{Name: "fewer-buckets", Condition: ConditionAnomaly, Metric: "RestoreAnomaly", Model: ModelHoltWinters, Season: season, SeasonBuckets: 24},
+		{Name: "kept", Condition: ConditionAnomaly, Metric: "RestoreAnomaly", Model: ModelHoltWinters, Season: season, SeasonBuckets: 24},
+		{Name: "removed", Condition: ConditionAnomaly, Metric: "RestoreAnomaly", Model: ModelEWMA},
+	}, nil, zap.NewNop().Sugar())
+	c := collector.Collector()
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "RestoreAnomaly", MType: "gauge", Value: collector.PtrFloat64(1)}, "1"))
+	saved.Evaluate(context.Background(), time.Now())
+	data, err := saved.State()
+	require.NoError(t, err)
+
+	restored := New([]Rule{
+		{Name: "ewma-to-hw", Condition: ConditionAnomaly, Metric: "RestoreAnomaly", Model: ModelHoltWinters, Season: season},
+		{Name: "fewer-buckets", Condition: ConditionAnomaly, Metric: "RestoreAnomaly", Model: ModelHoltWinters, Season: season, SeasonBuckets: 4},
+		{Name: "kept", Condition: ConditionAnomaly, Metric: "RestoreAnomaly", Model: ModelHoltWinters, Season: season, SeasonBuckets: 24},
+	}, nil, zap.NewNop().Sugar())
+	require.NoError(t, restored.Restore(data))
+	assert.NotContains(t, restored.models, "ewma-to-hw")
+	assert.NotContains(t, restored.models, "fewer-buckets")
+	assert.NotContains(t, restored.models, "removed")
+	assert.Contains(t, restored.models, "kept")
+
+	// первое вычисление после восстановления не должно завершаться паникой
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "RestoreAnomaly", MType: "gauge", Value: collector.PtrFloat64(2)}, "2"))
+	assert.NotPanics(t, func() { restored.Evaluate(context.Background(), time.Now()) })
+}
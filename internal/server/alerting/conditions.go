@@ -0,0 +1,111 @@
+package alerting
+
+import (
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"time"
+)
+
+// evaluateValue вычисляет условия threshold, increase, rate и deriv по текущему значению
+// и истории значений метрики. Если данных для расчета недостаточно, оповещение не срабатывает.
+func (e *Engine) evaluateValue(rule Rule, now time.Time) []result {
+	c := collector.Collector()
+	var (
+		value float64
+		ok    bool
+	)
+	switch rule.Condition {
+	case ConditionThreshold:
+		value, ok = c.NumericValue(rule.Metric)
+	case ConditionIncrease:
+		value, _, ok = increase(window(c.History(rule.Metric), now, time.Duration(rule.Window)))
+	case ConditionRate:
+		var elapsed time.Duration
+		value, elapsed, ok = increase(window(c.History(rule.Metric), now, time.Duration(rule.Window)))
+		if ok && elapsed > 0 {
+			value = value / elapsed.Seconds() * per(rule).Seconds()
+		} else {
+			ok = false
+		}
+	case ConditionDeriv:
+		value, ok = deriv(window(c.History(rule.Metric), now, time.Duration(rule.Window)))
+		value *= per(rule).Seconds()
+	}
+	res := result{labels: ruleLabels(rule, "metric", rule.Metric)}
+	if !ok {
+		res.summary = fmt.Sprintf("not enough data to compute %s of metric %q", rule.Condition, rule.Metric)
+		return []result{res}
+	}
+	res.value = value
+	res.firing = operators[rule.Op](value, rule.Threshold)
+	res.summary = fmt.Sprintf("%s of metric %q is %g (%s %g)", rule.Condition, rule.Metric, value, rule.Op, rule.Threshold)
+	return []result{res}
+}
+
+// per возвращает единицу времени правила для rate и deriv.
+func per(rule Rule) time.Duration {
+	if rule.Per <= 0 {
+		return time.Second
+	}
+	return time.Duration(rule.Per)
+}
+
+// window возвращает значения истории за окно (now-w, now] и последнее значение перед окном,
+// если оно есть, как начальное значение.
+func window(samples []collector.Sample, now time.Time, w time.Duration) []collector.Sample {
+	start := now.Add(-w)
+	first := len(samples)
+	for i, s := range samples {
+		if s.Time.After(start) {
+			first = i
+			break
+		}
+	}
+	if first > 0 {
+		first--
+	}
+	last := first
+	for last < len(samples) && !samples[last].Time.After(now) {
+		last++
+	}
+	return samples[first:last]
+}
+
+// increase возвращает прирост counter по значениям и время между первым и последним значением.
+// Уменьшение значения считается сбросом счетчика, и приростом становится новое значение.
+func increase(samples []collector.Sample) (float64, time.Duration, bool) {
+	if len(samples) < 2 {
+		return 0, 0, false
+	}
+	var total float64
+	for i := 1; i < len(samples); i++ {
+		if delta := samples[i].Value - samples[i-1].Value; delta >= 0 {
+			total += delta
+		} else {
+			total += samples[i].Value
+		}
+	}
+	return total, samples[len(samples)-1].Time.Sub(samples[0].Time), true
+}
+
+// deriv возвращает скорость изменения значения в секунду, оцененную методом наименьших квадратов.
+func deriv(samples []collector.Sample) (float64, bool) {
+	if len(samples) < 2 {
+		return 0, false
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	origin := samples[0].Time
+	for _, s := range samples {
+		x := s.Time.Sub(origin).Seconds()
+		sumX += x
+		sumY += s.Value
+		sumXY += x * s.Value
+		sumXX += x * x
+	}
+	n := float64(len(samples))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denominator, true
+}
@@ -0,0 +1,115 @@
+package alerting
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+// samplesAt создает значения истории с интервалом в секунду, начиная с start.
+func samplesAt(start time.Time, values ...float64) []collector.Sample {
+	samples := make([]collector.Sample, len(values))
+	for i, v := range values {
+		samples[i] = collector.Sample{Time: start.Add(time.Duration(i) * time.Second), Value: v}
+	}
+	return samples
+}
+
+func TestWindow(t *testing.T) {
+	start := time.Now()
+	samples := samplesAt(start, 0, 1, 2, 3, 4)
+	assert.Equal(t, samples[1:4], window(samples, start.Add(3*time.Second), 1500*time.Millisecond))
+	assert.Equal(t, samples[4:], window(samples, start.Add(time.Minute), time.Second))
+	assert.Equal(t, samples[:1], window(samples, start, time.Minute))
+	assert.Empty(t, window(nil, start, time.Minute))
+}
+
+func TestIncrease(t *testing.T) {
+	start := time.Now()
+	testCases := []struct {
+		name             string
+		samples          []collector.Sample
+		expectedIncrease float64
+		expectedElapsed  time.Duration
+		expectedOk       bool
+	}{
+		{name: "growing", samples: samplesAt(start, 10, 12, 15), expectedIncrease: 5, expectedElapsed: 2 * time.Second, expectedOk: true},
+		{name: "reset", samples: samplesAt(start, 10, 12, 3, 4), expectedIncrease: 6, expectedElapsed: 3 * time.Second, expectedOk: true},
+		{name: "negative: one sample", samples: samplesAt(start, 10)},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			value, elapsed, ok := increase(tt.samples)
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.Equal(t, tt.expectedIncrease, value)
+			assert.Equal(t, tt.expectedElapsed, elapsed)
+		})
+	}
+}
+
+func TestDeriv(t *testing.T) {
+	start := time.Now()
+	testCases := []struct {
+		name          string
+		samples       []collector.Sample
+		expectedDeriv float64
+		expectedOk    bool
+	}{
+		{name: "falling", samples: samplesAt(start, 100, 90, 80, 70), expectedDeriv: -10, expectedOk: true},
+		{name: "noisy", samples: samplesAt(start, 0, 3, 2, 5), expectedDeriv: 1.4, expectedOk: true},
+		{name: "negative: one sample", samples: samplesAt(start, 1)},
+		{name: "negative: same time", samples: []collector.Sample{{Time: start, Value: 1}, {Time: start, Value: 2}}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			value, ok := deriv(tt.samples)
+			assert.Equal(t, tt.expectedOk, ok)
+			assert.InDelta(t, tt.expectedDeriv, value, 1e-9)
+		})
+	}
+}
+
+func TestEngine_EvaluateValue(t *testing.T) {
+	c := collector.Collector()
+	c.SetHistoryRetention(time.Hour)
+	defer c.SetHistoryRetention(0)
+
+	e := New([]Rule{
+		{Name: "errors-increase", Condition: ConditionIncrease, Metric: "ValueErrors", Window: Duration(time.Minute), Op: ">", Threshold: 5},
+		{Name: "errors-rate", Condition: ConditionRate, Metric: "ValueErrors", Window: Duration(time.Minute), Op: ">", Threshold: 1000, Per: Duration(time.Minute)},
+		{Name: "low-memory", Condition: ConditionThreshold, Metric: "ValueFreeMemory", Op: "<", Threshold: 100},
+		{Name: "memory-deriv", Condition: ConditionDeriv, Metric: "ValueFreeMemory", Window: Duration(time.Minute), Op: "<", Threshold: 0},
+	}, nil, zap.NewNop().Sugar())
+
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "ValueErrors", MType: "counter", Delta: collector.PtrInt64(1)}, "1"))
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "ValueFreeMemory", MType: "gauge", Value: collector.PtrFloat64(500)}, "500"))
+	e.Evaluate(context.Background(), time.Now())
+	assert.Empty(t, e.Alerts())
+
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "ValueErrors", MType: "counter", Delta: collector.PtrInt64(9)}, "9"))
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "ValueFreeMemory", MType: "gauge", Value: collector.PtrFloat64(50)}, "50"))
+	e.Evaluate(context.Background(), time.Now())
+
+	fired := make(map[string]float64)
+	for _, alert := range e.Alerts() {
+		fired[alert.Rule] = alert.Value
+	}
+	assert.Len(t, fired, 4)
+	assert.Equal(t, 9.0, fired["errors-increase"])
+	assert.Equal(t, 50.0, fired["low-memory"])
+	assert.Less(t, fired["memory-deriv"], 0.0)
+}
+
+func TestHistoryRetention(t *testing.T) {
+	assert.Zero(t, HistoryRetention([]Rule{{Condition: ConditionAbsent, Window: Duration(time.Hour)}}))
+	assert.Equal(t, 5*time.Minute, HistoryRetention([]Rule{
+		{Condition: ConditionRate, Window: Duration(2 * time.Minute)},
+		{Condition: ConditionDeriv, Window: Duration(5 * time.Minute)},
+		{Condition: ConditionThreshold},
+	}))
+}
@@ -0,0 +1,145 @@
+package alerting
+
+import (
+	"sort"
+	"time"
+)
+
+// dispatchInterval - период проверки таймеров групп уведомлений.
+const dispatchInterval = time.Second
+
+// deliveryQueueSize - число уведомлений, ожидающих доставки, сверх которого новые уведомления отбрасываются.
+const deliveryQueueSize = 256
+
+// group - группа оповещений одного маршрута.
+type group struct {
+	labels   map[string]string   // метки группировки
+	firing   map[string]Alert    // сработавшие оповещения по отпечатку
+	resolved map[string]Alert    // оповещения, разрешенные после последнего уведомления
+	notified map[string]struct{} // сработавшие оповещения, о которых уже отправлено уведомление
+	next     time.Time           // время следующей проверки группы
+	lastSent time.Time           // время последнего уведомления
+}
+
+// dispatcher группирует оповещения по маршруту и решает, когда и о каких оповещениях уведомлять.
+type dispatcher struct {
+	route  Route
+	groups map[string]*group
+}
+
+// newDispatcher создает dispatcher для маршрута route.
+func newDispatcher(route Route) *dispatcher {
+	return &dispatcher{route: route, groups: make(map[string]*group)}
+}
+
+// group возвращает группу оповещения, создавая ее при необходимости.
+func (d *dispatcher) group(alert Alert) *group {
+	key, labels := d.route.groupKey(alert)
+	g, ok := d.groups[key]
+	if !ok {
+		g = &group{
+			labels:   labels,
+			firing:   make(map[string]Alert),
+			resolved: make(map[string]Alert),
+			notified: make(map[string]struct{}),
+		}
+		d.groups[key] = g
+	}
+	return g
+}
+
+// update передает dispatcher текущие сработавшие оповещения, о которых нужно уведомлять, и оповещения,
+// разрешенные на момент now. Сработавшие оповещения, которых больше нет в firing (например, попавшие
+// под заглушку), удаляются из групп без уведомления.
+func (d *dispatcher) update(firing, resolved []Alert, now time.Time) {
+	current := make(map[string]struct{}, len(firing))
+	for _, alert := range firing {
+		key := alert.Fingerprint()
+		current[key] = struct{}{}
+		g := d.group(alert)
+		g.firing[key] = alert
+		if g.next.IsZero() {
+			g.next = now.Add(time.Duration(d.route.GroupWait))
+		}
+	}
+	for _, alert := range resolved {
+		key := alert.Fingerprint()
+		g := d.group(alert)
+		delete(g.firing, key)
+		if _, ok := g.notified[key]; ok {
+			delete(g.notified, key)
+			g.resolved[key] = alert
+			if g.next.IsZero() {
+				g.next = now
+			}
+		}
+	}
+	for key, g := range d.groups {
+		for fp := range g.firing {
+			if _, ok := current[fp]; !ok {
+				delete(g.firing, fp)
+				delete(g.notified, fp)
+			}
+		}
+		if len(g.firing) == 0 && len(g.resolved) == 0 {
+			delete(d.groups, key)
+		}
+	}
+}
+
+// restore отмечает оповещения, восстановленные после перезапуска, как уже отправленные.
+func (d *dispatcher) restore(alerts []Alert, now time.Time) {
+	for _, alert := range alerts {
+		key := alert.Fingerprint()
+		g := d.group(alert)
+		g.firing[key] = alert
+		g.notified[key] = struct{}{}
+		g.lastSent = now
+		g.next = now.Add(time.Duration(d.route.GroupInterval))
+	}
+}
+
+// flush возвращает уведомления групп, таймеры которых истекли к моменту now. Уведомление содержит
+// новые сработавшие и разрешенные оповещения группы, а при повторе - все сработавшие оповещения.
+// Оповещения, о которых уже уведомляли, повторно отправляются только по RepeatInterval.
+func (d *dispatcher) flush(now time.Time) [][]Alert {
+	keys := make([]string, 0, len(d.groups))
+	for key := range d.groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var notifications [][]Alert
+	for _, key := range keys {
+		g := d.groups[key]
+		if g.next.IsZero() || now.Before(g.next) {
+			continue
+		}
+		repeat := d.route.RepeatInterval > 0 && !g.lastSent.IsZero() &&
+			now.Sub(g.lastSent) >= time.Duration(d.route.RepeatInterval)
+		var alerts []Alert
+		for fp, alert := range g.firing {
+			if _, ok := g.notified[fp]; !ok || repeat {
+				alerts = append(alerts, alert)
+			}
+			g.notified[fp] = struct{}{}
+		}
+		for _, alert := range g.resolved {
+			alerts = append(alerts, alert)
+		}
+		g.resolved = make(map[string]Alert)
+		if len(alerts) != 0 {
+			sort.Slice(alerts, func(i, j int) bool { return alerts[i].Fingerprint() < alerts[j].Fingerprint() })
+			notifications = append(notifications, alerts)
+			g.lastSent = now
+		}
+		if len(g.firing) == 0 {
+			delete(d.groups, key)
+			continue
+		}
+		if d.route.GroupInterval > 0 {
+			g.next = now.Add(time.Duration(d.route.GroupInterval))
+		}
+	}
+	return notifications
+}
@@ -0,0 +1,88 @@
+package alerting
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// firingAlert возвращает сработавшее оповещение правила rule для агента agent.
+func firingAlert(rule, agent string) Alert {
+	return Alert{Rule: rule, Labels: map[string]string{"agent": agent}, State: StateFiring}
+}
+
+// rulesOf возвращает правила оповещений уведомления.
+func rulesOf(alerts []Alert) []string {
+	rules := make([]string, 0, len(alerts))
+	for _, alert := range alerts {
+		rules = append(rules, alert.Rule+"/"+alert.State)
+	}
+	return rules
+}
+
+func TestRoute_GroupKey(t *testing.T) {
+	alert := Alert{Rule: "cpu", Severity: "critical", Labels: map[string]string{"agent": "10.0.0.1", "metric": "CPU"}}
+	testCases := []struct {
+		name        string
+		route       Route
+		expectedKey string
+	}{
+		{name: "single group", route: Route{}, expectedKey: ""},
+		{name: "by agent and rule", route: Route{GroupBy: []string{"rule", "agent"}}, expectedKey: "agent=10.0.0.1,rule=cpu"},
+		{name: "by severity", route: Route{GroupBy: []string{"severity"}}, expectedKey: "severity=critical"},
+		{name: "by all labels", route: Route{GroupBy: []string{GroupByAll}}, expectedKey: alert.Fingerprint()},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			key, _ := tt.route.groupKey(alert)
+			assert.Equal(t, tt.expectedKey, key)
+		})
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	d := newDispatcher(Route{
+		GroupBy:        []string{"agent"},
+		GroupWait:      Duration(30 * time.Second),
+		GroupInterval:  Duration(5 * time.Minute),
+		RepeatInterval: Duration(time.Hour),
+	})
+	start := time.Now()
+	cpu, memory, disk := firingAlert("cpu", "a"), firingAlert("memory", "a"), firingAlert("disk", "b")
+
+	// оповещения одного агента ждут group_wait и отправляются одним уведомлением
+	d.update([]Alert{cpu}, nil, start)
+	assert.Empty(t, d.flush(start))
+	d.update([]Alert{cpu, memory, disk}, nil, start.Add(10*time.Second))
+	notifications := d.flush(start.Add(30 * time.Second))
+	require.Len(t, notifications, 1)
+	assert.Equal(t, []string{"cpu/firing", "memory/firing"}, rulesOf(notifications[0]))
+	notifications = d.flush(start.Add(40 * time.Second))
+	require.Len(t, notifications, 1)
+	assert.Equal(t, []string{"disk/firing"}, rulesOf(notifications[0]))
+
+	// повторные вычисления тех же оповещений не приводят к уведомлениям
+	d.update([]Alert{cpu, memory, disk}, nil, start.Add(time.Minute))
+	assert.Empty(t, d.flush(start.Add(6*time.Minute)))
+
+	// разрешение отправляется не раньше group_interval после предыдущего уведомления
+	resolved := memory
+	resolved.State = StateResolved
+	d.update([]Alert{cpu, disk}, []Alert{resolved}, start.Add(7*time.Minute))
+	assert.Empty(t, d.flush(start.Add(8*time.Minute)))
+	notifications = d.flush(start.Add(11 * time.Minute))
+	require.Len(t, notifications, 1)
+	assert.Equal(t, []string{"memory/resolved"}, rulesOf(notifications[0]))
+
+	// по repeat_interval повторяются все сработавшие оповещения группы
+	notifications = d.flush(start.Add(72 * time.Minute))
+	require.Len(t, notifications, 2)
+	assert.Equal(t, []string{"cpu/firing"}, rulesOf(notifications[0]))
+	assert.Equal(t, []string{"disk/firing"}, rulesOf(notifications[1]))
+
+	// оповещение, пропавшее из сработавших без разрешения (под заглушкой), удаляется без уведомления
+	d.update([]Alert{disk}, nil, start.Add(80*time.Minute))
+	assert.Empty(t, d.flush(start.Add(90*time.Minute)))
+	assert.Len(t, d.groups, 1)
+}
@@ -0,0 +1,268 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	htmltemplate "html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// smtpTimeout - время ожидания подключения и обмена с SMTP сервером.
+const smtpTimeout = 30 * time.Second
+
+// Имена шаблонов письма. Шаблон html необязателен: без него письмо отправляется только текстом.
+const (
+	subjectTemplate = "subject"
+	textTemplate    = "text"
+	htmlTemplate    = "html"
+)
+
+// defaultEmailTemplates - шаблоны письма по умолчанию.
+const defaultEmailTemplates = `{{define "subject"}}[alerts] {{len .Firing}} firing, {{len .Resolved}} resolved{{end}}
+{{define "text"}}{{range .Alerts}}[{{.Alert.State}}] {{.Alert.Rule}}{{with .Alert.Severity}} ({{.}}){{end}}: {{.Alert.Summary}}
+{{range $k, $v := .Alert.Labels}}  {{$k}}={{$v}}
+{{end}}{{end}}{{end}}`
+
+// EmailConfig - настройки отправки уведомлений по электронной почте.
+type EmailConfig struct {
+	Addr      string      // адрес SMTP сервера host:port
+	Username  string      // имя пользователя для аутентификации PLAIN; пусто - без аутентификации
+	Password  string      // пароль для аутентификации
+	StartTLS  bool        // требовать шифрование STARTTLS
+	TLSConfig *tls.Config // настройки TLS для STARTTLS, по умолчанию проверяется сертификат сервера
+	From      string      // адрес отправителя
+	To        []string    // адреса получателей
+	Templates string      // путь к файлу шаблонов subject, text и html; пусто - шаблоны по умолчанию
+}
+
+// EmailAlert - данные одного оповещения в шаблонах письма.
+type EmailAlert struct {
+	Alert  Alert                   // оповещение
+	Rule   Rule                    // правило оповещения
+	Metric *collector.StoredMetric // текущее значение метрики оповещения, nil если метрики нет
+}
+
+// EmailData - данные шаблонов письма: оповещения уведомления, в том числе отдельно сработавшие и разрешенные.
+type EmailData struct {
+	Alerts   []EmailAlert
+	Firing   []EmailAlert
+	Resolved []EmailAlert
+}
+
+// Email отправляет уведомления письмами через SMTP с темой и телом по шаблонам text/template и html/template.
+type Email struct {
+	cfg  EmailConfig
+	text *texttemplate.Template
+	html *htmltemplate.Template // nil, если шаблон html не задан
+}
+
+// NewEmail создает Email и разбирает шаблоны письма. Шаблоны subject и text обрабатываются text/template,
+// шаблон html - html/template с экранированием значений.
+// Пустые адреса получателей пропускаются.
+func NewEmail(cfg EmailConfig) (*Email, error) {
+	to := make([]string, 0, len(cfg.To))
+	for _, addr := range cfg.To {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	cfg.To = to
+	if cfg.Addr == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, errors.New("email notifier needs SMTP address, sender and recipients")
+	}
+	source := defaultEmailTemplates
+	if cfg.Templates != "" {
+		data, err := os.ReadFile(cfg.Templates)
+		if err != nil {
+			return nil, fmt.Errorf("error while reading email templates: %w", err)
+		}
+		source = string(data)
+	}
+	text, err := texttemplate.New("email").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing email templates: %w", err)
+	}
+	for _, name := range []string{subjectTemplate, textTemplate} {
+		if text.Lookup(name) == nil {
+			return nil, fmt.Errorf("email templates must define %q", name)
+		}
+	}
+	e := &Email{cfg: cfg, text: text}
+	if text.Lookup(htmlTemplate) != nil {
+		if e.html, err = htmltemplate.New("email").Parse(source); err != nil {
+			return nil, fmt.Errorf("error while parsing email templates: %w", err)
+		}
+	}
+	return e, nil
+}
+
+// Notify отправляет одно письмо со всеми оповещениями уведомления.
+func (e *Email) Notify(ctx context.Context, alerts []Alert) error {
+	message, err := e.message(alerts, time.Now())
+	if err != nil {
+		return err
+	}
+	return e.send(ctx, message)
+}
+
+// emailData возвращает данные шаблонов для оповещений.
+func emailData(alerts []Alert) EmailData {
+	var data EmailData
+	c := collector.Collector()
+	for _, alert := range alerts {
+		item := EmailAlert{Alert: alert, Rule: alert.rule}
+		if name, ok := alert.Labels["metric"]; ok {
+			if m, err := c.GetMetric(name); err == nil {
+				item.Metric = &m
+			}
+		}
+		data.Alerts = append(data.Alerts, item)
+		if alert.State == StateResolved {
+			data.Resolved = append(data.Resolved, item)
+		} else {
+			data.Firing = append(data.Firing, item)
+		}
+	}
+	return data
+}
+
+// message формирует письмо с заголовками и телом по шаблонам.
+func (e *Email) message(alerts []Alert, now time.Time) ([]byte, error) {
+	data := emailData(alerts)
+	var subject, text, html bytes.Buffer
+	if err := e.text.ExecuteTemplate(&subject, subjectTemplate, data); err != nil {
+		return nil, fmt.Errorf("error while executing email subject template: %w", err)
+	}
+	if err := e.text.ExecuteTemplate(&text, textTemplate, data); err != nil {
+		return nil, fmt.Errorf("error while executing email text template: %w", err)
+	}
+	if e.html != nil {
+		if err := e.html.ExecuteTemplate(&html, htmlTemplate, data); err != nil {
+			return nil, fmt.Errorf("error while executing email html template: %w", err)
+		}
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	if e.html == nil {
+		msg.WriteString("Content-Type: text/plain; charset=utf-8\r\nContent-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&msg, text.Bytes()); err != nil {
+			return nil, err
+		}
+		return msg.Bytes(), nil
+	}
+	parts := multipart.NewWriter(&msg)
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	for _, part := range []struct {
+		contentType string
+		body        []byte
+	}{
+		{contentType: "text/plain; charset=utf-8", body: text.Bytes()},
+		{contentType: "text/html; charset=utf-8", body: html.Bytes()},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return msg.Bytes(), nil
+}
+
+// writeQuotedPrintable записывает тело части письма в кодировке quoted-printable.
+func writeQuotedPrintable(w io.Writer, body []byte) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write(body); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// send отправляет письмо через SMTP сервер с STARTTLS и аутентификацией, если они заданы.
+func (e *Email) send(ctx context.Context, message []byte) error {
+	host, _, err := net.SplitHostPort(e.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid SMTP address %q: %w", e.cfg.Addr, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", e.cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("error while connecting to SMTP server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err = conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("error while connecting to SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if e.cfg.StartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP server does not support STARTTLS")
+		}
+		tlsConfig := e.cfg.TLSConfig
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{ServerName: host}
+		}
+		if err = client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("error while starting TLS: %w", err)
+		}
+	}
+	if e.cfg.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", e.cfg.Username, e.cfg.Password, host)); err != nil {
+			return fmt.Errorf("error while authenticating to SMTP server: %w", err)
+		}
+	}
+	if err = client.Mail(e.cfg.From); err != nil {
+		return fmt.Errorf("error while sending email: %w", err)
+	}
+	for _, to := range e.cfg.To {
+		if err = client.Rcpt(to); err != nil {
+			return fmt.Errorf("error while sending email to %s: %w", to, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("error while sending email: %w", err)
+	}
+	if _, err = w.Write(message); err != nil {
+		return fmt.Errorf("error while sending email: %w", err)
+	}
+	if err = w.Close(); err != nil {
+		return fmt.Errorf("error while sending email: %w", err)
+	}
+	return client.Quit()
+}
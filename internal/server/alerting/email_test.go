@@ -0,0 +1,262 @@
+package alerting
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSMTP - SMTP сервер для тестов, который принимает письма и запоминает их.
+type fakeSMTP struct {
+	listener net.Listener
+	tls      *tls.Config // если задан, сервер поддерживает STARTTLS
+	auth     string      // ожидаемые данные AUTH PLAIN в base64; пусто - без аутентификации
+	messages chan fakeMessage
+}
+
+// fakeMessage - письмо, принятое fakeSMTP.
+type fakeMessage struct {
+	from   string
+	to     []string
+	data   string
+	tls    bool
+	authed bool
+}
+
+// newFakeSMTP запускает fakeSMTP на локальном адресе.
+func newFakeSMTP(t *testing.T, tlsConfig *tls.Config, username, password string) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeSMTP{listener: listener, tls: tlsConfig, messages: make(chan fakeMessage, 10)}
+	if username != "" {
+		s.auth = base64.StdEncoding.EncodeToString([]byte("\x00" + username + "\x00" + password))
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// serve обрабатывает одно SMTP соединение.
+func (s *fakeSMTP) serve(conn net.Conn) {
+	defer conn.Close()
+	r, w := bufio.NewReader(conn), conn
+	reply := func(line string) { io.WriteString(w, line+"\r\n") }
+	var msg fakeMessage
+	reply("220 fake ESMTP")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimSpace(line)
+		switch upper := strings.ToUpper(cmd); {
+		case strings.HasPrefix(upper, "EHLO"):
+			reply("250-fake")
+			if s.tls != nil && !msg.tls {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case upper == "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+			conn, r, w = tlsConn, bufio.NewReader(tlsConn), tlsConn
+			msg.tls = true
+		case strings.HasPrefix(upper, "AUTH PLAIN"):
+			if s.auth == "" || strings.TrimSpace(cmd[len("AUTH PLAIN"):]) != s.auth {
+				reply("535 authentication failed")
+				continue
+			}
+			msg.authed = true
+			reply("235 ok")
+		case strings.HasPrefix(upper, "MAIL FROM:"):
+			if s.auth != "" && !msg.authed {
+				reply("530 authentication required")
+				continue
+			}
+			msg.from = strings.Trim(cmd[len("MAIL FROM:"):], "<>")
+			reply("250 ok")
+		case strings.HasPrefix(upper, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(cmd[len("RCPT TO:"):], "<>"))
+			reply("250 ok")
+		case upper == "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(line, "."))
+			}
+			msg.data = data.String()
+			s.messages <- msg
+			reply("250 accepted")
+		case upper == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("250 ok")
+		}
+	}
+}
+
+// receive возвращает следующее принятое письмо.
+func (s *fakeSMTP) receive(t *testing.T) fakeMessage {
+	select {
+	case msg := <-s.messages:
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatal("email was not received")
+		return fakeMessage{}
+	}
+}
+
+// decodePart возвращает тело части письма в кодировке quoted-printable.
+func decodePart(t *testing.T, r io.Reader) string {
+	body, err := io.ReadAll(quotedprintable.NewReader(r))
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestEmail_Notify(t *testing.T) {
+	c := collector.Collector()
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "EmailGauge", MType: "gauge", Value: collector.PtrFloat64(97.5)}, "97.5"))
+	rule := Rule{Name: "cpu-high", Condition: ConditionThreshold, Metric: "EmailGauge", Op: ">", Threshold: 90, Severity: "critical"}
+	alerts := []Alert{
+		{Rule: "cpu-high", Labels: map[string]string{"metric": "EmailGauge"}, Severity: "critical", State: StateFiring, Value: 97.5, Summary: "<cpu> is high", rule: rule},
+		{Rule: "disk", Labels: map[string]string{"agent": "10.0.0.1"}, State: StateResolved, Summary: "disk is fine"},
+	}
+	templates := filepath.Join(t.TempDir(), "email.tmpl")
+	require.NoError(t, os.WriteFile(templates, []byte(`{{define "subject"}}Тревога: {{len .Firing}} firing{{end}}
+{{define "text"}}{{range .Firing}}{{.Alert.Rule}} {{.Metric.TextValue}} {{.Rule.Op}} {{.Rule.Threshold}}{{end}}{{end}}
+{{define "html"}}{{range .Alerts}}<p>{{.Alert.Summary}}</p>{{end}}{{end}}`), 0666))
+
+	server := httptest.NewTLSServer(nil)
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+
+	testCases := []struct {
+		name      string
+		starttls  bool
+		username  string
+		templates string
+		check     func(t *testing.T, msg fakeMessage)
+	}{
+		{
+			name: "default templates without auth",
+			check: func(t *testing.T, msg fakeMessage) {
+				m, err := mail.ReadMessage(strings.NewReader(msg.data))
+				require.NoError(t, err)
+				assert.Equal(t, "[alerts] 1 firing, 1 resolved", m.Header.Get("Subject"))
+				assert.Equal(t, "text/plain; charset=utf-8", m.Header.Get("Content-Type"))
+				body := decodePart(t, m.Body)
+				assert.Contains(t, body, "[firing] cpu-high (critical): <cpu> is high\r\n  metric=EmailGauge\r\n")
+				assert.Contains(t, body, "[resolved] disk: disk is fine\r\n  agent=10.0.0.1\r\n")
+			},
+		},
+		{
+			name:      "starttls, auth and custom templates",
+			starttls:  true,
+			username:  "alerts",
+			templates: templates,
+			check: func(t *testing.T, msg fakeMessage) {
+				assert.True(t, msg.tls)
+				assert.True(t, msg.authed)
+				m, err := mail.ReadMessage(strings.NewReader(msg.data))
+				require.NoError(t, err)
+				subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+				require.NoError(t, err)
+				assert.Equal(t, "Тревога: 1 firing", subject)
+				mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+				require.NoError(t, err)
+				assert.Equal(t, "multipart/alternative", mediaType)
+				parts := multipart.NewReader(m.Body, params["boundary"])
+				text, err := parts.NextPart()
+				require.NoError(t, err)
+				assert.Equal(t, "cpu-high 97.5 > 90", decodePart(t, text))
+				html, err := parts.NextPart()
+				require.NoError(t, err)
+				assert.Equal(t, "<p>&lt;cpu&gt; is high</p><p>disk is fine</p>", decodePart(t, html))
+			},
+		},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var tlsConfig *tls.Config
+			if tt.starttls {
+				tlsConfig = server.TLS
+			}
+			smtpServer := newFakeSMTP(t, tlsConfig, tt.username, "secret")
+			email, err := NewEmail(EmailConfig{
+				Addr:      smtpServer.listener.Addr().String(),
+				Username:  tt.username,
+				Password:  "secret",
+				StartTLS:  tt.starttls,
+				TLSConfig: &tls.Config{RootCAs: roots, ServerName: "127.0.0.1"},
+				From:      "alerts@example.com",
+				To:        []string{"oncall@example.com", "team@example.com"},
+				Templates: tt.templates,
+			})
+			require.NoError(t, err)
+			require.NoError(t, email.Notify(context.Background(), alerts))
+			msg := smtpServer.receive(t)
+			assert.Equal(t, "alerts@example.com", msg.from)
+			assert.Equal(t, []string{"oncall@example.com", "team@example.com"}, msg.to)
+			tt.check(t, msg)
+		})
+	}
+}
+
+func TestEmail_Errors(t *testing.T) {
+	_, err := NewEmail(EmailConfig{Addr: "127.0.0.1:25", From: "alerts@example.com", To: []string{""}})
+	assert.Error(t, err)
+
+	templates := filepath.Join(t.TempDir(), "email.tmpl")
+	require.NoError(t, os.WriteFile(templates, []byte(`{{define "subject"}}alerts{{end}}`), 0666))
+	_, err = NewEmail(EmailConfig{Addr: "127.0.0.1:25", From: "alerts@example.com", To: []string{"oncall@example.com"}, Templates: templates})
+	assert.ErrorContains(t, err, `"text"`)
+
+	// сервер без STARTTLS и с неверным паролем
+	smtpServer := newFakeSMTP(t, nil, "alerts", "secret")
+	for _, cfg := range []EmailConfig{
+		{StartTLS: true},
+		{Username: "alerts", Password: "wrong"},
+	} {
+		cfg.Addr, cfg.From, cfg.To = smtpServer.listener.Addr().String(), "alerts@example.com", []string{"oncall@example.com"}
+		email, err := NewEmail(cfg)
+		require.NoError(t, err)
+		assert.Error(t, email.Notify(context.Background(), []Alert{{Rule: "r", State: StateFiring}}))
+	}
+}
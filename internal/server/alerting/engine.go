@@ -0,0 +1,433 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Состояния оповещения.
+const (
+	StateFiring   = "firing"   // условие правила выполняется
+	StateResolved = "resolved" // условие правила перестало выполняться
+)
+
+// Alert - оповещение, созданное правилом для одного экземпляра (метрики или агента).
+type Alert struct {
+	ID         string            `json:"id"`                    // идентификатор для подтверждения, производный от Fingerprint
+	Rule       string            `json:"rule"`                  // имя правила
+	Labels     map[string]string `json:"labels"`                // метки правила и экземпляра
+	Severity   string            `json:"severity,omitempty"`    // важность оповещения
+	State      string            `json:"state"`                 // состояние оповещения
+	Value      float64           `json:"value"`                 // значение, на котором сработало условие
+	Summary    string            `json:"summary"`               // описание оповещения
+	ActiveAt   time.Time         `json:"active_at"`             // время срабатывания
+	ResolvedAt *time.Time        `json:"resolved_at,omitempty"` // время разрешения
+	SilencedBy []string          `json:"silenced_by,omitempty"` // действующие заглушки, подавляющие уведомления
+	AckedAt    *time.Time        `json:"acked_at,omitempty"`    // время подтверждения
+	AckedBy    string            `json:"acked_by,omitempty"`    // кто подтвердил оповещение
+	Escalated  []string          `json:"escalated,omitempty"`   // получатели, которым оповещение уже эскалировано
+
+	rule Rule // правило оповещения для шаблонов уведомлений
+}
+
+// Fingerprint возвращает ключ оповещения, уникальный для правила и набора меток.
+func (a Alert) Fingerprint() string {
+	keys := make([]string, 0, len(a.Labels))
+	for k := range a.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(a.Rule)
+	for _, k := range keys {
+		b.WriteString("," + k + "=" + a.Labels[k])
+	}
+	return b.String()
+}
+
+// Notifier отправляет уведомления об изменении состояния оповещений.
+type Notifier interface {
+	Notify(ctx context.Context, alerts []Alert) error
+}
+
+// result - результат вычисления правила для одного экземпляра.
+type result struct {
+	labels  map[string]string
+	value   float64
+	firing  bool
+	summary string
+}
+
+// Engine периодически вычисляет правила оповещений и отправляет уведомления при смене состояния.
+type Engine struct {
+	mu        sync.Mutex
+	rules     []Rule
+	alerts    map[string]*Alert
+	receivers map[string]Notifier // получатели уведомлений по имени; получатель по умолчанию - с пустым именем
+	logger    *zap.SugaredLogger
+	started   time.Time
+	models    map[string]*anomalyModel // модели правил anomaly по имени правила
+	silences  map[string]*Silence      // заглушки по идентификатору
+	routeTree *routeNode               // корень дерева маршрутов
+	routes    []*routeNode             // все узлы дерева маршрутов с группами уведомлений
+	history   HistoryStore             // хранилище истории переходов, nil если история не ведется
+	slos      []SLO                    // SLO из файла правил
+	sloSeries map[string]*sloSeries    // история счетчиков SLO по имени SLO
+	ruleFile  string                   // файл правил для ReloadRules
+	smtp      EmailConfig              // SMTP настройки получателей писем из файла правил
+	queue     chan notification        // очередь доставки уведомлений во время Run, nil - доставка синхронная
+}
+
+// New создает Engine с проверенными правилами. Notifier - получатель по умолчанию; если он равен nil,
+// изменения состояния, не направленные маршрутами другим получателям, только логируются.
+// По умолчанию все оповещения отправляются одной группой без ожидания и повтора; см. SetRoute.
+func New(rules []Rule, notifier Notifier, logger *zap.SugaredLogger) *Engine {
+	e := &Engine{
+		rules:     rules,
+		alerts:    make(map[string]*Alert),
+		receivers: make(map[string]Notifier),
+		logger:    logger,
+		started:   time.Now(),
+		models:    make(map[string]*anomalyModel),
+		silences:  make(map[string]*Silence),
+		sloSeries: make(map[string]*sloSeries),
+	}
+	if notifier != nil {
+		e.receivers[""] = notifier
+	}
+	e.routeTree, e.routes = newRouteTree(Route{})
+	return e
+}
+
+// SetRoute задает дерево маршрутов уведомлений. Вызывается до Run и Restore.
+func (e *Engine) SetRoute(route Route) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.routeTree, e.routes = newRouteTree(route)
+}
+
+// SetReceivers добавляет именованных получателей уведомлений, на которых ссылаются маршруты. Вызывается до Run.
+func (e *Engine) SetReceivers(receivers map[string]Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for name, notifier := range receivers {
+		e.receivers[name] = notifier
+	}
+}
+
+// Run вычисляет правила с интервалом interval и проверяет таймеры групп уведомлений до отмены контекста.
+// Уведомления доставляются отдельной горутиной через очередь размером deliveryQueueSize,
+// чтобы медленный получатель не задерживал вычисление правил; при переполнении очереди уведомление отбрасывается.
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	queue := make(chan notification, deliveryQueueSize)
+	e.mu.Lock()
+	e.queue = queue
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.queue = nil
+		e.mu.Unlock()
+	}()
+	go e.deliver(ctx, queue)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	dispatchTicker := time.NewTicker(dispatchInterval)
+	defer dispatchTicker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			e.Evaluate(ctx, now)
+		case now := <-dispatchTicker.C:
+			e.Dispatch(ctx, now)
+		}
+	}
+}
+
+// Evaluate вычисляет все правила на момент now и передает сработавшие и разрешенные оповещения
+// в группы уведомлений подходящих маршрутов, после чего отправляет уведомления групп, таймеры которых
+// истекли, и эскалирует неподтвержденные оповещения.
+// Оповещения под действующей заглушкой не попадают в группы; если заглушка истекает раньше,
+// чем оповещение разрешается, уведомление о срабатывании отправляется после ее окончания.
+func (e *Engine) Evaluate(ctx context.Context, now time.Time) {
+	start := time.Now()
+	e.mu.Lock()
+	e.pruneSilences(now)
+	e.observeSLOs(now)
+	var changed []Alert
+	seen := make(map[string]struct{})
+	for _, rule := range e.rules {
+		for _, res := range e.evaluateRule(rule, now) {
+			alert := Alert{
+				Rule:     rule.Name,
+				Labels:   res.labels,
+				Severity: rule.Severity,
+				State:    StateFiring,
+				Value:    res.value,
+				Summary:  res.summary,
+				ActiveAt: now,
+				rule:     rule,
+			}
+			key := alert.Fingerprint()
+			alert.ID = alertID(key)
+			seen[key] = struct{}{}
+			active, ok := e.alerts[key]
+			switch {
+			case res.firing && ok:
+				active.Value = res.value
+				active.Summary = res.summary
+				active.rule = rule
+				active.SilencedBy = e.silencedBy(*active, now)
+			case res.firing:
+				alert.SilencedBy = e.silencedBy(alert, now)
+				e.alerts[key] = &alert
+				changed = append(changed, alert)
+			case ok:
+				active.Value = res.value
+				active.Summary = res.summary
+				active.rule = rule
+				changed = append(changed, e.resolve(key, active, now))
+			}
+		}
+	}
+	// оповещения правил и экземпляров, которых больше нет, разрешаются
+	for key, active := range e.alerts {
+		if _, ok := seen[key]; !ok {
+			changed = append(changed, e.resolve(key, active, now))
+		}
+	}
+	var firing, resolved []Alert
+	for _, alert := range e.alerts {
+		if len(alert.SilencedBy) == 0 {
+			firing = append(firing, *alert)
+		}
+	}
+	for _, alert := range changed {
+		if alert.State == StateResolved && len(alert.SilencedBy) == 0 {
+			resolved = append(resolved, alert)
+		}
+	}
+	notifications := e.withNotifiers(append(e.routeAlerts(firing, resolved, now), e.escalate(now)...))
+	e.mu.Unlock()
+	selfmetrics.ObserveAlertEvaluation(time.Since(start))
+
+	for _, alert := range changed {
+		e.logger.Infow("alert "+alert.State, "rule", alert.Rule, "labels", alert.Labels, "value", alert.Value,
+			"summary", alert.Summary, "silenced_by", alert.SilencedBy)
+	}
+	e.recordHistory(ctx, changed)
+	e.notify(ctx, notifications)
+}
+
+// Dispatch отправляет уведомления групп, таймеры которых истекли к моменту now, и эскалирует
+// неподтвержденные оповещения, срок эскалации которых наступил.
+func (e *Engine) Dispatch(ctx context.Context, now time.Time) {
+	e.mu.Lock()
+	notifications := e.withNotifiers(append(e.flushRoutes(now), e.escalate(now)...))
+	e.mu.Unlock()
+	e.notify(ctx, notifications)
+}
+
+// notify передает уведомления в очередь доставки или, вне Run, отправляет их сразу.
+func (e *Engine) notify(ctx context.Context, notifications []notification) {
+	e.mu.Lock()
+	queue := e.queue
+	e.mu.Unlock()
+	for _, n := range notifications {
+		if queue == nil {
+			e.send(ctx, n)
+			continue
+		}
+		select {
+		case queue <- n:
+		default:
+			e.logger.Errorw("notification queue is full, notification dropped", "event", "send alert notification", "receiver", n.receiver)
+		}
+	}
+}
+
+// deliver отправляет уведомления из очереди до отмены контекста.
+func (e *Engine) deliver(ctx context.Context, queue <-chan notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case n := <-queue:
+			e.send(ctx, n)
+		}
+	}
+}
+
+// send отправляет одно уведомление получателю.
+// Уведомления получателю по умолчанию, если он не задан, пропускаются.
+func (e *Engine) send(ctx context.Context, n notification) {
+	if n.notifier == nil {
+		if n.receiver != "" {
+			e.logger.Errorw("unknown receiver "+n.receiver, "event", "send alert notification")
+		}
+		return
+	}
+	if err := n.notifier.Notify(ctx, n.alerts); err != nil {
+		e.logger.Errorw(err.Error(), "event", "send alert notification", "receiver", n.receiver)
+	}
+}
+
+// withNotifiers определяет получателя каждого уведомления, вызывается под mu.
+func (e *Engine) withNotifiers(notifications []notification) []notification {
+	for i := range notifications {
+		notifications[i].notifier = e.receivers[notifications[i].receiver]
+	}
+	return notifications
+}
+
+// resolve отмечает оповещение разрешенным и удаляет его из активных, вызывается под mu.
+func (e *Engine) resolve(key string, active *Alert, now time.Time) Alert {
+	delete(e.alerts, key)
+	resolved := *active
+	resolved.State = StateResolved
+	resolved.ResolvedAt = &now
+	resolved.SilencedBy = e.silencedBy(resolved, now)
+	return resolved
+}
+
+// evaluateRule вычисляет правило для всех его экземпляров, вызывается под mu.
+func (e *Engine) evaluateRule(rule Rule, now time.Time) []result {
+	switch rule.Condition {
+	case ConditionAbsent:
+		return e.evaluateAbsent(rule, now)
+	case ConditionThreshold, ConditionIncrease, ConditionRate, ConditionDeriv:
+		return e.evaluateValue(rule, now)
+	case ConditionAnomaly:
+		return e.evaluateAnomaly(rule, now)
+	case ConditionBurnRate:
+		return e.evaluateBurnRate(rule, now)
+	default:
+		return nil
+	}
+}
+
+// evaluateAbsent проверяет, что метрика или агент обновлялись не раньше, чем Window назад.
+// Метрика или агент, которых сервер еще не видел, отсчитываются от запуска Engine.
+func (e *Engine) evaluateAbsent(rule Rule, now time.Time) []result {
+	c := collector.Collector()
+	check := func(kind, name string, lastSeen time.Time, ok bool) result {
+		if !ok {
+			lastSeen = e.started
+		}
+		silent := now.Sub(lastSeen)
+		return result{
+			labels:  ruleLabels(rule, kind, name),
+			value:   silent.Seconds(),
+			firing:  silent > time.Duration(rule.Window),
+			summary: fmt.Sprintf("%s %q has not been updated for %s", kind, name, silent.Truncate(time.Second)),
+		}
+	}
+	switch {
+	case rule.Metric != "":
+		lastSeen, ok := c.MetricLastSeen(rule.Metric)
+		return []result{check("metric", rule.Metric, lastSeen, ok)}
+	case rule.Agent == AnyAgent:
+		agents := c.Agents()
+		results := make([]result, 0, len(agents))
+		for _, agent := range agents {
+			lastSeen, ok := c.AgentLastSeen(agent)
+			results = append(results, check("agent", agent, lastSeen, ok))
+		}
+		return results
+	default:
+		lastSeen, ok := c.AgentLastSeen(rule.Agent)
+		return []result{check("agent", rule.Agent, lastSeen, ok)}
+	}
+}
+
+// ruleLabels возвращает метки оповещения: метки правила и метку экземпляра kind=name.
+func ruleLabels(rule Rule, kind, name string) map[string]string {
+	labels := make(map[string]string, len(rule.Labels)+1)
+	for k, v := range rule.Labels {
+		labels[k] = v
+	}
+	labels[kind] = name
+	return labels
+}
+
+// Alerts возвращает активные оповещения, отсортированные по правилу и меткам.
+func (e *Engine) Alerts() []Alert {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	alerts := make([]Alert, 0, len(e.alerts))
+	for _, alert := range e.alerts {
+		alerts = append(alerts, *alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Fingerprint() < alerts[j].Fingerprint() })
+	return alerts
+}
+
+// state - состояние Engine, сохраняемое в хранилище.
+type state struct {
+	Alerts []Alert                  `json:"alerts"`
+	Models map[string]*anomalyModel `json:"models,omitempty"`
+	SLOs   map[string]*sloSeries    `json:"slos,omitempty"`
+}
+
+// State возвращает состояние активных оповещений, моделей аномалий и истории счетчиков SLO в формате JSON
+// для сохранения в хранилище.
+func (e *Engine) State() ([]byte, error) {
+	alerts := e.Alerts()
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return json.Marshal(state{Alerts: alerts, Models: e.models, SLOs: e.sloSeries})
+}
+
+// Restore восстанавливает активные оповещения, модели аномалий и историю счетчиков SLO, сохраненные State.
+// Восстановленные оповещения не отправляются повторно; оповещения правил, которых больше нет,
+// разрешатся при следующем вычислении. Модели аномалий восстанавливаются, только если соответствуют
+// текущему правилу с тем же именем.
+func (e *Engine) Restore(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var s state
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("error while parsing alerts state: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	now := time.Now()
+	for _, alert := range s.Alerts {
+		alert := alert
+		key := alert.Fingerprint()
+		if alert.ID == "" {
+			alert.ID = alertID(key)
+		}
+		e.alerts[key] = &alert
+		if len(alert.SilencedBy) == 0 {
+			for _, node := range e.routeTree.match(alert) {
+				node.dispatcher.restore([]Alert{alert}, now)
+			}
+		}
+	}
+	rules := make(map[string]Rule, len(e.rules))
+	for _, rule := range e.rules {
+		rules[rule.Name] = rule
+	}
+	for name, m := range s.Models {
+		// модель, сохраненная для другого определения правила, начинает обучение заново
+		if rule, ok := rules[name]; ok && m != nil && m.fits(rule) {
+			e.models[name] = m
+		}
+	}
+	for name, series := range s.SLOs {
+		e.sloSeries[name] = series
+	}
+	return nil
+}
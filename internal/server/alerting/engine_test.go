@@ -0,0 +1,147 @@
+package alerting
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// recordingNotifier запоминает отправленные уведомления.
+type recordingNotifier struct {
+	sent [][]Alert
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	n.sent = append(n.sent, alerts)
+	return nil
+}
+
+// blockingNotifier считает вызовы и не завершает отправку до закрытия release.
+type blockingNotifier struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (n *blockingNotifier) Notify(ctx context.Context, alerts []Alert) error {
+	n.calls.Add(1)
+	select {
+	case <-n.release:
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+func TestEngine_RunSlowReceiver(t *testing.T) {
+	c := collector.Collector()
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "SlowReceiverGauge", MType: "gauge"}, "20"))
+	notifier := &blockingNotifier{release: make(chan struct{})}
+	e := New([]Rule{{Name: "slow", Condition: ConditionThreshold, Metric: "SlowReceiverGauge", Op: ">", Threshold: 10}}, notifier, zap.NewNop().Sugar())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go e.Run(ctx, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool { return notifier.calls.Load() == 1 }, time.Second, 5*time.Millisecond)
+	// получатель завис на первом уведомлении, но правила продолжают вычисляться
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "SlowReceiverGauge", MType: "gauge"}, "0"))
+	require.Eventually(t, func() bool { return len(e.Alerts()) == 0 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), notifier.calls.Load())
+
+	close(notifier.release)
+	require.Eventually(t, func() bool { return notifier.calls.Load() == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestEngine_EvaluateAbsent(t *testing.T) {
+	c := collector.Collector()
+	now := time.Now()
+	old := now.Add(-2 * time.Minute)
+	c.RestoreLastSeen(collector.LastSeenState{
+		Metrics: map[string]time.Time{"AbsentGauge": old},
+		Agents:  map[string]time.Time{"10.2.0.1": old},
+	})
+
+	notifier := &recordingNotifier{}
+	e := New([]Rule{
+		{Name: "absent-gauge", Condition: ConditionAbsent, Metric: "AbsentGauge", Window: Duration(time.Minute), Severity: "warning"},
+		{Name: "silent-agent", Condition: ConditionAbsent, Agent: "10.2.0.1", Window: Duration(time.Minute), Labels: map[string]string{"team": "infra"}},
+		{Name: "never-seen", Condition: ConditionAbsent, Metric: "NeverSeenGauge", Window: Duration(time.Minute)},
+	}, notifier, zap.NewNop().Sugar())
+	e.started = old
+	ctx := context.Background()
+
+	e.Evaluate(ctx, old.Add(30*time.Second))
+	assert.Empty(t, e.Alerts())
+	assert.Empty(t, notifier.sent)
+
+	e.Evaluate(ctx, now)
+	alerts := e.Alerts()
+	require.Len(t, alerts, 3)
+	assert.Equal(t, "absent-gauge", alerts[0].Rule)
+	assert.Equal(t, map[string]string{"metric": "AbsentGauge"}, alerts[0].Labels)
+	assert.Equal(t, "warning", alerts[0].Severity)
+	assert.Equal(t, StateFiring, alerts[0].State)
+	assert.Equal(t, now, alerts[0].ActiveAt)
+	assert.Equal(t, 120.0, alerts[0].Value)
+	assert.Equal(t, "never-seen", alerts[1].Rule)
+	assert.Equal(t, map[string]string{"agent": "10.2.0.1", "team": "infra"}, alerts[2].Labels)
+	require.Len(t, notifier.sent, 1)
+	assert.Len(t, notifier.sent[0], 3)
+
+	// повторное вычисление не отправляет уведомления о тех же оповещениях
+	e.Evaluate(ctx, now.Add(time.Second))
+	assert.Len(t, notifier.sent, 1)
+
+	// агент снова прислал метрику: оповещения о метрике и об агенте разрешаются
+	require.NoError(t, c.CollectFrom("10.2.0.1", collector.MetricRequest{ID: "AbsentGauge", MType: "gauge", Value: collector.PtrFloat64(3)}, "3"))
+	e.Evaluate(ctx, time.Now())
+	require.Len(t, notifier.sent, 2)
+	require.Len(t, notifier.sent[1], 2)
+	for _, alert := range notifier.sent[1] {
+		assert.Equal(t, StateResolved, alert.State)
+		assert.NotNil(t, alert.ResolvedAt)
+	}
+	alerts = e.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, "never-seen", alerts[0].Rule)
+}
+
+func TestEngine_EvaluateAnyAgent(t *testing.T) {
+	c := collector.Collector()
+	old := time.Now().Add(-time.Hour)
+	c.RestoreLastSeen(collector.LastSeenState{Agents: map[string]time.Time{"10.3.0.1": old}})
+
+	e := New([]Rule{{Name: "silent-agents", Condition: ConditionAbsent, Agent: AnyAgent, Window: Duration(time.Minute)}}, nil, zap.NewNop().Sugar())
+	e.Evaluate(context.Background(), time.Now())
+
+	var silent []string
+	for _, alert := range e.Alerts() {
+		silent = append(silent, alert.Labels["agent"])
+	}
+	assert.Contains(t, silent, "10.3.0.1")
+}
+
+func TestEngine_StateRestore(t *testing.T) {
+	notifier := &recordingNotifier{}
+	rules := []Rule{{Name: "restored", Condition: ConditionAbsent, Agent: "10.4.0.1", Window: Duration(time.Minute)}}
+	e := New(rules, notifier, zap.NewNop().Sugar())
+	e.Evaluate(context.Background(), time.Now().Add(2*time.Minute))
+	require.Len(t, e.Alerts(), 1)
+	data, err := e.State()
+	require.NoError(t, err)
+
+	// после перезапуска сработавшее оповещение восстанавливается без повторного уведомления
+	restarted := New(rules, notifier, zap.NewNop().Sugar())
+	require.NoError(t, restarted.Restore(data))
+	restored, err := restarted.State()
+	require.NoError(t, err)
+	assert.JSONEq(t, string(data), string(restored))
+	restarted.Evaluate(context.Background(), time.Now().Add(2*time.Minute))
+	assert.Len(t, notifier.sent, 1)
+
+	assert.NoError(t, restarted.Restore(nil))
+	assert.Error(t, restarted.Restore([]byte("{")))
+}
@@ -0,0 +1,73 @@
+package alerting
+
+import (
+	"errors"
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ErrAlertNotFound - активного оповещения с таким идентификатором нет.
+var ErrAlertNotFound = errors.New("alert not found")
+
+// alertID возвращает идентификатор оповещения с отпечатком fingerprint.
+func alertID(fingerprint string) string {
+	h := fnv.New64a()
+	h.Write([]byte(fingerprint))
+	return strconv.FormatUint(h.Sum64(), 16)
+}
+
+// Ack подтверждает активное оповещение с идентификатором id на момент now: подтвержденное оповещение
+// больше не эскалируется. Подтверждение действует до разрешения оповещения.
+// Возвращает ErrAlertNotFound, если активного оповещения нет.
+func (e *Engine) Ack(id, by string, now time.Time) (Alert, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, alert := range e.alerts {
+		if alert.ID != id {
+			continue
+		}
+		if alert.AckedAt == nil {
+			alert.AckedAt = &now
+			alert.AckedBy = by
+			e.logger.Infow("alert acknowledged", "rule", alert.Rule, "labels", alert.Labels, "by", by)
+		}
+		return *alert, nil
+	}
+	return Alert{}, ErrAlertNotFound
+}
+
+// escalate возвращает уведомления получателям эскалации об оповещениях, которые сработали не позже,
+// чем After эскалации подходящего маршрута назад, и до сих пор не подтверждены и не подавлены.
+// Каждому получателю эскалации оповещение отправляется один раз. Вызывается под mu.
+func (e *Engine) escalate(now time.Time) []notification {
+	byReceiver := make(map[string][]Alert)
+	for _, alert := range e.alerts {
+		if alert.AckedAt != nil || len(alert.SilencedBy) != 0 {
+			continue
+		}
+		for _, node := range e.routeTree.match(*alert) {
+			esc := node.route.Escalation
+			if esc == nil || now.Sub(alert.ActiveAt) < time.Duration(esc.After) || slices.Contains(alert.Escalated, esc.Receiver) {
+				continue
+			}
+			alert.Escalated = append(alert.Escalated, esc.Receiver)
+			byReceiver[esc.Receiver] = append(byReceiver[esc.Receiver], *alert)
+		}
+	}
+	receivers := make([]string, 0, len(byReceiver))
+	for receiver := range byReceiver {
+		receivers = append(receivers, receiver)
+	}
+	sort.Strings(receivers)
+	notifications := make([]notification, 0, len(receivers))
+	for _, receiver := range receivers {
+		alerts := byReceiver[receiver]
+		sort.Slice(alerts, func(i, j int) bool { return alerts[i].Fingerprint() < alerts[j].Fingerprint() })
+		e.logger.Infow("alerts escalated", "receiver", receiver, "count", len(alerts))
+		notifications = append(notifications, notification{receiver: receiver, alerts: alerts})
+	}
+	return notifications
+}
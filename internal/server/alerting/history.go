@@ -0,0 +1,76 @@
+package alerting
+
+import (
+	"context"
+	"errors"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerthistory"
+)
+
+// ErrHistoryNotConfigured представляет ошибку запроса истории, когда хранилище истории не задано.
+var ErrHistoryNotConfigured = errors.New("alert history is not configured")
+
+// Event - переход оповещения в состояние firing или resolved.
+type Event = alerthistory.Event
+
+// HistoryQuery - условия выборки истории оповещений; нулевые значения не ограничивают выборку.
+type HistoryQuery = alerthistory.Query
+
+// eventOf возвращает событие перехода оповещения в его текущее состояние.
+func eventOf(alert Alert) Event {
+	at := alert.ActiveAt
+	if alert.ResolvedAt != nil {
+		at = *alert.ResolvedAt
+	}
+	return Event{
+		ID:       alert.ID,
+		Time:     at,
+		Rule:     alert.Rule,
+		Labels:   alert.Labels,
+		Severity: alert.Severity,
+		State:    alert.State,
+		Value:    alert.Value,
+		Summary:  alert.Summary,
+	}
+}
+
+// HistoryStore хранит историю переходов оповещений.
+// AlertHistory возвращает последние события, подходящие под условия, в порядке возрастания времени.
+type HistoryStore interface {
+	AppendAlertEvents(ctx context.Context, events []Event) error
+	AlertHistory(ctx context.Context, q HistoryQuery) ([]Event, error)
+}
+
+// SetHistory задает хранилище истории переходов оповещений. Вызывается до Run.
+func (e *Engine) SetHistory(store HistoryStore) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.history = store
+}
+
+// History возвращает историю переходов оповещений из хранилища.
+func (e *Engine) History(ctx context.Context, q HistoryQuery) ([]Event, error) {
+	e.mu.Lock()
+	store := e.history
+	e.mu.Unlock()
+	if store == nil {
+		return nil, ErrHistoryNotConfigured
+	}
+	return store.AlertHistory(ctx, q)
+}
+
+// recordHistory сохраняет переходы оповещений в хранилище истории, если оно задано.
+func (e *Engine) recordHistory(ctx context.Context, changed []Alert) {
+	e.mu.Lock()
+	store := e.history
+	e.mu.Unlock()
+	if store == nil || len(changed) == 0 {
+		return
+	}
+	events := make([]Event, 0, len(changed))
+	for _, alert := range changed {
+		events = append(events, eventOf(alert))
+	}
+	if err := store.AppendAlertEvents(ctx, events); err != nil {
+		e.logger.Errorw(err.Error(), "event", "record alert history")
+	}
+}
@@ -0,0 +1,58 @@
+package alerting
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// memoryHistory хранит историю оповещений в памяти.
+type memoryHistory struct {
+	events []Event
+}
+
+func (h *memoryHistory) AppendAlertEvents(ctx context.Context, events []Event) error {
+	h.events = append(h.events, events...)
+	return nil
+}
+
+func (h *memoryHistory) AlertHistory(ctx context.Context, q HistoryQuery) ([]Event, error) {
+	var events []Event
+	for _, event := range h.events {
+		if q.Match(event) {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func TestEngine_History(t *testing.T) {
+	c := collector.Collector()
+	e := New([]Rule{{Name: "history-high", Condition: ConditionThreshold, Metric: "HistoryGauge", Op: ">", Threshold: 10, Severity: "warning"}}, nil, zap.NewNop().Sugar())
+	ctx := context.Background()
+	_, err := e.History(ctx, HistoryQuery{})
+	assert.ErrorIs(t, err, ErrHistoryNotConfigured)
+
+	store := &memoryHistory{}
+	e.SetHistory(store)
+	now := time.Now()
+	for i, v := range []float64{20, 25, 5} {
+		require.NoError(t, c.Collect(collector.MetricRequest{ID: "HistoryGauge", MType: "gauge", Value: collector.PtrFloat64(v)}, strconv.FormatFloat(v, 'f', -1, 64)))
+		e.Evaluate(ctx, now.Add(time.Duration(i)*time.Minute))
+	}
+
+	events, err := e.History(ctx, HistoryQuery{Rule: "history-high"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, Event{ID: alertID(Alert{Rule: "history-high", Labels: map[string]string{"metric": "HistoryGauge"}}.Fingerprint()), Time: now, Rule: "history-high", Labels: map[string]string{"metric": "HistoryGauge"}, Severity: "warning",
+		State: StateFiring, Value: 20, Summary: events[0].Summary}, events[0])
+	assert.Equal(t, StateResolved, events[1].State)
+	assert.Equal(t, events[0].ID, events[1].ID)
+	assert.Equal(t, now.Add(2*time.Minute), events[1].Time)
+	assert.Equal(t, 5.0, events[1].Value)
+}
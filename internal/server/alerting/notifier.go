@@ -0,0 +1,64 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Notifiers отправляет уведомления каждому получателю из списка и возвращает объединенные ошибки.
+type Notifiers []Notifier
+
+// Notify отправляет оповещения всем получателям; ошибка одного получателя не мешает остальным.
+func (n Notifiers) Notify(ctx context.Context, alerts []Alert) error {
+	var errs []error
+	for _, notifier := range n {
+		if err := notifier.Notify(ctx, alerts); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// webhookTimeout - время ожидания ответа получателя уведомлений.
+const webhookTimeout = 10 * time.Second
+
+// Webhook отправляет уведомления POST запросом с телом {"alerts": [...]} в формате JSON.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook создает Webhook, отправляющий уведомления на адрес url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Notify отправляет оповещения; ответ с кодом не из диапазона 2xx считается ошибкой.
+func (w *Webhook) Notify(ctx context.Context, alerts []Alert) error {
+	body, err := json.Marshal(state{Alerts: alerts})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error while sending webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with status %d", w.url, resp.StatusCode)
+	}
+	return nil
+}
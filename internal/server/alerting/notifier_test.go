@@ -0,0 +1,32 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhook_Notify(t *testing.T) {
+	var received state
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received.Alerts[0].Rule == "rejected" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	activeAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	alerts := []Alert{{Rule: "absent", Labels: map[string]string{"metric": "PollCount"}, State: StateFiring, Value: 120, ActiveAt: activeAt}}
+	w := NewWebhook(srv.URL)
+	require.NoError(t, w.Notify(context.Background(), alerts))
+	assert.Equal(t, alerts, received.Alerts)
+
+	assert.Error(t, w.Notify(context.Background(), []Alert{{Rule: "rejected"}}))
+}
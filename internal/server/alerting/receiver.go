@@ -0,0 +1,119 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Receiver - именованный получатель уведомлений из файла правил. Задается ровно один способ доставки:
+// webhook, письмо или запись в файл журнала.
+type Receiver struct {
+	Name    string         `json:"name"`               // имя получателя для маршрутов и эскалации
+	Webhook string         `json:"webhook,omitempty"`  // адрес для уведомлений POST запросом
+	Email   *EmailReceiver `json:"email,omitempty"`    // получатели письма; SMTP сервер задается флагами
+	LogFile string         `json:"log_file,omitempty"` // путь к файлу журнала уведомлений
+}
+
+// EmailReceiver - адреса и шаблоны письма получателя Receiver.
+type EmailReceiver struct {
+	To        []string `json:"to"`                  // адреса получателей
+	Templates string   `json:"templates,omitempty"` // путь к файлу шаблонов; пусто - шаблоны SMTP настроек
+}
+
+// Validate проверяет получателя и возвращает ErrInvalidRule с описанием ошибки.
+func (r Receiver) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: receiver name is required", ErrInvalidRule)
+	}
+	kinds := 0
+	if r.Webhook != "" {
+		kinds++
+	}
+	if r.Email != nil {
+		if len(r.Email.To) == 0 {
+			return fmt.Errorf("%w: receiver %q: email needs recipients", ErrInvalidRule, r.Name)
+		}
+		kinds++
+	}
+	if r.LogFile != "" {
+		kinds++
+	}
+	if kinds != 1 {
+		return fmt.Errorf("%w: receiver %q must have exactly one of webhook, email and log_file", ErrInvalidRule, r.Name)
+	}
+	return nil
+}
+
+// Notifier создает получателя уведомлений. Письма отправляются через SMTP сервер из smtp,
+// адреса и шаблоны получателя заменяют заданные в smtp.
+func (r Receiver) Notifier(smtp EmailConfig) (Notifier, error) {
+	switch {
+	case r.Webhook != "":
+		return NewWebhook(r.Webhook), nil
+	case r.Email != nil:
+		cfg := smtp
+		cfg.To = r.Email.To
+		if r.Email.Templates != "" {
+			cfg.Templates = r.Email.Templates
+		}
+		email, err := NewEmail(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("receiver %q: %w", r.Name, err)
+		}
+		return email, nil
+	case r.LogFile != "":
+		return NewLogFile(r.LogFile), nil
+	default:
+		return nil, fmt.Errorf("%w: receiver %q has no delivery method", ErrInvalidRule, r.Name)
+	}
+}
+
+// NewReceivers создает получателей уведомлений по именам.
+func NewReceivers(receivers []Receiver, smtp EmailConfig) (map[string]Notifier, error) {
+	notifiers := make(map[string]Notifier, len(receivers))
+	for _, r := range receivers {
+		notifier, err := r.Notifier(smtp)
+		if err != nil {
+			return nil, err
+		}
+		notifiers[r.Name] = notifier
+	}
+	return notifiers, nil
+}
+
+// LogFile записывает уведомления в файл журнала, по одной строке JSON {"time": ..., "alerts": [...]} на уведомление.
+type LogFile struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewLogFile создает LogFile, дописывающий уведомления в файл path.
+func NewLogFile(path string) *LogFile {
+	return &LogFile{path: path}
+}
+
+// Notify дописывает уведомление в конец файла, создавая файл при необходимости.
+func (l *LogFile) Notify(_ context.Context, alerts []Alert) error {
+	line, err := json.Marshal(struct {
+		Time   time.Time `json:"time"`
+		Alerts []Alert   `json:"alerts"`
+	}{Time: time.Now(), Alerts: alerts})
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
+		return fmt.Errorf("error while opening alert log file: %w", err)
+	}
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error while writing alert log file: %w", err)
+	}
+	return file.Close()
+}
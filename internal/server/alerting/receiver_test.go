@@ -0,0 +1,59 @@
+package alerting
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReceiver_Validate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		receiver      Receiver
+		expectedError bool
+	}{
+		{name: "positive: webhook", receiver: Receiver{Name: "ops", Webhook: "http://localhost/alerts"}},
+		{name: "positive: email", receiver: Receiver{Name: "ops", Email: &EmailReceiver{To: []string{"ops@example.com"}}}},
+		{name: "positive: log file", receiver: Receiver{Name: "ops", LogFile: "alerts.log"}},
+		{name: "negative: no name", receiver: Receiver{LogFile: "alerts.log"}, expectedError: true},
+		{name: "negative: no delivery", receiver: Receiver{Name: "ops"}, expectedError: true},
+		{name: "negative: two deliveries", receiver: Receiver{Name: "ops", Webhook: "http://localhost/alerts", LogFile: "alerts.log"}, expectedError: true},
+		{name: "negative: email without recipients", receiver: Receiver{Name: "ops", Email: &EmailReceiver{}}, expectedError: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.receiver.Validate()
+			if tt.expectedError {
+				assert.ErrorIs(t, err, ErrInvalidRule)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestLogFile_Notify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alerts.log")
+	notifiers, err := NewReceivers([]Receiver{{Name: "log", LogFile: path}}, EmailConfig{})
+	require.NoError(t, err)
+	notifier := notifiers["log"]
+	require.NoError(t, notifier.Notify(context.Background(), []Alert{firingAlert("cpu", "a")}))
+	require.NoError(t, notifier.Notify(context.Background(), []Alert{firingAlert("memory", "a"), firingAlert("disk", "b")}))
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var counts []int
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry state
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		counts = append(counts, len(entry.Alerts))
+	}
+	assert.Equal(t, []int{1, 2}, counts)
+}
@@ -0,0 +1,120 @@
+package alerting
+
+import (
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"reflect"
+	"time"
+)
+
+// SetRuleFile задает файл правил, который читает ReloadRules, и SMTP настройки для получателей писем из файла.
+func (e *Engine) SetRuleFile(path string, smtp EmailConfig) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.ruleFile = path
+	e.smtp = smtp
+}
+
+// ReloadRules перечитывает файл правил, заданный SetRuleFile, и заменяет правила, маршруты и получателей.
+// Файл и получатели проверяются до замены: при ошибке продолжают действовать прежние правила.
+func (e *Engine) ReloadRules() error {
+	e.mu.Lock()
+	path, smtp := e.ruleFile, e.smtp
+	e.mu.Unlock()
+	file, err := LoadRuleFile(path)
+	if err != nil {
+		return err
+	}
+	receivers, err := NewReceivers(file.Receivers, smtp)
+	if err != nil {
+		return err
+	}
+	collector.Collector().SetHistoryRetention(HistoryRetention(file.Rules))
+	e.Reload(file, receivers)
+	return nil
+}
+
+// Reload заменяет проверенные правила, дерево маршрутов и именованных получателей.
+// Активные оповещения сохраняются: оповещения неизмененных правил продолжаются как прежде, оповещения
+// измененных правил проверяются по новому определению при следующем вычислении, а оповещения удаленных
+// правил разрешаются при следующем вычислении с уведомлением. Модели аномалий измененных и удаленных
+// правил сбрасываются, а их производные метрики удаляются. Сбрасывается и история SLO, которых больше
+// нет или у которых изменились счетчики.
+// Об оповещениях, о которых уже уведомляли, новые маршруты повторно не уведомляют.
+func (e *Engine) Reload(file RuleFile, receivers map[string]Notifier) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	unchanged := make(map[string]struct{}, len(file.Rules))
+	previous := make(map[string]Rule, len(e.rules))
+	for _, rule := range e.rules {
+		previous[rule.Name] = rule
+	}
+	for _, rule := range file.Rules {
+		if old, ok := previous[rule.Name]; ok && reflect.DeepEqual(old, rule) {
+			unchanged[rule.Name] = struct{}{}
+		}
+	}
+	for name := range e.models {
+		if _, ok := unchanged[name]; !ok {
+			delete(e.models, name)
+		}
+	}
+	for name, rule := range previous {
+		if _, ok := unchanged[name]; !ok && rule.Condition == ConditionAnomaly {
+			deleteAnomalyMetrics(rule)
+		}
+	}
+	e.rules = file.Rules
+	// история счетчиков сохраняется для SLO с теми же счетчиками, даже если изменились цель или окно
+	counters := make(map[string]SLO, len(file.SLOs))
+	for _, slo := range file.SLOs {
+		counters[slo.Name] = slo
+	}
+	for _, slo := range e.slos {
+		if updated, ok := counters[slo.Name]; !ok || updated.Good != slo.Good || updated.Total != slo.Total {
+			delete(e.sloSeries, slo.Name)
+		}
+	}
+	e.slos = file.SLOs
+
+	named := make(map[string]Notifier, len(receivers)+1)
+	if notifier, ok := e.receivers[""]; ok {
+		named[""] = notifier
+	}
+	for name, notifier := range receivers {
+		named[name] = notifier
+	}
+	e.receivers = named
+
+	now := time.Now()
+	previousRoutes := e.routes
+	e.routeTree, e.routes = newRouteTree(file.Route)
+	for _, alert := range e.alerts {
+		if len(alert.SilencedBy) != 0 || !notified(previousRoutes, alert.Fingerprint()) {
+			continue
+		}
+		for _, node := range e.routeTree.match(*alert) {
+			node.dispatcher.restore([]Alert{*alert}, now)
+		}
+	}
+	e.logger.Infow("alert rules reloaded", "rules", len(file.Rules), "unchanged", len(unchanged),
+		"receivers", len(receivers))
+}
+
+// notified сообщает, уведомлял ли какой-либо из маршрутов routes об оповещении с отпечатком fingerprint.
+func notified(routes []*routeNode, fingerprint string) bool {
+	for _, node := range routes {
+		for _, g := range node.dispatcher.groups {
+			if _, ok := g.notified[fingerprint]; ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Rules возвращает действующие правила оповещений.
+func (e *Engine) Rules() []Rule {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Rule(nil), e.rules...)
+}
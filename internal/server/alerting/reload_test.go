@@ -0,0 +1,88 @@
+package alerting
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEngine_Reload(t *testing.T) {
+	c := collector.Collector()
+	c.RestoreLastSeen(collector.LastSeenState{Agents: map[string]time.Time{"10.7.0.1": time.Now(), "10.7.0.2": time.Now()}})
+	kept := Rule{Name: "kept", Condition: ConditionAbsent, Agent: "10.7.0.1", Window: Duration(time.Minute)}
+	changed := Rule{Name: "changed", Condition: ConditionAbsent, Agent: "10.7.0.2", Window: Duration(time.Minute)}
+	notifier := &recordingNotifier{}
+	e := New([]Rule{kept, changed}, notifier, zap.NewNop().Sugar())
+	e.models["kept"] = &anomalyModel{Count: 3}
+	e.models["changed"] = &anomalyModel{Count: 3}
+	ctx := context.Background()
+	start := time.Now().Add(2 * time.Minute)
+	e.Evaluate(ctx, start)
+	require.Len(t, notifier.sent, 1)
+	require.Len(t, e.Alerts(), 2)
+
+	// оповещения неизмененного правила не отправляются повторно, модели измененного правила сбрасываются
+	changed.Window = Duration(time.Hour)
+	e.Reload(RuleFile{Rules: []Rule{kept, changed}}, nil)
+	assert.Contains(t, e.models, "kept")
+	assert.NotContains(t, e.models, "changed")
+	e.Evaluate(ctx, start.Add(time.Minute))
+	require.Len(t, notifier.sent, 2)
+	assert.Equal(t, []string{"changed/resolved"}, rulesOf(notifier.sent[1]))
+	require.Len(t, e.Alerts(), 1)
+	assert.Equal(t, "kept", e.Alerts()[0].Rule)
+
+	// оповещения удаленного правила разрешаются при следующем вычислении
+	e.Reload(RuleFile{}, nil)
+	assert.Empty(t, e.Rules())
+	e.Evaluate(ctx, start.Add(2*time.Minute))
+	require.Len(t, notifier.sent, 3)
+	assert.Equal(t, []string{"kept/resolved"}, rulesOf(notifier.sent[2]))
+}
+
+func TestEngine_ReloadDuringEvaluate(t *testing.T) {
+	rule := Rule{Name: "reload-race", Condition: ConditionThreshold, Metric: "ReloadRaceGauge", Op: ">", Threshold: 10}
+	e := New([]Rule{rule}, &recordingNotifier{}, zap.NewNop().Sugar())
+	ctx := context.Background()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			e.Reload(RuleFile{Rules: []Rule{rule}}, map[string]Notifier{"team": &recordingNotifier{}})
+		}
+	}()
+	now := time.Now()
+	for i := 0; i < 100; i++ {
+		value := "0"
+		if i%2 == 0 {
+			value = "20"
+		}
+		require.NoError(t, collector.Collector().Collect(collector.MetricRequest{ID: "ReloadRaceGauge", MType: "gauge"}, value))
+		e.Evaluate(ctx, now.Add(time.Duration(i)*time.Second))
+	}
+	<-done
+}
+
+func TestEngine_ReloadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	e := New(nil, nil, zap.NewNop().Sugar())
+	e.SetRuleFile(path, EmailConfig{})
+	assert.Error(t, e.ReloadRules())
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules":[{"name":"high","condition":"threshold","metric":"Alloc","op":">","threshold":10}]}`), 0666))
+	require.NoError(t, e.ReloadRules())
+	require.Len(t, e.Rules(), 1)
+
+	// неверный файл не заменяет действующие правила
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules":[{"name":"high","condition":"threshold"}]}`), 0666))
+	assert.ErrorIs(t, e.ReloadRules(), ErrInvalidRule)
+	require.Len(t, e.Rules(), 1)
+	assert.Equal(t, 10.0, e.Rules()[0].Threshold)
+}
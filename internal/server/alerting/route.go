@@ -0,0 +1,201 @@
+package alerting
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GroupByAll в GroupBy маршрута означает отдельную группу для каждого оповещения.
+const GroupByAll = "..."
+
+// Route - маршрут уведомлений. Как в Alertmanager, маршруты образуют дерево: оповещение проходит
+// по первому подходящему дочернему маршруту (или по всем подходящим, если у маршрута задан Continue)
+// до самого глубокого подходящего маршрута, который и отправляет уведомление получателю Receiver.
+// Незаданные получатель, группировка, таймеры и эскалация наследуются от родительского маршрута.
+//
+// Оповещения маршрута группируются по меткам GroupBy, и уведомление о группе отправляется одним
+// сообщением: первое уведомление ждет GroupWait, чтобы собрать связанные оповещения, изменения
+// в группе отправляются не чаще GroupInterval, а продолжающиеся оповещения повторяются через RepeatInterval.
+type Route struct {
+	Receiver string            `json:"receiver,omitempty"` // получатель уведомлений; пусто - получатель по умолчанию
+	Match    map[string]string `json:"match,omitempty"`    // метки оповещения; метки rule и severity сравниваются с правилом
+	Metric   string            `json:"metric,omitempty"`   // шаблон имени метрики в синтаксисе path.Match
+	Continue bool              `json:"continue,omitempty"` // продолжать проверку следующих маршрутов после совпадения
+	Routes   []Route           `json:"routes,omitempty"`   // дочерние маршруты
+
+	GroupBy        []string    `json:"group_by,omitempty"`        // метки группировки, например agent или rule; пусто - одна группа
+	GroupWait      Duration    `json:"group_wait,omitempty"`      // ожидание перед первым уведомлением о группе
+	GroupInterval  Duration    `json:"group_interval,omitempty"`  // минимальный интервал между уведомлениями об изменениях группы
+	RepeatInterval Duration    `json:"repeat_interval,omitempty"` // интервал повторных уведомлений; ноль отключает повтор
+	Escalation     *Escalation `json:"escalation,omitempty"`      // эскалация неподтвержденных оповещений
+}
+
+// Escalation - эскалация оповещения, которое остается сработавшим и неподтвержденным дольше After:
+// уведомление о нем однократно отправляется получателю Receiver.
+type Escalation struct {
+	After    Duration `json:"after"`    // время от срабатывания до эскалации
+	Receiver string   `json:"receiver"` // получатель эскалации
+}
+
+// Validate проверяет маршрут и его дочерние маршруты и возвращает ErrInvalidRule с описанием ошибки.
+func (r Route) Validate() error {
+	if r.GroupWait < 0 || r.GroupInterval < 0 || r.RepeatInterval < 0 {
+		return fmt.Errorf("%w: route intervals must not be negative", ErrInvalidRule)
+	}
+	for _, label := range r.GroupBy {
+		if label == GroupByAll && len(r.GroupBy) > 1 {
+			return fmt.Errorf("%w: route group_by %q can't be combined with other labels", ErrInvalidRule, GroupByAll)
+		}
+	}
+	if _, err := path.Match(r.Metric, ""); err != nil {
+		return fmt.Errorf("%w: route metric pattern: %v", ErrInvalidRule, err)
+	}
+	if r.Escalation != nil && (r.Escalation.After <= 0 || r.Escalation.Receiver == "") {
+		return fmt.Errorf("%w: route escalation needs positive after and receiver", ErrInvalidRule)
+	}
+	for _, child := range r.Routes {
+		if err := child.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receivers возвращает имена получателей маршрута, его эскалации и дочерних маршрутов.
+func (r Route) receivers() []string {
+	names := []string{r.Receiver}
+	if r.Escalation != nil {
+		names = append(names, r.Escalation.Receiver)
+	}
+	for _, child := range r.Routes {
+		names = append(names, child.receivers()...)
+	}
+	return names
+}
+
+// inherit возвращает маршрут с незаданными параметрами, взятыми из родительского маршрута parent.
+func (r Route) inherit(parent Route) Route {
+	if r.Receiver == "" {
+		r.Receiver = parent.Receiver
+	}
+	if r.GroupBy == nil {
+		r.GroupBy = parent.GroupBy
+	}
+	if r.GroupWait == 0 {
+		r.GroupWait = parent.GroupWait
+	}
+	if r.GroupInterval == 0 {
+		r.GroupInterval = parent.GroupInterval
+	}
+	if r.RepeatInterval == 0 {
+		r.RepeatInterval = parent.RepeatInterval
+	}
+	if r.Escalation == nil {
+		r.Escalation = parent.Escalation
+	}
+	return r
+}
+
+// matches сообщает, подходит ли оповещение под условия маршрута.
+func (r Route) matches(alert Alert) bool {
+	return matchAlert(r.Metric, r.Match, alert)
+}
+
+// groupKey возвращает ключ и метки группы оповещения.
+func (r Route) groupKey(alert Alert) (string, map[string]string) {
+	if len(r.GroupBy) == 1 && r.GroupBy[0] == GroupByAll {
+		return alert.Fingerprint(), alert.Labels
+	}
+	labels := make(map[string]string, len(r.GroupBy))
+	parts := make([]string, 0, len(r.GroupBy))
+	for _, label := range r.GroupBy {
+		labels[label] = alertLabel(alert, label)
+		parts = append(parts, label+"="+labels[label])
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ","), labels
+}
+
+// routeNode - узел дерева маршрутов с унаследованными параметрами и своими группами уведомлений.
+type routeNode struct {
+	route      Route
+	children   []*routeNode
+	dispatcher *dispatcher
+}
+
+// newRouteTree строит дерево маршрутов и возвращает корень и все узлы дерева.
+func newRouteTree(route Route) (*routeNode, []*routeNode) {
+	var nodes []*routeNode
+	var build func(route Route) *routeNode
+	build = func(route Route) *routeNode {
+		node := &routeNode{route: route, dispatcher: newDispatcher(route)}
+		nodes = append(nodes, node)
+		for _, child := range route.Routes {
+			node.children = append(node.children, build(child.inherit(route)))
+		}
+		return node
+	}
+	root := build(route)
+	return root, nodes
+}
+
+// match возвращает узлы, которые отправляют уведомления об оповещении.
+func (n *routeNode) match(alert Alert) []*routeNode {
+	var matched []*routeNode
+	for _, child := range n.children {
+		if !child.route.matches(alert) {
+			continue
+		}
+		matched = append(matched, child.match(alert)...)
+		if !child.route.Continue {
+			break
+		}
+	}
+	if len(matched) == 0 {
+		return []*routeNode{n}
+	}
+	return matched
+}
+
+// notification - уведомление получателю receiver.
+// notifier определяется под mu, так как Reload заменяет получателей во время отправки уведомлений.
+type notification struct {
+	receiver string
+	notifier Notifier
+	alerts   []Alert
+}
+
+// routeAlerts передает сработавшие и разрешенные оповещения в группы узлов дерева, под которые они подходят,
+// и возвращает уведомления групп, таймеры которых истекли к моменту now. Вызывается под mu.
+func (e *Engine) routeAlerts(firing, resolved []Alert, now time.Time) []notification {
+	nodeFiring := make(map[*routeNode][]Alert, len(e.routes))
+	nodeResolved := make(map[*routeNode][]Alert, len(e.routes))
+	for _, alert := range firing {
+		for _, node := range e.routeTree.match(alert) {
+			nodeFiring[node] = append(nodeFiring[node], alert)
+		}
+	}
+	for _, alert := range resolved {
+		for _, node := range e.routeTree.match(alert) {
+			nodeResolved[node] = append(nodeResolved[node], alert)
+		}
+	}
+	for _, node := range e.routes {
+		node.dispatcher.update(nodeFiring[node], nodeResolved[node], now)
+	}
+	return e.flushRoutes(now)
+}
+
+// flushRoutes возвращает уведомления групп всех узлов, таймеры которых истекли к моменту now. Вызывается под mu.
+func (e *Engine) flushRoutes(now time.Time) []notification {
+	var notifications []notification
+	for _, node := range e.routes {
+		for _, alerts := range node.dispatcher.flush(now) {
+			notifications = append(notifications, notification{receiver: node.route.Receiver, alerts: alerts})
+		}
+	}
+	return notifications
+}
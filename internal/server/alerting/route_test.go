@@ -0,0 +1,94 @@
+package alerting
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestRouteTree_Match(t *testing.T) {
+	root, nodes := newRouteTree(Route{
+		Receiver:      "default",
+		GroupInterval: Duration(time.Minute),
+		Routes: []Route{
+			{Receiver: "pager", Match: map[string]string{"severity": "critical"}, Continue: true},
+			{Receiver: "cpu", Metric: "CPU*", Routes: []Route{
+				{Receiver: "cpu-agent", Match: map[string]string{"agent": "10.0.0.1"}},
+			}},
+			{Receiver: "never", Metric: "CPU*"},
+		},
+	})
+	require.Len(t, nodes, 5)
+	assert.Equal(t, Duration(time.Minute), nodes[3].route.GroupInterval)
+
+	testCases := []struct {
+		name              string
+		alert             Alert
+		expectedReceivers []string
+	}{
+		{name: "no match", alert: Alert{Rule: "memory", Labels: map[string]string{"metric": "Alloc"}}, expectedReceivers: []string{"default"}},
+		{name: "metric", alert: Alert{Rule: "cpu", Labels: map[string]string{"metric": "CPUutilization1"}}, expectedReceivers: []string{"cpu"}},
+		{name: "nested route", alert: Alert{Rule: "cpu", Labels: map[string]string{"metric": "CPUutilization1", "agent": "10.0.0.1"}}, expectedReceivers: []string{"cpu-agent"}},
+		{name: "continue", alert: Alert{Rule: "cpu", Severity: "critical", Labels: map[string]string{"metric": "CPUutilization1"}}, expectedReceivers: []string{"pager", "cpu"}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var receivers []string
+			for _, node := range root.match(tt.alert) {
+				receivers = append(receivers, node.route.Receiver)
+			}
+			assert.Equal(t, tt.expectedReceivers, receivers)
+		})
+	}
+}
+
+func TestEngine_Escalation(t *testing.T) {
+	c := collector.Collector()
+	c.RestoreLastSeen(collector.LastSeenState{Agents: map[string]time.Time{"10.6.0.1": time.Now(), "10.6.0.2": time.Now(), "10.6.0.3": time.Now()}})
+	rules := []Rule{
+		{Name: "silent-critical", Condition: ConditionAbsent, Agent: "10.6.0.1", Window: Duration(time.Minute), Severity: "critical"},
+		{Name: "silent-warning", Condition: ConditionAbsent, Agent: "10.6.0.2", Window: Duration(time.Minute), Severity: "warning"},
+		{Name: "silent-acked", Condition: ConditionAbsent, Agent: "10.6.0.3", Window: Duration(time.Minute), Severity: "critical"},
+	}
+	ops, pager, fallback := &recordingNotifier{}, &recordingNotifier{}, &recordingNotifier{}
+	e := New(rules, fallback, zap.NewNop().Sugar())
+	e.SetRoute(Route{Routes: []Route{{
+		Receiver:   "ops",
+		Match:      map[string]string{"severity": "critical"},
+		Escalation: &Escalation{After: Duration(10 * time.Minute), Receiver: "pager"},
+	}}})
+	e.SetReceivers(map[string]Notifier{"ops": ops, "pager": pager})
+	ctx := context.Background()
+	start := time.Now().Add(2 * time.Minute)
+
+	// оповещения направляются получателям маршрутов
+	e.Evaluate(ctx, start)
+	require.Len(t, ops.sent, 1)
+	assert.Equal(t, []string{"silent-acked/firing", "silent-critical/firing"}, rulesOf(ops.sent[0]))
+	require.Len(t, fallback.sent, 1)
+	assert.Equal(t, []string{"silent-warning/firing"}, rulesOf(fallback.sent[0]))
+	assert.Empty(t, pager.sent)
+
+	// подтвержденное оповещение не эскалируется, неподтвержденное эскалируется один раз
+	for _, alert := range e.Alerts() {
+		if alert.Rule == "silent-acked" {
+			acked, err := e.Ack(alert.ID, "admin", start)
+			require.NoError(t, err)
+			assert.Equal(t, "admin", acked.AckedBy)
+		}
+	}
+	_, err := e.Ack("unknown", "admin", start)
+	assert.ErrorIs(t, err, ErrAlertNotFound)
+	e.Dispatch(ctx, start.Add(5*time.Minute))
+	assert.Empty(t, pager.sent)
+	e.Dispatch(ctx, start.Add(10*time.Minute))
+	require.Len(t, pager.sent, 1)
+	assert.Equal(t, []string{"silent-critical/firing"}, rulesOf(pager.sent[0]))
+	assert.Equal(t, []string{"pager"}, pager.sent[0][0].Escalated)
+	e.Dispatch(ctx, start.Add(20*time.Minute))
+	assert.Len(t, pager.sent, 1)
+}
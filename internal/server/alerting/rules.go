@@ -0,0 +1,259 @@
+// Package alerting вычисляет правила оповещений по метрикам сервера, хранит состояние оповещений
+// и отправляет уведомления о срабатывании и разрешении оповещений.
+package alerting
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Условия правил оповещений.
+const (
+	// ConditionAbsent срабатывает, если метрика или агент не обновлялись дольше Window.
+	ConditionAbsent = "absent"
+	// ConditionThreshold сравнивает текущее значение метрики с Threshold.
+	ConditionThreshold = "threshold"
+	// ConditionIncrease сравнивает прирост counter за Window с Threshold.
+	ConditionIncrease = "increase"
+	// ConditionRate сравнивает среднюю скорость роста counter за Window (в единицах за Per) с Threshold.
+	ConditionRate = "rate"
+	// ConditionDeriv сравнивает скорость изменения gauge за Window (в единицах за Per),
+	// оцененную методом наименьших квадратов, с Threshold.
+	ConditionDeriv = "deriv"
+	// ConditionAnomaly срабатывает, если z-оценка значения метрики относительно прогноза модели Model
+	// по модулю превышает Sensitivity; модель обновляется при каждом новом значении метрики.
+	ConditionAnomaly = "anomaly"
+	// ConditionBurnRate срабатывает, если скорость расхода бюджета ошибок SLO и за Window, и за ShortWindow
+	// превышает Threshold. Правила burn_rate создаются для каждого SLO файла правил автоматически.
+	ConditionBurnRate = "burn_rate"
+)
+
+// Операторы сравнения значения с порогом.
+var operators = map[string]func(value, threshold float64) bool{
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+	"==": func(v, t float64) bool { return v == t },
+	"!=": func(v, t float64) bool { return v != t },
+}
+
+// AnyAgent в поле Agent правила означает проверку каждого агента, от которого были записи.
+const AnyAgent = "*"
+
+// ErrInvalidRule представляет ошибку в описании правила оповещения.
+var ErrInvalidRule = errors.New("invalid alert rule")
+
+// Duration - длительность, которая в JSON задается строкой вида "2m" или числом секунд.
+type Duration time.Duration
+
+// UnmarshalJSON разбирает длительность из строки time.ParseDuration или числа секунд.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string or a number of seconds: %w", err)
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// String возвращает длительность в формате time.Duration.
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON записывает длительность строкой вида "2m0s".
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Rule - правило оповещения.
+type Rule struct {
+	Name      string            `json:"name"`               // уникальное имя правила
+	Condition string            `json:"condition"`          // условие срабатывания
+	Metric    string            `json:"metric,omitempty"`   // имя проверяемой метрики
+	Agent     string            `json:"agent,omitempty"`    // проверяемый агент или AnyAgent
+	Window    Duration          `json:"window"`             // окно, за которое проверяется условие
+	Op        string            `json:"op,omitempty"`       // оператор сравнения с порогом
+	Threshold float64           `json:"threshold"`          // порог
+	Per       Duration          `json:"per,omitempty"`      // единица времени для rate и deriv, по умолчанию секунда
+	Severity  string            `json:"severity,omitempty"` // важность оповещения
+	Labels    map[string]string `json:"labels,omitempty"`   // дополнительные метки оповещения
+
+	// Параметры условия anomaly; нулевые значения заменяются значениями по умолчанию.
+	Model         string   `json:"model,omitempty"`          // модель: ModelEWMA или ModelHoltWinters
+	Alpha         float64  `json:"alpha,omitempty"`          // коэффициент сглаживания уровня и дисперсии, по умолчанию 0.3
+	Beta          float64  `json:"beta,omitempty"`           // коэффициент сглаживания тренда Holt-Winters, по умолчанию 0.1
+	Gamma         float64  `json:"gamma,omitempty"`          // коэффициент сглаживания сезонности Holt-Winters, по умолчанию 0.1
+	Season        Duration `json:"season,omitempty"`         // длина сезона Holt-Winters, например "24h"
+	SeasonBuckets int      `json:"season_buckets,omitempty"` // число сезонных корзин Holt-Winters, по умолчанию 24
+	Sensitivity   float64  `json:"sensitivity,omitempty"`    // порог z-оценки, по умолчанию 3
+	WarmUp        Duration `json:"warm_up,omitempty"`        // период прогрева модели, для Holt-Winters по умолчанию один сезон
+
+	// Параметры условия burn_rate.
+	SLO         string   `json:"slo,omitempty"`          // имя SLO из файла правил
+	ShortWindow Duration `json:"short_window,omitempty"` // короткое окно, подтверждающее, что бюджет расходуется сейчас
+}
+
+// Validate проверяет правило и возвращает ErrInvalidRule с описанием ошибки.
+func (r Rule) Validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("%w: empty name", ErrInvalidRule)
+	}
+	if r.Window <= 0 && r.Condition != ConditionThreshold && r.Condition != ConditionAnomaly {
+		return fmt.Errorf("%w %q: window must be positive", ErrInvalidRule, r.Name)
+	}
+	switch r.Condition {
+	case ConditionAbsent:
+		if (r.Metric == "") == (r.Agent == "") {
+			return fmt.Errorf("%w %q: absent condition needs either metric or agent", ErrInvalidRule, r.Name)
+		}
+	case ConditionThreshold, ConditionIncrease, ConditionRate, ConditionDeriv:
+		if r.Metric == "" || r.Agent != "" {
+			return fmt.Errorf("%w %q: %s condition needs metric", ErrInvalidRule, r.Name, r.Condition)
+		}
+		if _, ok := operators[r.Op]; !ok {
+			return fmt.Errorf("%w %q: unknown operator %q", ErrInvalidRule, r.Name, r.Op)
+		}
+		if r.Per < 0 {
+			return fmt.Errorf("%w %q: per must be positive", ErrInvalidRule, r.Name)
+		}
+	case ConditionAnomaly:
+		return r.validateAnomaly()
+	case ConditionBurnRate:
+		if r.SLO == "" || r.Metric != "" || r.Agent != "" {
+			return fmt.Errorf("%w %q: burn_rate condition needs slo", ErrInvalidRule, r.Name)
+		}
+		if r.ShortWindow <= 0 || r.ShortWindow >= r.Window || r.Threshold <= 0 {
+			return fmt.Errorf("%w %q: burn_rate needs short_window shorter than window and positive threshold", ErrInvalidRule, r.Name)
+		}
+	default:
+		return fmt.Errorf("%w %q: unknown condition %q", ErrInvalidRule, r.Name, r.Condition)
+	}
+	return nil
+}
+
+// validateAnomaly проверяет параметры условия anomaly.
+func (r Rule) validateAnomaly() error {
+	if r.Metric == "" || r.Agent != "" {
+		return fmt.Errorf("%w %q: anomaly condition needs metric", ErrInvalidRule, r.Name)
+	}
+	if r.Alpha < 0 || r.Alpha > 1 || r.Beta < 0 || r.Beta > 1 || r.Gamma < 0 || r.Gamma > 1 {
+		return fmt.Errorf("%w %q: alpha, beta and gamma must be between 0 and 1", ErrInvalidRule, r.Name)
+	}
+	if r.Sensitivity < 0 || r.WarmUp < 0 || r.SeasonBuckets < 0 {
+		return fmt.Errorf("%w %q: sensitivity, warm_up and season_buckets must not be negative", ErrInvalidRule, r.Name)
+	}
+	switch r.Model {
+	case ModelEWMA:
+	case ModelHoltWinters:
+		if r.Season <= 0 {
+			return fmt.Errorf("%w %q: holt_winters model needs positive season", ErrInvalidRule, r.Name)
+		}
+	default:
+		return fmt.Errorf("%w %q: unknown anomaly model %q", ErrInvalidRule, r.Name, r.Model)
+	}
+	return nil
+}
+
+// HistoryRetention возвращает время хранения истории значений метрик, достаточное для правил:
+// наибольшее окно правил increase, rate и deriv или ноль, если таких правил нет.
+func HistoryRetention(rules []Rule) time.Duration {
+	var retention time.Duration
+	for _, rule := range rules {
+		switch rule.Condition {
+		case ConditionIncrease, ConditionRate, ConditionDeriv:
+			retention = max(retention, time.Duration(rule.Window))
+		}
+	}
+	return retention
+}
+
+// RuleFile - содержимое файла правил оповещений.
+type RuleFile struct {
+	Rules     []Rule     `json:"rules"`               // правила оповещений
+	SLOs      []SLO      `json:"slos,omitempty"`      // SLO, для которых создаются правила burn_rate
+	Route     Route      `json:"route,omitempty"`     // корень дерева маршрутов уведомлений
+	Receivers []Receiver `json:"receivers,omitempty"` // получатели уведомлений маршрутов
+}
+
+// LoadRuleFile читает правила оповещений и маршрут уведомлений из JSON файла и проверяет их.
+func LoadRuleFile(path string) (RuleFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return RuleFile{}, fmt.Errorf("error while reading alert rules: %w", err)
+	}
+	return ParseRuleFile(data)
+}
+
+// ParseRules разбирает правила оповещений в формате JSON и проверяет их.
+// Имена правил должны быть уникальными.
+func ParseRules(data []byte) ([]Rule, error) {
+	file, err := ParseRuleFile(data)
+	return file.Rules, err
+}
+
+// ParseRuleFile разбирает файл правил оповещений в формате JSON и проверяет правила, SLO, маршруты и получателей.
+// К правилам добавляются правила burn_rate, созданные по SLO. Маршруты могут ссылаться только на объявленных
+// получателей; пустое имя означает получателя по умолчанию.
+func ParseRuleFile(data []byte) (RuleFile, error) {
+	var file RuleFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return RuleFile{}, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+	}
+	slos := make(map[string]struct{}, len(file.SLOs))
+	for _, slo := range file.SLOs {
+		if err := slo.Validate(); err != nil {
+			return RuleFile{}, err
+		}
+		if _, ok := slos[slo.Name]; ok {
+			return RuleFile{}, fmt.Errorf("%w: slo %q: duplicate name", ErrInvalidRule, slo.Name)
+		}
+		slos[slo.Name] = struct{}{}
+		file.Rules = append(file.Rules, slo.Rules()...)
+	}
+	names := make(map[string]struct{}, len(file.Rules))
+	for _, rule := range file.Rules {
+		if err := rule.Validate(); err != nil {
+			return RuleFile{}, err
+		}
+		if _, ok := names[rule.Name]; ok {
+			return RuleFile{}, fmt.Errorf("%w %q: duplicate name", ErrInvalidRule, rule.Name)
+		}
+		if _, ok := slos[rule.SLO]; rule.Condition == ConditionBurnRate && !ok {
+			return RuleFile{}, fmt.Errorf("%w %q: unknown slo %q", ErrInvalidRule, rule.Name, rule.SLO)
+		}
+		names[rule.Name] = struct{}{}
+	}
+	if err := file.Route.Validate(); err != nil {
+		return RuleFile{}, err
+	}
+	receivers := make(map[string]struct{}, len(file.Receivers))
+	for _, receiver := range file.Receivers {
+		if err := receiver.Validate(); err != nil {
+			return RuleFile{}, err
+		}
+		if _, ok := receivers[receiver.Name]; ok {
+			return RuleFile{}, fmt.Errorf("%w: receiver %q: duplicate name", ErrInvalidRule, receiver.Name)
+		}
+		receivers[receiver.Name] = struct{}{}
+	}
+	for _, name := range file.Route.receivers() {
+		if _, ok := receivers[name]; name != "" && !ok {
+			return RuleFile{}, fmt.Errorf("%w: route refers to unknown receiver %q", ErrInvalidRule, name)
+		}
+	}
+	return file, nil
+}
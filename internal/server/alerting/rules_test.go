@@ -0,0 +1,115 @@
+package alerting
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          string
+		expectedRules []Rule
+		expectedError error
+	}{
+		{
+			name: "positive",
+			data: `{"rules":[{"name":"no-poll","condition":"absent","metric":"PollCount","window":"2m","severity":"critical"},{"name":"silent-agents","condition":"absent","agent":"*","window":90}]}`,
+			expectedRules: []Rule{
+				{Name: "no-poll", Condition: ConditionAbsent, Metric: "PollCount", Window: Duration(2 * time.Minute), Severity: "critical"},
+				{Name: "silent-agents", Condition: ConditionAbsent, Agent: AnyAgent, Window: Duration(90 * time.Second)},
+			},
+		},
+		{
+			name: "positive: value conditions",
+			data: `{"rules":[{"name":"errors","condition":"rate","metric":"Errors","window":"2m","op":">","threshold":5},{"name":"memory","condition":"deriv","metric":"FreeMemory","window":"5m","op":"<","threshold":-1000,"per":"1m"},{"name":"cpu","condition":"threshold","metric":"CPUutilization1","op":">=","threshold":90}]}`,
+			expectedRules: []Rule{
+				{Name: "errors", Condition: ConditionRate, Metric: "Errors", Window: Duration(2 * time.Minute), Op: ">", Threshold: 5},
+				{Name: "memory", Condition: ConditionDeriv, Metric: "FreeMemory", Window: Duration(5 * time.Minute), Op: "<", Threshold: -1000, Per: Duration(time.Minute)},
+				{Name: "cpu", Condition: ConditionThreshold, Metric: "CPUutilization1", Op: ">=", Threshold: 90},
+			},
+		},
+		{
+			name: "positive: anomaly conditions",
+			data: `{"rules":[{"name":"rps","condition":"anomaly","metric":"Requests","model":"ewma","sensitivity":4},{"name":"daily","condition":"anomaly","metric":"Requests","model":"holt_winters","season":"24h","season_buckets":48,"warm_up":"48h"}]}`,
+			expectedRules: []Rule{
+				{Name: "rps", Condition: ConditionAnomaly, Metric: "Requests", Model: ModelEWMA, Sensitivity: 4},
+				{Name: "daily", Condition: ConditionAnomaly, Metric: "Requests", Model: ModelHoltWinters, Season: Duration(24 * time.Hour), SeasonBuckets: 48, WarmUp: Duration(48 * time.Hour)},
+			},
+		},
+		{name: "negative: bad json", data: `{"rules":`, expectedError: ErrInvalidRule},
+		{name: "negative: unknown operator", data: `{"rules":[{"name":"r","condition":"rate","metric":"Errors","window":"1m","op":"=>","threshold":1}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: rate for agent", data: `{"rules":[{"name":"r","condition":"rate","agent":"*","window":"1m","op":">","threshold":1}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: increase without window", data: `{"rules":[{"name":"r","condition":"increase","metric":"Errors","op":">","threshold":1}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: empty name", data: `{"rules":[{"condition":"absent","metric":"PollCount","window":"1m"}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: no window", data: `{"rules":[{"name":"r","condition":"absent","metric":"PollCount"}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: unknown condition", data: `{"rules":[{"name":"r","condition":"above","metric":"PollCount","window":"1m"}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: metric and agent", data: `{"rules":[{"name":"r","condition":"absent","metric":"PollCount","agent":"*","window":"1m"}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: unknown anomaly model", data: `{"rules":[{"name":"r","condition":"anomaly","metric":"A","model":"arima"}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: holt_winters without season", data: `{"rules":[{"name":"r","condition":"anomaly","metric":"A","model":"holt_winters"}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: alpha out of range", data: `{"rules":[{"name":"r","condition":"anomaly","metric":"A","model":"ewma","alpha":1.5}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: duplicate name", data: `{"rules":[{"name":"r","condition":"absent","metric":"A","window":"1m"},{"name":"r","condition":"absent","metric":"B","window":"1m"}]}`, expectedError: ErrInvalidRule},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := ParseRules([]byte(tt.data))
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedRules, rules)
+		})
+	}
+}
+
+func TestParseRuleFile(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          string
+		expectedRoute Route
+		expectedError error
+	}{
+		{
+			name:          "positive",
+			data:          `{"rules":[],"route":{"group_by":["agent","rule"],"group_wait":"30s","group_interval":"5m","repeat_interval":"4h"}}`,
+			expectedRoute: Route{GroupBy: []string{"agent", "rule"}, GroupWait: Duration(30 * time.Second), GroupInterval: Duration(5 * time.Minute), RepeatInterval: Duration(4 * time.Hour)},
+		},
+		{name: "positive: no route", data: `{"rules":[]}`},
+		{name: "negative: negative interval", data: `{"route":{"group_wait":-1}}`, expectedError: ErrInvalidRule},
+		{name: "negative: group by all with labels", data: `{"route":{"group_by":["...","agent"]}}`, expectedError: ErrInvalidRule},
+		{
+			name: "positive: routing tree",
+			data: `{"receivers":[{"name":"ops","log_file":"alerts.log"},{"name":"pager","webhook":"http://localhost/page"}],
+				"route":{"routes":[{"receiver":"ops","match":{"severity":"critical"},"escalation":{"after":"15m","receiver":"pager"}}]}}`,
+			expectedRoute: Route{Routes: []Route{{
+				Receiver:   "ops",
+				Match:      map[string]string{"severity": "critical"},
+				Escalation: &Escalation{After: Duration(15 * time.Minute), Receiver: "pager"},
+			}}},
+		},
+		{name: "negative: unknown receiver", data: `{"route":{"routes":[{"receiver":"ops"}]}}`, expectedError: ErrInvalidRule},
+		{name: "negative: duplicate receiver", data: `{"receivers":[{"name":"ops","log_file":"a.log"},{"name":"ops","log_file":"b.log"}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: escalation without deadline", data: `{"receivers":[{"name":"ops","log_file":"a.log"}],"route":{"escalation":{"receiver":"ops"}}}`, expectedError: ErrInvalidRule},
+		{name: "negative: bad metric pattern", data: `{"route":{"routes":[{"metric":"["}]}}`, expectedError: ErrInvalidRule},
+		{name: "positive: slo", data: `{"slos":[{"name":"api","good":"Good","total":"Total","objective":0.99,"window":"720h"}]}`},
+		{name: "negative: duplicate slo", data: `{"slos":[{"name":"api","good":"Good","total":"Total","objective":0.99,"window":"720h"},
+			{"name":"api","good":"Good","total":"Total","objective":0.9,"window":"720h"}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: unknown slo", data: `{"rules":[{"name":"burn","condition":"burn_rate","slo":"api","window":"1h","short_window":"5m","threshold":14.4}]}`, expectedError: ErrInvalidRule},
+		{name: "negative: generated rule name clash", data: `{"rules":[{"name":"api-burn-rate-1h","condition":"threshold","metric":"Alloc","op":">"}],
+			"slos":[{"name":"api","good":"Good","total":"Total","objective":0.99,"window":"720h"}]}`, expectedError: ErrInvalidRule},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := ParseRuleFile([]byte(tt.data))
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedRoute, file.Route)
+		})
+	}
+}
@@ -0,0 +1,304 @@
+package alerting
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Состояния заглушки оповещений.
+const (
+	SilenceActive  = "active"  // заглушка действует
+	SilencePending = "pending" // заглушка начнет действовать позже или ждет окна расписания
+	SilenceExpired = "expired" // срок действия заглушки истек
+)
+
+// silenceRetention - время, в течение которого истекшие заглушки остаются в списке.
+const silenceRetention = 24 * time.Hour
+
+var (
+	// ErrInvalidSilence представляет ошибку в описании заглушки оповещений.
+	ErrInvalidSilence = errors.New("invalid silence")
+	// ErrSilenceNotFound представляет ошибку для не найденной заглушки.
+	ErrSilenceNotFound = errors.New("silence not found")
+)
+
+// weekdays - дни недели расписания заглушки.
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Schedule - повторяющееся окно обслуживания: начинается в Start в указанные дни недели и длится Duration.
+type Schedule struct {
+	Weekdays []string `json:"weekdays,omitempty"` // дни недели (mon, tue, ...), по умолчанию каждый день
+	Start    string   `json:"start"`              // время начала окна в формате "15:04"
+	Duration Duration `json:"duration"`           // длительность окна, не больше недели
+	Timezone string   `json:"timezone,omitempty"` // часовой пояс расписания, по умолчанию UTC
+}
+
+// validate проверяет расписание.
+func (s Schedule) validate() error {
+	if _, err := time.Parse("15:04", s.Start); err != nil {
+		return fmt.Errorf("%w: schedule start must be in 15:04 format", ErrInvalidSilence)
+	}
+	if s.Duration <= 0 || time.Duration(s.Duration) > 7*24*time.Hour {
+		return fmt.Errorf("%w: schedule duration must be positive and at most a week", ErrInvalidSilence)
+	}
+	for _, day := range s.Weekdays {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("%w: unknown weekday %q", ErrInvalidSilence, day)
+		}
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSilence, err)
+	}
+	return nil
+}
+
+// contains сообщает, попадает ли момент t в одно из окон расписания.
+func (s Schedule) contains(t time.Time) bool {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err := time.Parse("15:04", s.Start)
+	if err != nil {
+		return false
+	}
+	t = t.In(loc)
+	// окно длиной до недели могло начаться в любой из предыдущих семи дней
+	for days := 0; days <= 7; days++ {
+		day := t.AddDate(0, 0, -days)
+		from := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, loc)
+		if !s.onWeekday(from.Weekday()) {
+			continue
+		}
+		if !t.Before(from) && t.Before(from.Add(time.Duration(s.Duration))) {
+			return true
+		}
+	}
+	return false
+}
+
+// onWeekday сообщает, начинается ли окно расписания в день недели day.
+func (s Schedule) onWeekday(day time.Weekday) bool {
+	if len(s.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range s.Weekdays {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Silence - заглушка, которая подавляет уведомления об оповещениях, подходящих под шаблон имени метрики
+// и метки. Оповещения под заглушкой по-прежнему вычисляются и отображаются с полем SilencedBy.
+type Silence struct {
+	ID        string            `json:"id"`                   // идентификатор заглушки
+	Metric    string            `json:"metric,omitempty"`     // шаблон имени метрики в синтаксисе path.Match
+	Labels    map[string]string `json:"labels,omitempty"`     // метки оповещения; метки rule и severity сравниваются с правилом
+	StartsAt  time.Time         `json:"starts_at"`            // начало действия, по умолчанию время создания
+	EndsAt    *time.Time        `json:"ends_at,omitempty"`    // окончание действия; для расписания может отсутствовать
+	Duration  Duration          `json:"duration,omitempty"`   // длительность действия, если EndsAt не задано
+	Schedule  *Schedule         `json:"schedule,omitempty"`   // повторяющееся окно обслуживания
+	Comment   string            `json:"comment,omitempty"`    // причина заглушки
+	CreatedBy string            `json:"created_by,omitempty"` // автор заглушки
+	State     string            `json:"state,omitempty"`      // состояние на момент запроса списка
+}
+
+// validate проверяет заглушку.
+func (s Silence) validate() error {
+	if s.Metric == "" && len(s.Labels) == 0 {
+		return fmt.Errorf("%w: metric pattern or labels are required", ErrInvalidSilence)
+	}
+	if _, err := path.Match(s.Metric, ""); err != nil {
+		return fmt.Errorf("%w: metric pattern: %v", ErrInvalidSilence, err)
+	}
+	if s.Duration < 0 {
+		return fmt.Errorf("%w: duration must be positive", ErrInvalidSilence)
+	}
+	if s.EndsAt == nil && s.Duration == 0 && s.Schedule == nil {
+		return fmt.Errorf("%w: ends_at, duration or schedule is required", ErrInvalidSilence)
+	}
+	if s.EndsAt != nil && !s.EndsAt.After(s.StartsAt) {
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidSilence)
+	}
+	if s.Schedule != nil {
+		return s.Schedule.validate()
+	}
+	return nil
+}
+
+// state возвращает состояние заглушки на момент now.
+func (s Silence) state(now time.Time) string {
+	switch {
+	case s.EndsAt != nil && !now.Before(*s.EndsAt):
+		return SilenceExpired
+	case now.Before(s.StartsAt), s.Schedule != nil && !s.Schedule.contains(now):
+		return SilencePending
+	default:
+		return SilenceActive
+	}
+}
+
+// matches сообщает, подходит ли оповещение под заглушку.
+func (s Silence) matches(alert Alert) bool {
+	return matchAlert(s.Metric, s.Labels, alert)
+}
+
+// matchAlert сообщает, подходит ли оповещение под шаблон имени метрики metric и метки labels.
+// Пустой шаблон подходит под любое оповещение.
+func matchAlert(metric string, labels map[string]string, alert Alert) bool {
+	if metric != "" {
+		name, ok := alert.Labels["metric"]
+		if !ok {
+			return false
+		}
+		if matched, _ := path.Match(metric, name); !matched {
+			return false
+		}
+	}
+	for k, v := range labels {
+		if alertLabel(alert, k) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// alertLabel возвращает метку оповещения; метки rule и severity, если их нет среди меток, берутся из правила.
+func alertLabel(alert Alert, key string) string {
+	if v, ok := alert.Labels[key]; ok {
+		return v
+	}
+	switch key {
+	case "rule":
+		return alert.Rule
+	case "severity":
+		return alert.Severity
+	}
+	return ""
+}
+
+// AddSilence проверяет и добавляет заглушку на момент now и возвращает ее с назначенным идентификатором.
+func (e *Engine) AddSilence(s Silence, now time.Time) (Silence, error) {
+	if s.StartsAt.IsZero() {
+		s.StartsAt = now
+	}
+	if err := s.validate(); err != nil {
+		return Silence{}, err
+	}
+	if s.EndsAt == nil && s.Duration > 0 {
+		endsAt := s.StartsAt.Add(time.Duration(s.Duration))
+		s.EndsAt = &endsAt
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return Silence{}, fmt.Errorf("error while generating silence id: %w", err)
+	}
+	s.ID = hex.EncodeToString(id)
+	s.State = ""
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.silences[s.ID] = &s
+	s.State = s.state(now)
+	return s, nil
+}
+
+// ExpireSilence завершает действие заглушки id на момент now.
+// Заглушка остается в списке как истекшая; возвращает ErrSilenceNotFound, если заглушки нет.
+func (e *Engine) ExpireSilence(id string, now time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	s, ok := e.silences[id]
+	if !ok {
+		return ErrSilenceNotFound
+	}
+	if s.state(now) != SilenceExpired {
+		s.EndsAt = &now
+		if s.StartsAt.After(now) {
+			s.StartsAt = now
+		}
+	}
+	return nil
+}
+
+// Silences возвращает заглушки с их состоянием на момент now, отсортированные по времени начала.
+// Заглушки, истекшие больше суток назад, удаляются.
+func (e *Engine) Silences(now time.Time) []Silence {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.pruneSilences(now)
+	silences := make([]Silence, 0, len(e.silences))
+	for _, s := range e.silences {
+		silence := *s
+		silence.State = s.state(now)
+		silences = append(silences, silence)
+	}
+	sort.Slice(silences, func(i, j int) bool {
+		if !silences[i].StartsAt.Equal(silences[j].StartsAt) {
+			return silences[i].StartsAt.Before(silences[j].StartsAt)
+		}
+		return silences[i].ID < silences[j].ID
+	})
+	return silences
+}
+
+// pruneSilences удаляет заглушки, истекшие раньше silenceRetention до now, вызывается под mu.
+func (e *Engine) pruneSilences(now time.Time) {
+	for id, s := range e.silences {
+		if s.EndsAt != nil && now.Sub(*s.EndsAt) > silenceRetention {
+			delete(e.silences, id)
+		}
+	}
+}
+
+// silencedBy возвращает идентификаторы действующих заглушек, подходящих под оповещение, вызывается под mu.
+func (e *Engine) silencedBy(alert Alert, now time.Time) []string {
+	var ids []string
+	for id, s := range e.silences {
+		if s.state(now) == SilenceActive && s.matches(alert) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SilencesState возвращает заглушки в формате JSON для сохранения в хранилище.
+func (e *Engine) SilencesState() ([]byte, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	silences := make([]*Silence, 0, len(e.silences))
+	for _, s := range e.silences {
+		silences = append(silences, s)
+	}
+	return json.Marshal(silences)
+}
+
+// RestoreSilences восстанавливает заглушки, сохраненные SilencesState.
+func (e *Engine) RestoreSilences(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	var silences []*Silence
+	if err := json.Unmarshal(data, &silences); err != nil {
+		return fmt.Errorf("error while parsing silences state: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, s := range silences {
+		s.State = ""
+		e.silences[s.ID] = s
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package alerting
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestSchedule_Contains(t *testing.T) {
+	// 2024-01-01 - понедельник
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		name     string
+		schedule Schedule
+		at       time.Time
+		expected bool
+	}{
+		{name: "daily window", schedule: Schedule{Start: "02:00", Duration: Duration(time.Hour)}, at: monday.Add(150 * time.Minute), expected: true},
+		{name: "after daily window", schedule: Schedule{Start: "02:00", Duration: Duration(time.Hour)}, at: monday.Add(3 * time.Hour)},
+		{name: "window over midnight", schedule: Schedule{Start: "23:00", Duration: Duration(2 * time.Hour)}, at: monday.Add(30 * time.Minute), expected: true},
+		{name: "weekday matches", schedule: Schedule{Weekdays: []string{"mon"}, Start: "10:00", Duration: Duration(time.Hour)}, at: monday.Add(10 * time.Hour), expected: true},
+		{name: "weekday does not match", schedule: Schedule{Weekdays: []string{"tue"}, Start: "10:00", Duration: Duration(time.Hour)}, at: monday.Add(10 * time.Hour)},
+		{name: "window started previous day", schedule: Schedule{Weekdays: []string{"Sun"}, Start: "22:00", Duration: Duration(4 * time.Hour)}, at: monday.Add(time.Hour), expected: true},
+		{name: "timezone", schedule: Schedule{Start: "03:00", Duration: Duration(time.Hour), Timezone: "Europe/Moscow"}, at: monday, expected: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, tt.schedule.validate())
+			assert.Equal(t, tt.expected, tt.schedule.contains(tt.at))
+		})
+	}
+}
+
+func TestEngine_AddSilence(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name          string
+		silence       Silence
+		expectedState string
+		expectedError error
+	}{
+		{name: "positive: duration", silence: Silence{Metric: "Poll*", Duration: Duration(time.Hour)}, expectedState: SilenceActive},
+		{name: "positive: future", silence: Silence{Labels: map[string]string{"rule": "r"}, StartsAt: now.Add(time.Hour), Duration: Duration(time.Hour)}, expectedState: SilencePending},
+		{name: "positive: schedule", silence: Silence{Metric: "*", Schedule: &Schedule{Start: now.UTC().Add(-time.Minute).Format("15:04"), Duration: Duration(time.Hour)}}, expectedState: SilenceActive},
+		{name: "negative: no matchers", silence: Silence{Duration: Duration(time.Hour)}, expectedError: ErrInvalidSilence},
+		{name: "negative: no end", silence: Silence{Metric: "Poll*"}, expectedError: ErrInvalidSilence},
+		{name: "negative: bad pattern", silence: Silence{Metric: "[", Duration: Duration(time.Hour)}, expectedError: ErrInvalidSilence},
+		{name: "negative: bad schedule", silence: Silence{Metric: "*", Schedule: &Schedule{Start: "25:00", Duration: Duration(time.Hour)}}, expectedError: ErrInvalidSilence},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			e := New(nil, nil, zap.NewNop().Sugar())
+			silence, err := e.AddSilence(tt.silence, now)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+				assert.Empty(t, e.Silences(now))
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, silence.ID)
+			assert.Equal(t, tt.expectedState, silence.State)
+			assert.Equal(t, []Silence{silence}, e.Silences(now))
+		})
+	}
+}
+
+func TestEngine_Silences(t *testing.T) {
+	c := collector.Collector()
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "SilencedGauge", MType: "gauge", Value: collector.PtrFloat64(50)}, "50"))
+	notifier := &recordingNotifier{}
+	e := New([]Rule{
+		{Name: "silenced-low", Condition: ConditionThreshold, Metric: "SilencedGauge", Op: "<", Threshold: 100},
+	}, notifier, zap.NewNop().Sugar())
+	ctx := context.Background()
+	now := time.Now()
+
+	silence, err := e.AddSilence(Silence{Metric: "Silenced*", Duration: Duration(time.Minute)}, now)
+	require.NoError(t, err)
+
+	// оповещение отслеживается, но уведомление не отправляется
+	e.Evaluate(ctx, now)
+	alerts := e.Alerts()
+	require.Len(t, alerts, 1)
+	assert.Equal(t, []string{silence.ID}, alerts[0].SilencedBy)
+	assert.Empty(t, notifier.sent)
+
+	// после окончания заглушки отправляется уведомление о срабатывании
+	require.NoError(t, e.ExpireSilence(silence.ID, now.Add(time.Second)))
+	e.Evaluate(ctx, now.Add(2*time.Second))
+	require.Len(t, notifier.sent, 1)
+	assert.Equal(t, StateFiring, notifier.sent[0][0].State)
+	assert.Empty(t, notifier.sent[0][0].SilencedBy)
+	assert.Equal(t, SilenceExpired, e.Silences(now.Add(2 * time.Second))[0].State)
+	assert.ErrorIs(t, e.ExpireSilence("unknown", now), ErrSilenceNotFound)
+
+	// заглушки сохраняются и восстанавливаются, истекшие удаляются через сутки
+	data, err := e.SilencesState()
+	require.NoError(t, err)
+	restored := New(nil, nil, zap.NewNop().Sugar())
+	require.NoError(t, restored.RestoreSilences(data))
+	assert.Len(t, restored.Silences(now.Add(time.Hour)), 1)
+	assert.Empty(t, restored.Silences(now.Add(25*time.Hour)))
+}
@@ -0,0 +1,271 @@
+package alerting
+
+import (
+	"fmt"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Имена производных метрик SLO. Метрики записываются с меткой slo, скорость расхода бюджета - еще и с меткой window.
+const (
+	SLOIndicatorMetric   = collector.ReservedPrefix + "slo_sli"                    // доля успешных событий за окно SLO
+	SLOErrorBudgetMetric = collector.ReservedPrefix + "slo_error_budget_remaining" // доля оставшегося бюджета ошибок
+	SLOBurnRateMetric    = collector.ReservedPrefix + "slo_burn_rate"              // скорость расхода бюджета за окно
+)
+
+const (
+	sloResolution            = 60        // точность окон: промежуток между значениями не больше 1/60 их возраста
+	minSLOWindow             = time.Hour // минимальное окно SLO
+	burnRateShortWindowRatio = 12        // отношение длинного окна правила burn_rate к короткому
+)
+
+// burnRateAlert - окно и порог одного из правил burn_rate, которые создаются для SLO.
+type burnRateAlert struct {
+	divisor  int     // длинное окно правила - окно SLO, деленное на divisor
+	budget   float64 // доля бюджета, израсходованная за длинное окно при пороговой скорости
+	severity string  // важность оповещения
+}
+
+// burnRateAlerts - правила burn_rate по схеме multi-window, multi-burn-rate из SRE Workbook: для окна SLO 30 дней
+// это 2% бюджета за 1 час (5 минут), 5% за 6 часов (30 минут), 10% за сутки (2 часа) и 10% за 3 дня (6 часов).
+var burnRateAlerts = []burnRateAlert{
+	{divisor: 720, budget: 0.02, severity: "critical"},
+	{divisor: 120, budget: 0.05, severity: "critical"},
+	{divisor: 30, budget: 0.1, severity: "warning"},
+	{divisor: 10, budget: 0.1, severity: "warning"},
+}
+
+// SLO - цель уровня обслуживания: доля успешных событий Good среди всех событий Total за скользящее окно Window
+// должна быть не ниже Objective. Good и Total - метрики типа counter.
+type SLO struct {
+	Name      string            `json:"name"`             // уникальное имя SLO
+	Good      string            `json:"good"`             // counter успешных событий
+	Total     string            `json:"total"`            // counter всех событий
+	Objective float64           `json:"objective"`        // цель, например 0.999
+	Window    Duration          `json:"window"`           // окно SLO, например "720h"
+	Labels    map[string]string `json:"labels,omitempty"` // дополнительные метки оповещений SLO
+}
+
+// Validate проверяет SLO и возвращает ErrInvalidRule с описанием ошибки.
+func (s SLO) Validate() error {
+	if s.Name == "" {
+		return fmt.Errorf("%w: slo name is required", ErrInvalidRule)
+	}
+	if s.Good == "" || s.Total == "" || s.Good == s.Total {
+		return fmt.Errorf("%w: slo %q needs different good and total counters", ErrInvalidRule, s.Name)
+	}
+	if s.Objective <= 0 || s.Objective >= 1 {
+		return fmt.Errorf("%w: slo %q: objective must be between 0 and 1", ErrInvalidRule, s.Name)
+	}
+	if time.Duration(s.Window) < minSLOWindow {
+		return fmt.Errorf("%w: slo %q: window must be at least %s", ErrInvalidRule, s.Name, minSLOWindow)
+	}
+	return nil
+}
+
+// Rules возвращает правила burn_rate SLO с именами вида <slo>-burn-rate-<окно>.
+func (s SLO) Rules() []Rule {
+	rules := make([]Rule, 0, len(burnRateAlerts))
+	for _, a := range burnRateAlerts {
+		long := time.Duration(s.Window) / time.Duration(a.divisor)
+		rules = append(rules, Rule{
+			Name:        s.Name + "-burn-rate-" + formatWindow(long),
+			Condition:   ConditionBurnRate,
+			SLO:         s.Name,
+			Window:      Duration(long),
+			ShortWindow: Duration(long / burnRateShortWindowRatio),
+			Op:          ">",
+			Threshold:   a.budget * float64(a.divisor),
+			Severity:    a.severity,
+			Labels:      s.Labels,
+		})
+	}
+	return rules
+}
+
+// formatWindow записывает окно без нулевых минут и секунд: "1h" вместо "1h0m0s".
+func formatWindow(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// sloPoint - накопленные с учетом сбросов значения счетчиков SLO в момент времени.
+type sloPoint struct {
+	Time  time.Time `json:"time"`
+	Good  float64   `json:"good"`
+	Total float64   `json:"total"`
+}
+
+// sloSeries - история счетчиков SLO. Значения прореживаются так, что промежуток между соседними значениями
+// не превышает 1/sloResolution их возраста: окна любой длины вычисляются с такой относительной точностью,
+// а число хранимых значений растет логарифмически с длиной окна SLO.
+type sloSeries struct {
+	Good   float64    `json:"good"`  // последнее значение counter успешных событий
+	Total  float64    `json:"total"` // последнее значение counter всех событий
+	Points []sloPoint `json:"points"`
+}
+
+// observe добавляет значения счетчиков good и total на момент now и удаляет значения старше окна window,
+// кроме последнего значения перед началом окна. Уменьшение значения считается сбросом счетчика.
+func (s *sloSeries) observe(good, total float64, now time.Time, window time.Duration) {
+	point := sloPoint{Time: now}
+	if n := len(s.Points); n != 0 {
+		point.Good = s.Points[n-1].Good + counterDelta(s.Good, good)
+		point.Total = s.Points[n-1].Total + counterDelta(s.Total, total)
+	}
+	s.Good, s.Total = good, total
+	points := append(s.Points, point)
+
+	cutoff := now.Add(-window)
+	drop := 0
+	for drop < len(points)-1 && !points[drop+1].Time.After(cutoff) {
+		drop++
+	}
+	points = points[drop:]
+	thinned := points[:1]
+	for i := 1; i < len(points)-1; i++ {
+		prev := thinned[len(thinned)-1]
+		if points[i+1].Time.Sub(prev.Time) > now.Sub(points[i].Time)/sloResolution {
+			thinned = append(thinned, points[i])
+		}
+	}
+	if len(points) > 1 {
+		thinned = append(thinned, points[len(points)-1])
+	}
+	s.Points = thinned
+}
+
+// counterDelta возвращает прирост counter от previous до current; уменьшение считается сбросом счетчика.
+func counterDelta(previous, current float64) float64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+// increase возвращает прирост счетчиков за окно (now-w, now]. Началом окна считается последнее значение
+// не позже now-w или самое старое значение, если история короче окна. ok равен false, если значений меньше двух.
+func (s *sloSeries) increase(now time.Time, w time.Duration) (good, total float64, ok bool) {
+	if len(s.Points) < 2 {
+		return 0, 0, false
+	}
+	start := now.Add(-w)
+	i := sort.Search(len(s.Points), func(i int) bool { return s.Points[i].Time.After(start) })
+	if i > 0 {
+		i--
+	}
+	first, last := s.Points[i], s.Points[len(s.Points)-1]
+	return last.Good - first.Good, last.Total - first.Total, true
+}
+
+// burnRate возвращает скорость расхода бюджета ошибок SLO за окно w: долю неуспешных событий,
+// деленную на допустимую долю 1-Objective. Без событий за окно скорость равна нулю.
+func (s *sloSeries) burnRate(slo SLO, now time.Time, w time.Duration) (float64, bool) {
+	good, total, ok := s.increase(now, w)
+	if !ok || total <= 0 {
+		return 0, ok
+	}
+	return (total - good) / total / (1 - slo.Objective), true
+}
+
+// observeSLOs учитывает текущие значения счетчиков каждого SLO и публикует SLI, оставшийся бюджет ошибок
+// и скорость его расхода за окна правил burn_rate. Вызывается под mu.
+func (e *Engine) observeSLOs(now time.Time) {
+	c := collector.Collector()
+	for _, slo := range e.slos {
+		good, okGood := c.NumericValue(slo.Good)
+		total, okTotal := c.NumericValue(slo.Total)
+		if !okGood || !okTotal {
+			continue
+		}
+		series, ok := e.sloSeries[slo.Name]
+		if !ok {
+			series = &sloSeries{}
+			e.sloSeries[slo.Name] = series
+		}
+		series.observe(good, total, now, time.Duration(slo.Window))
+		status := series.status(slo, now)
+		c.SetGauge(selfmetrics.ID(SLOIndicatorMetric, "slo", slo.Name), status.SLI)
+		c.SetGauge(selfmetrics.ID(SLOErrorBudgetMetric, "slo", slo.Name), status.ErrorBudgetRemaining)
+		for window, rate := range status.BurnRates {
+			c.SetGauge(selfmetrics.ID(SLOBurnRateMetric, "slo", slo.Name, "window", window), rate)
+		}
+	}
+}
+
+// SLOStatus - состояние SLO за его окно.
+type SLOStatus struct {
+	SLO                  SLO                `json:"slo"`                    // определение SLO
+	SLI                  float64            `json:"sli"`                    // доля успешных событий за окно SLO
+	ErrorBudgetRemaining float64            `json:"error_budget_remaining"` // доля оставшегося бюджета ошибок, отрицательная при перерасходе
+	BurnRates            map[string]float64 `json:"burn_rates"`             // скорость расхода бюджета по окнам правил burn_rate
+}
+
+// status возвращает состояние SLO на момент now. Без событий за окно SLI равен единице, а бюджет не израсходован.
+func (s *sloSeries) status(slo SLO, now time.Time) SLOStatus {
+	status := SLOStatus{SLO: slo, SLI: 1, ErrorBudgetRemaining: 1, BurnRates: make(map[string]float64)}
+	if good, total, ok := s.increase(now, time.Duration(slo.Window)); ok && total > 0 {
+		status.SLI = good / total
+		status.ErrorBudgetRemaining = 1 - (1-status.SLI)/(1-slo.Objective)
+	}
+	for _, rule := range slo.Rules() {
+		for _, w := range []Duration{rule.Window, rule.ShortWindow} {
+			rate, _ := s.burnRate(slo, now, time.Duration(w))
+			status.BurnRates[formatWindow(time.Duration(w))] = rate
+		}
+	}
+	return status
+}
+
+// SLOs возвращает состояние SLO, отсортированных по имени, на момент now.
+func (e *Engine) SLOs(now time.Time) []SLOStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	statuses := make([]SLOStatus, 0, len(e.slos))
+	for _, slo := range e.slos {
+		series, ok := e.sloSeries[slo.Name]
+		if !ok {
+			series = &sloSeries{}
+		}
+		statuses = append(statuses, series.status(slo, now))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].SLO.Name < statuses[j].SLO.Name })
+	return statuses
+}
+
+// evaluateBurnRate проверяет, превышает ли скорость расхода бюджета ошибок SLO порог правила
+// и за длинное, и за короткое окно.
+func (e *Engine) evaluateBurnRate(rule Rule, now time.Time) []result {
+	res := result{labels: ruleLabels(rule, "slo", rule.SLO)}
+	var slo SLO
+	for _, s := range e.slos {
+		if s.Name == rule.SLO {
+			slo = s
+		}
+	}
+	series, ok := e.sloSeries[rule.SLO]
+	if slo.Name == "" || !ok {
+		res.summary = fmt.Sprintf("not enough data to compute burn rate of slo %q", rule.SLO)
+		return []result{res}
+	}
+	long, okLong := series.burnRate(slo, now, time.Duration(rule.Window))
+	short, okShort := series.burnRate(slo, now, time.Duration(rule.ShortWindow))
+	if !okLong || !okShort {
+		res.summary = fmt.Sprintf("not enough data to compute burn rate of slo %q", rule.SLO)
+		return []result{res}
+	}
+	res.value = long
+	res.firing = long > rule.Threshold && short > rule.Threshold
+	res.summary = fmt.Sprintf("slo %q burns error budget %.2fx over %s and %.2fx over %s (threshold %g)",
+		rule.SLO, long, formatWindow(time.Duration(rule.Window)), short, formatWindow(time.Duration(rule.ShortWindow)), rule.Threshold)
+	return []result{res}
+}
@@ -0,0 +1,146 @@
+package alerting
+
+import (
+	"context"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSLO_Rules(t *testing.T) {
+	slo := SLO{Name: "checkout", Good: "CheckoutOK", Total: "CheckoutAll", Objective: 0.999, Window: Duration(720 * time.Hour)}
+	require.NoError(t, slo.Validate())
+	rules := slo.Rules()
+	require.Len(t, rules, 4)
+	testCases := []struct {
+		name        string
+		window      time.Duration
+		shortWindow time.Duration
+		threshold   float64
+		severity    string
+	}{
+		{name: "checkout-burn-rate-1h", window: time.Hour, shortWindow: 5 * time.Minute, threshold: 14.4, severity: "critical"},
+		{name: "checkout-burn-rate-6h", window: 6 * time.Hour, shortWindow: 30 * time.Minute, threshold: 6, severity: "critical"},
+		{name: "checkout-burn-rate-24h", window: 24 * time.Hour, shortWindow: 2 * time.Hour, threshold: 3, severity: "warning"},
+		{name: "checkout-burn-rate-72h", window: 72 * time.Hour, shortWindow: 6 * time.Hour, threshold: 1, severity: "warning"},
+	}
+	for i, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := rules[i]
+			assert.NoError(t, rule.Validate())
+			assert.Equal(t, tt.name, rule.Name)
+			assert.Equal(t, Duration(tt.window), rule.Window)
+			assert.Equal(t, Duration(tt.shortWindow), rule.ShortWindow)
+			assert.InDelta(t, tt.threshold, rule.Threshold, 1e-9)
+			assert.Equal(t, tt.severity, rule.Severity)
+		})
+	}
+}
+
+func TestSLO_Validate(t *testing.T) {
+	valid := SLO{Name: "api", Good: "Good", Total: "Total", Objective: 0.99, Window: Duration(24 * time.Hour)}
+	testCases := []struct {
+		name   string
+		modify func(s *SLO)
+	}{
+		{name: "no name", modify: func(s *SLO) { s.Name = "" }},
+		{name: "same counters", modify: func(s *SLO) { s.Good = s.Total }},
+		{name: "objective out of range", modify: func(s *SLO) { s.Objective = 1 }},
+		{name: "short window", modify: func(s *SLO) { s.Window = Duration(time.Minute) }},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			slo := valid
+			tt.modify(&slo)
+			assert.ErrorIs(t, slo.Validate(), ErrInvalidRule)
+		})
+	}
+}
+
+func TestSLOSeries(t *testing.T) {
+	var s sloSeries
+	start := time.Now()
+	window := 24 * time.Hour
+	// 30 часов событий раз в 10 секунд: 1% ошибок, после 20 часов счетчики сбрасываются
+	var good, total float64
+	for i := 0; i <= 30*360; i++ {
+		now := start.Add(time.Duration(i) * 10 * time.Second)
+		if i == 20*360 {
+			good, total = 0, 0
+		}
+		s.observe(good, total, now, window)
+		good += 99
+		total += 100
+	}
+	end := start.Add(30 * time.Hour)
+
+	// история прорежена, но окна вычисляются с точностью около 1/sloResolution
+	assert.Less(t, len(s.Points), 1000)
+	assert.False(t, s.Points[0].Time.After(end.Add(-window)))
+	for _, w := range []time.Duration{5 * time.Minute, time.Hour, 6 * time.Hour, window} {
+		g, tot, ok := s.increase(end, w)
+		require.True(t, ok)
+		expected := w.Seconds() / 10 * 100
+		assert.InDelta(t, expected, tot, expected*2/sloResolution, "window %s", w)
+		assert.InDelta(t, 0.99, g/tot, 1e-9)
+	}
+	rate, ok := s.burnRate(SLO{Objective: 0.99}, end, time.Hour)
+	require.True(t, ok)
+	assert.InDelta(t, 1, rate, 1e-9)
+
+	var empty sloSeries
+	_, ok = empty.burnRate(SLO{Objective: 0.99}, end, time.Hour)
+	assert.False(t, ok)
+}
+
+func TestEngine_EvaluateBurnRate(t *testing.T) {
+	c := collector.Collector()
+	slo := SLO{Name: "payments", Good: "PaymentsGood", Total: "PaymentsTotal", Objective: 0.99, Window: Duration(720 * time.Hour)}
+	file, err := ParseRuleFile([]byte(`{"slos":[{"name":"payments","good":"PaymentsGood","total":"PaymentsTotal","objective":0.99,"window":"720h"}]}`))
+	require.NoError(t, err)
+	require.Len(t, file.Rules, 4)
+	e := New(nil, nil, zap.NewNop().Sugar())
+	e.Reload(file, nil)
+
+	// 10% ошибок - скорость расхода бюджета 10: срабатывают все правила, кроме правила 1h с порогом 14.4
+	start := time.Now()
+	for i := 0; i <= 65; i++ {
+		if i > 0 {
+			require.NoError(t, c.Collect(collector.MetricRequest{ID: slo.Good, MType: collector.Counter}, strconv.Itoa(90)))
+			require.NoError(t, c.Collect(collector.MetricRequest{ID: slo.Total, MType: collector.Counter}, strconv.Itoa(100)))
+		}
+		e.Evaluate(context.Background(), start.Add(time.Duration(i)*time.Minute))
+	}
+	var firing []string
+	for _, alert := range e.Alerts() {
+		firing = append(firing, alert.Rule)
+		assert.Equal(t, "payments", alert.Labels["slo"])
+	}
+	assert.Equal(t, []string{"payments-burn-rate-24h", "payments-burn-rate-6h", "payments-burn-rate-72h"}, firing)
+
+	statuses := e.SLOs(start.Add(65 * time.Minute))
+	require.Len(t, statuses, 1)
+	assert.InDelta(t, 0.9, statuses[0].SLI, 1e-9)
+	assert.InDelta(t, -9, statuses[0].ErrorBudgetRemaining, 1e-9)
+	assert.InDelta(t, 10, statuses[0].BurnRates["5m"], 1e-9)
+
+	budget, ok := c.NumericValue(selfmetrics.ID(SLOErrorBudgetMetric, "slo", "payments"))
+	require.True(t, ok)
+	assert.InDelta(t, -9, budget, 1e-9)
+	rate, ok := c.NumericValue(selfmetrics.ID(SLOBurnRateMetric, "slo", "payments", "window", "1h"))
+	require.True(t, ok)
+	assert.InDelta(t, 10, rate, 1e-9)
+
+	// история счетчиков SLO сохраняется в состоянии
+	data, err := e.State()
+	require.NoError(t, err)
+	restarted := New(nil, nil, zap.NewNop().Sugar())
+	restarted.Reload(file, nil)
+	require.NoError(t, restarted.Restore(data))
+	assert.Equal(t, statuses, restarted.SLOs(start.Add(65*time.Minute)))
+}
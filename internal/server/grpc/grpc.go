@@ -2,17 +2,32 @@ package grpc
 
 import (
 	"context"
+	"crypto/subtle"
+	"errors"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerting"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
 	pb "github.com/ZnNr/go-musthave-metrics.git/proto"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"log"
+	"net"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 // MetricsServer определяет структуру сервера метрик.
+// AdminToken - токен администратора для удаления и сброса метрик; без него эти операции запрещены.
+// Alerts - движок оповещений; если он не задан, запросы оповещений возвращают Unimplemented.
 type MetricsServer struct {
 	pb.UnimplementedMetricsServer
+	AdminToken string
+	Alerts     *alerting.Engine
 }
 
 // SaveMetricFromJSON сохраняет метрику из JSON и возвращает ответ.
@@ -21,6 +36,15 @@ func (s *MetricsServer) SaveMetricFromJSON(ctx context.Context, in *pb.MetricReq
 	metric := collector.MetricRequest{
 		ID:    in.ID,
 		MType: in.MType,
+		Op:    in.Op,
+	}
+	if in.Metadata != nil {
+		metric.Metadata = &collector.MetricMetadata{
+			Unit:        in.Metadata.Unit,
+			Description: in.Metadata.Description,
+			Type:        in.Metadata.Type,
+			Owner:       in.Metadata.Owner,
+		}
 	}
 
 	// Получение значения метрики.
@@ -30,8 +54,15 @@ func (s *MetricsServer) SaveMetricFromJSON(ctx context.Context, in *pb.MetricReq
 		metricValue = strconv.Itoa(int(in.Delta))
 		metric.Delta = &in.Delta
 	case collector.Gauge:
-		metricValue = strconv.FormatFloat(in.Value, 'f', -1, 64)
+		metricValue = collector.FormatGauge(in.Value)
 		metric.Value = &in.Value
+	case collector.Histogram:
+		if in.Histogram == nil {
+			return &pb.SaveMetricResponse{
+				ResultJSON: nil,
+			}, status.Error(codes.InvalidArgument, collector.ErrBadRequest.Error())
+		}
+		metric.Histogram = histogramFromProto(in.Histogram)
 	default:
 		// Возвращаем ошибку, если тип метрики не поддерживается.
 		return &pb.SaveMetricResponse{
@@ -39,7 +70,19 @@ func (s *MetricsServer) SaveMetricFromJSON(ctx context.Context, in *pb.MetricReq
 		}, status.Error(codes.Unimplemented, collector.ErrNotImplemented.Error())
 	}
 
-	if err := c.Collect(metric, metricValue); err != nil {
+	if err := c.CollectFrom(agentID(ctx), metric, metricValue); err != nil {
+		if errors.Is(err, collector.ErrLimitExceeded) {
+			// Возвращаем ошибку, если запись превышает лимит числа серий или скорости записи.
+			return &pb.SaveMetricResponse{
+				ResultJSON: nil,
+			}, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		if errors.Is(err, collector.ErrConflict) {
+			// Возвращаем ошибку, если тип метрики расходится с зарегистрированным.
+			return &pb.SaveMetricResponse{
+				ResultJSON: nil,
+			}, status.Error(codes.FailedPrecondition, err.Error())
+		}
 		// Возвращаем ошибку, если коллектор не смог собрать метрику.
 		return &pb.SaveMetricResponse{
 			ResultJSON: nil,
@@ -59,3 +102,174 @@ func (s *MetricsServer) SaveMetricFromJSON(ctx context.Context, in *pb.MetricReq
 		ResultJSON: resultJSON,
 	}, nil
 }
+
+// DeleteMetric удаляет метрику по имени и типу.
+func (s *MetricsServer) DeleteMetric(ctx context.Context, in *pb.MetricRequest) (*pb.SaveMetricResponse, error) {
+	if err := s.checkAdmin(ctx); err != nil {
+		return &pb.SaveMetricResponse{}, err
+	}
+	if err := collector.Collector().DeleteMetric(in.ID, in.MType); err != nil {
+		if errors.Is(err, collector.ErrNotFound) {
+			return &pb.SaveMetricResponse{}, status.Error(codes.NotFound, err.Error())
+		}
+		return &pb.SaveMetricResponse{}, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.SaveMetricResponse{}, nil
+}
+
+// ResetMetric сбрасывает значение метрики по имени и типу и возвращает сброшенную метрику в формате JSON.
+func (s *MetricsServer) ResetMetric(ctx context.Context, in *pb.MetricRequest) (*pb.SaveMetricResponse, error) {
+	if err := s.checkAdmin(ctx); err != nil {
+		return &pb.SaveMetricResponse{}, err
+	}
+	c := collector.Collector()
+	if err := c.ResetMetric(in.ID, in.MType); err != nil {
+		if errors.Is(err, collector.ErrNotFound) {
+			return &pb.SaveMetricResponse{}, status.Error(codes.NotFound, err.Error())
+		}
+		return &pb.SaveMetricResponse{}, status.Error(codes.Internal, err.Error())
+	}
+	resultJSON, err := c.GetMetricJSON(in.ID)
+	if err != nil {
+		return &pb.SaveMetricResponse{}, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.SaveMetricResponse{
+		ResultJSON: resultJSON,
+	}, nil
+}
+
+// ListMetrics возвращает сохраненные метрики, имена которых начинаются с Prefix, с их описаниями.
+func (s *MetricsServer) ListMetrics(ctx context.Context, in *pb.ListMetricsRequest) (*pb.ListMetricsResponse, error) {
+	c := collector.Collector()
+	metrics := c.Snapshot()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].ID < metrics[j].ID
+	})
+	resp := &pb.ListMetricsResponse{}
+	for _, m := range metrics {
+		if !strings.HasPrefix(m.ID, in.Prefix) {
+			continue
+		}
+		info := &pb.MetricInfo{ID: m.ID, MType: m.MType}
+		if md, ok := c.GetMetadata(m.ID); ok {
+			info.Metadata = &pb.MetricMetadata{
+				Unit:        md.Unit,
+				Description: md.Description,
+				Type:        md.Type,
+				Owner:       md.Owner,
+			}
+		}
+		resp.Metrics = append(resp.Metrics, info)
+	}
+	return resp, nil
+}
+
+// ListAlerts возвращает текущие сработавшие оповещения, при заданном Rule - только оповещения этого правила.
+func (s *MetricsServer) ListAlerts(ctx context.Context, in *pb.AlertsRequest) (*pb.AlertsResponse, error) {
+	if s.Alerts == nil {
+		return &pb.AlertsResponse{}, status.Error(codes.Unimplemented, "alerting is not configured")
+	}
+	resp := &pb.AlertsResponse{}
+	for _, alert := range s.Alerts.Alerts() {
+		if in.Rule != "" && alert.Rule != in.Rule {
+			continue
+		}
+		resp.Alerts = append(resp.Alerts, &pb.Alert{
+			ID:         alert.ID,
+			Rule:       alert.Rule,
+			Labels:     alert.Labels,
+			Severity:   alert.Severity,
+			State:      alert.State,
+			Value:      alert.Value,
+			Summary:    alert.Summary,
+			Time:       alert.ActiveAt.UnixMilli(),
+			SilencedBy: alert.SilencedBy,
+		})
+	}
+	return resp, nil
+}
+
+// AlertHistory возвращает историю переходов оповещений за период From - To.
+func (s *MetricsServer) AlertHistory(ctx context.Context, in *pb.AlertsRequest) (*pb.AlertsResponse, error) {
+	if s.Alerts == nil {
+		return &pb.AlertsResponse{}, status.Error(codes.Unimplemented, "alerting is not configured")
+	}
+	q := alerting.HistoryQuery{Rule: in.Rule, Limit: int(in.Limit)}
+	if in.From != 0 {
+		q.From = time.UnixMilli(in.From)
+	}
+	if in.To != 0 {
+		q.To = time.UnixMilli(in.To)
+	}
+	events, err := s.Alerts.History(ctx, q)
+	if errors.Is(err, alerting.ErrHistoryNotConfigured) {
+		return &pb.AlertsResponse{}, status.Error(codes.Unimplemented, err.Error())
+	}
+	if err != nil {
+		return &pb.AlertsResponse{}, status.Error(codes.Internal, err.Error())
+	}
+	resp := &pb.AlertsResponse{}
+	for _, event := range events {
+		resp.Alerts = append(resp.Alerts, &pb.Alert{
+			ID:       event.ID,
+			Rule:     event.Rule,
+			Labels:   event.Labels,
+			Severity: event.Severity,
+			State:    event.State,
+			Value:    event.Value,
+			Summary:  event.Summary,
+			Time:     event.Time.UnixMilli(),
+		})
+	}
+	return resp, nil
+}
+
+// checkAdmin проверяет токен администратора в метаданных запроса "authorization: Bearer <token>".
+func (s *MetricsServer) checkAdmin(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token, ok := strings.CutPrefix(v, "Bearer ")
+		if ok && s.AdminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.AdminToken)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "admin token required")
+}
+
+// histogramFromProto преобразует гистограмму из gRPC-запроса в значение коллектора.
+func histogramFromProto(in *pb.Histogram) *collector.HistogramValue {
+	h := &collector.HistogramValue{
+		Count:     in.Count,
+		Sum:       in.Sum,
+		Bounds:    in.Bounds,
+		Buckets:   in.Buckets,
+		Schema:    in.Schema,
+		ZeroCount: in.ZeroCount,
+	}
+	if len(in.Positive) != 0 {
+		h.Positive = in.Positive
+	}
+	return h
+}
+
+// agentID возвращает идентификатор агента для лимитов: адрес клиента без порта.
+// Метаданные x-real-ip не учитываются, так как их задает сам клиент.
+func agentID(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// UnaryInterceptor учитывает gRPC вызов в собственных метриках сервера по методу и коду ответа.
+func UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	selfmetrics.ObserveGRPC(info.FullMethod, status.Code(err).String(), time.Since(start))
+	return resp, err
+}
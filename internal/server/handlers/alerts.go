@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerting"
+	"github.com/go-chi/chi/v5"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ListAlertsHandler - a method for listing current firing alerts.
+func (h *Handler) ListAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		http.Error(w, "alerting is not configured", http.StatusNotImplemented)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.alerts.Alerts())
+}
+
+// AlertHistoryHandler - a method for listing alert state transitions.
+// Transitions can be filtered by "rule", "from" and "to" (RFC 3339) query parameters;
+// "limit" sets maximum number of latest transitions in response.
+func (h *Handler) AlertHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		http.Error(w, "alerting is not configured", http.StatusNotImplemented)
+		return
+	}
+	q, err := historyQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := h.alerts.History(r.Context(), q)
+	if errors.Is(err, alerting.ErrHistoryNotConfigured) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, events)
+}
+
+// historyQuery parses alert history query from url query parameters.
+func historyQuery(r *http.Request) (alerting.HistoryQuery, error) {
+	values := r.URL.Query()
+	q := alerting.HistoryQuery{Rule: values.Get("rule")}
+	var err error
+	if from := values.Get("from"); from != "" {
+		if q.From, err = time.Parse(time.RFC3339, from); err != nil {
+			return q, err
+		}
+	}
+	if to := values.Get("to"); to != "" {
+		if q.To, err = time.Parse(time.RFC3339, to); err != nil {
+			return q, err
+		}
+	}
+	if limit := values.Get("limit"); limit != "" {
+		if q.Limit, err = strconv.Atoi(limit); err != nil {
+			return q, err
+		}
+	}
+	return q, nil
+}
+
+// ListSLOsHandler - a method for listing SLOs with their SLI, remaining error budget and burn rates.
+func (h *Handler) ListSLOsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		http.Error(w, "alerting is not configured", http.StatusNotImplemented)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.alerts.SLOs(time.Now()))
+}
+
+// ReloadRulesHandler - a method for reloading alert rules file.
+// Rules are validated before they replace current ones; response contains loaded rules.
+func (h *Handler) ReloadRulesHandler(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		http.Error(w, "alerting is not configured", http.StatusNotImplemented)
+		return
+	}
+	err := h.alerts.ReloadRules()
+	if errors.Is(err, alerting.ErrInvalidRule) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.alerts.Rules())
+}
+
+// AckAlertHandler - a method for acknowledging firing alert by id from url.
+// Optional JSON body {"by": "..."} names who acknowledged the alert. Acknowledged alerts are not escalated.
+func (h *Handler) AckAlertHandler(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		http.Error(w, "alerting is not configured", http.StatusNotImplemented)
+		return
+	}
+	var ack struct {
+		By string `json:"by"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&ack); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	alert, err := h.alerts.Ack(chi.URLParam(r, "id"), ack.By, time.Now())
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, alert)
+}
+
+// ListSilencesHandler - a method for listing alert silences with their current state.
+func (h *Handler) ListSilencesHandler(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		http.Error(w, "alerting is not configured", http.StatusNotImplemented)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.alerts.Silences(time.Now()))
+}
+
+// CreateSilenceHandler - a method for creating alert silence from JSON body of http request.
+// Silence is active for fixed duration (ends_at or duration) or on a recurring schedule.
+func (h *Handler) CreateSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		http.Error(w, "alerting is not configured", http.StatusNotImplemented)
+		return
+	}
+	var silence alerting.Silence
+	if err := json.NewDecoder(r.Body).Decode(&silence); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	silence, err := h.alerts.AddSilence(silence, time.Now())
+	if errors.Is(err, alerting.ErrInvalidSilence) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, silence)
+}
+
+// ExpireSilenceHandler - a method for expiring alert silence by id from url.
+func (h *Handler) ExpireSilenceHandler(w http.ResponseWriter, r *http.Request) {
+	if h.alerts == nil {
+		http.Error(w, "alerting is not configured", http.StatusNotImplemented)
+		return
+	}
+	if err := h.alerts.ExpireSilence(chi.URLParam(r, "id"), time.Now()); err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeJSON writes value as JSON response with given status.
+func (h *Handler) writeJSON(w http.ResponseWriter, status int, value any) {
+	answer, err := json.Marshal(value)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	if _, err = w.Write(answer); err != nil {
+		return
+	}
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerting"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/saver/file"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSilences(t *testing.T) {
+	r := chi.NewRouter()
+	h := Handler{adminToken: "secret", alerts: alerting.New(nil, nil, zap.NewNop().Sugar())}
+	r.Get("/admin/silences/", h.ListSilencesHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(h.CheckAdminHandler)
+		r.Post("/admin/silences/", h.CreateSilenceHandler)
+		r.Delete("/admin/silences/{id}", h.ExpireSilenceHandler)
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	client := resty.New()
+
+	testCases := []struct {
+		name         string
+		body         string
+		token        string
+		expectedCode int
+	}{
+		{name: "negative: no token", body: `{"metric":"Poll*","duration":"1h"}`, expectedCode: http.StatusUnauthorized},
+		{name: "negative: bad json", body: `{"metric":`, token: "secret", expectedCode: http.StatusBadRequest},
+		{name: "negative: no end", body: `{"metric":"Poll*"}`, token: "secret", expectedCode: http.StatusBadRequest},
+		{name: "fixed duration", body: `{"metric":"Poll*","duration":"1h","comment":"deploy"}`, token: "secret", expectedCode: http.StatusCreated},
+		{name: "recurring schedule", body: `{"labels":{"severity":"warning"},"schedule":{"weekdays":["sat","sun"],"start":"02:00","duration":"2h"}}`, token: "secret", expectedCode: http.StatusCreated},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := client.R().SetBody(tt.body)
+			if tt.token != "" {
+				req.SetAuthToken(tt.token)
+			}
+			resp, err := req.Post(srv.URL + "/admin/silences/")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode())
+		})
+	}
+
+	resp, err := client.R().Get(srv.URL + "/admin/silences/")
+	require.NoError(t, err)
+	var silences []alerting.Silence
+	require.NoError(t, json.Unmarshal(resp.Body(), &silences))
+	require.Len(t, silences, 2)
+	assert.Equal(t, "deploy", silences[0].Comment)
+	assert.Equal(t, alerting.SilenceActive, silences[0].State)
+
+	resp, err = client.R().SetAuthToken("secret").Delete(srv.URL + "/admin/silences/" + silences[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	resp, err = client.R().SetAuthToken("secret").Delete(srv.URL + "/admin/silences/unknown")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+
+	resp, err = client.R().Get(srv.URL + "/admin/silences/")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(resp.Body(), &silences))
+	assert.Equal(t, alerting.SilenceExpired, silences[0].State)
+
+	h.alerts = nil
+	resp, err = client.R().Get(srv.URL + "/admin/silences/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode())
+}
+
+func TestAlerts(t *testing.T) {
+	c := collector.Collector()
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "AlertsAPIGauge", MType: "gauge", Value: collector.PtrFloat64(99)}, "99"))
+	engine := alerting.New([]alerting.Rule{
+		{Name: "api-high", Condition: alerting.ConditionThreshold, Metric: "AlertsAPIGauge", Op: ">", Threshold: 90},
+	}, nil, zap.NewNop().Sugar())
+	engine.SetHistory(file.New(filepath.Join(t.TempDir(), "metrics.json")))
+	engine.Evaluate(context.Background(), time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	r := chi.NewRouter()
+	h := Handler{alerts: engine}
+	r.Get("/alerts", h.ListAlertsHandler)
+	r.Get("/alerts/history", h.AlertHistoryHandler)
+	r.Post("/alerts/{id}/ack", h.AckAlertHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	client := resty.New()
+
+	resp, err := client.R().Get(srv.URL + "/alerts")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	var alerts []alerting.Alert
+	require.NoError(t, json.Unmarshal(resp.Body(), &alerts))
+	require.Len(t, alerts, 1)
+	assert.Equal(t, 99.0, alerts[0].Value)
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedCode   int
+		expectedEvents int
+	}{
+		{name: "all", query: "", expectedCode: http.StatusOK, expectedEvents: 1},
+		{name: "by rule", query: "?rule=api-high&from=2024-01-01T00:00:00Z&to=2024-01-02T00:00:00Z&limit=5", expectedCode: http.StatusOK, expectedEvents: 1},
+		{name: "other rule", query: "?rule=other", expectedCode: http.StatusOK},
+		{name: "before period", query: "?to=2024-01-01T00:00:00Z", expectedCode: http.StatusOK},
+		{name: "negative: bad time", query: "?from=yesterday", expectedCode: http.StatusBadRequest},
+		{name: "negative: bad limit", query: "?limit=many", expectedCode: http.StatusBadRequest},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.R().Get(srv.URL + "/alerts/history" + tt.query)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode())
+			if tt.expectedCode != http.StatusOK {
+				return
+			}
+			var events []alerting.Event
+			require.NoError(t, json.Unmarshal(resp.Body(), &events))
+			assert.Len(t, events, tt.expectedEvents)
+		})
+	}
+
+	ackCases := []struct {
+		name         string
+		id           string
+		body         string
+		expectedCode int
+	}{
+		{name: "positive", id: alerts[0].ID, body: `{"by":"admin"}`, expectedCode: http.StatusOK},
+		{name: "positive: no body", id: alerts[0].ID, expectedCode: http.StatusOK},
+		{name: "negative: unknown alert", id: "unknown", expectedCode: http.StatusNotFound},
+		{name: "negative: bad body", id: alerts[0].ID, body: "{", expectedCode: http.StatusBadRequest},
+	}
+	for _, tt := range ackCases {
+		t.Run("ack "+tt.name, func(t *testing.T) {
+			resp, err := client.R().SetBody(tt.body).Post(srv.URL + "/alerts/" + tt.id + "/ack")
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode())
+		})
+	}
+	acked := engine.Alerts()
+	require.Len(t, acked, 1)
+	assert.Equal(t, "admin", acked[0].AckedBy)
+	assert.NotNil(t, acked[0].AckedAt)
+}
+
+func TestReloadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules":[{"name":"high","condition":"threshold","metric":"Alloc","op":">","threshold":10}]}`), 0666))
+	engine := alerting.New(nil, nil, zap.NewNop().Sugar())
+	engine.SetRuleFile(path, alerting.EmailConfig{})
+
+	r := chi.NewRouter()
+	h := Handler{alerts: engine}
+	r.Post("/admin/alerts/reload", h.ReloadRulesHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	client := resty.New()
+
+	resp, err := client.R().Post(srv.URL + "/admin/alerts/reload")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	var rules []alerting.Rule
+	require.NoError(t, json.Unmarshal(resp.Body(), &rules))
+	require.Len(t, rules, 1)
+	assert.Equal(t, "high", rules[0].Name)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"rules":[{"name":"high"}]}`), 0666))
+	resp, err = client.R().Post(srv.URL + "/admin/alerts/reload")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	assert.Len(t, engine.Rules(), 1)
+
+	require.NoError(t, os.Remove(path))
+	resp, err = client.R().Post(srv.URL + "/admin/alerts/reload")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode())
+}
+
+func TestSLOs(t *testing.T) {
+	file, err := alerting.ParseRuleFile([]byte(`{"slos":[{"name":"search","good":"SearchGood","total":"SearchTotal","objective":0.995,"window":"168h"}]}`))
+	require.NoError(t, err)
+	engine := alerting.New(nil, nil, zap.NewNop().Sugar())
+	engine.Reload(file, nil)
+
+	r := chi.NewRouter()
+	h := Handler{alerts: engine}
+	r.Get("/slos", h.ListSLOsHandler)
+	r.Get("/", h.ShowMetricsHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	client := resty.New()
+
+	resp, err := client.R().Get(srv.URL + "/slos")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	var slos []alerting.SLOStatus
+	require.NoError(t, json.Unmarshal(resp.Body(), &slos))
+	require.Len(t, slos, 1)
+	assert.Equal(t, "search", slos[0].SLO.Name)
+	assert.Equal(t, 1.0, slos[0].ErrorBudgetRemaining)
+
+	resp, err = client.R().Get(srv.URL + "/")
+	require.NoError(t, err)
+	assert.Contains(t, string(resp.Body()), "<h3>search</h3>")
+	assert.Contains(t, string(resp.Body()), "error budget remaining 100.0%")
+
+	h.alerts = nil
+	resp, err = client.R().Get(srv.URL + "/slos")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode())
+}
@@ -6,12 +6,15 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha256"
+	"crypto/subtle"
 	"crypto/x509"
 	"database/sql"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	collector2 "github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerting"
 	"github.com/go-chi/chi/v5"
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"html/template"
@@ -20,22 +23,25 @@ import (
 	"net/http"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 // SaveMetricHandler - a method for saving metric from url.
+// Gauge operation (set, add or sub) can be passed in "op" query parameter.
 func (h *Handler) SaveMetricHandler(w http.ResponseWriter, r *http.Request) {
 
 	metricType := chi.URLParam(r, "type")
 	metricName := chi.URLParam(r, "name")
 	metricValue := chi.URLParam(r, "value")
 
-	if err := collector2.Collector().Collect(
+	if err := collector2.Collector().CollectFrom(agentID(r),
 		collector2.MetricRequest{
 			ID:    metricName,
 			MType: metricType,
+			Op:    r.URL.Query().Get("op"),
 		}, metricValue); err != nil {
-		w.WriteHeader(h.getStatusOnError(err))
+		h.writeSaveError(w, err)
 		return
 	}
 
@@ -75,9 +81,9 @@ func (h *Handler) SaveMetricFromJSONHandler(w http.ResponseWriter, r *http.Reque
 	}
 
 	// save metric
-	resultJSON, err := h.collectMetric(metric)
+	resultJSON, err := h.collectMetric(agentID(r), metric)
 	if err != nil {
-		w.WriteHeader(h.getStatusOnError(err))
+		h.writeSaveError(w, err)
 		return
 	}
 
@@ -104,12 +110,26 @@ func (h *Handler) SaveListMetricsFromJSONHandler(w http.ResponseWriter, r *http.
 		return
 	}
 
+	// save all metrics from request; limits are checked for the whole batch before any metric is saved
+	values := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		value, err := requestValue(metric)
+		if err != nil {
+			h.writeSaveError(w, err)
+			return
+		}
+		values = append(values, value)
+	}
+	c := collector2.Collector()
+	if err := c.CollectAllFrom(agentID(r), metrics, values); err != nil {
+		h.writeSaveError(w, err)
+		return
+	}
 	var results []byte
-	// save all metrics from request
 	for _, metric := range metrics {
-		resultJSON, err := h.collectMetric(metric)
+		resultJSON, err := c.GetMetricJSON(metric.ID)
 		if err != nil {
-			w.WriteHeader(h.getStatusOnError(err))
+			h.writeSaveError(w, err)
 			return
 		}
 		results = append(results, resultJSON...)
@@ -143,12 +163,18 @@ func (h *Handler) GetMetricFromJSONHandler(w http.ResponseWriter, r *http.Reques
 		w.WriteHeader(h.getStatusOnError(err))
 		return
 	}
+	if hideStale(r) && collector2.Collector().IsStale(metric.ID) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
 	// get metric value
 	switch metric.MType {
 	case collector2.Counter:
 		metric.Delta = resultJSON.CounterValue
 	case collector2.Gauge:
 		metric.Value = resultJSON.GaugeValue
+	case collector2.Histogram:
+		metric.Histogram = resultJSON.HistogramValue
 	}
 	answer, err := json.Marshal(metric)
 	if err != nil {
@@ -170,7 +196,7 @@ func (h *Handler) GetMetricHandler(w http.ResponseWriter, r *http.Request) {
 	metricType := chi.URLParam(r, "type")
 	metricName := chi.URLParam(r, "name")
 
-	if metricType != collector2.Counter && metricType != collector2.Gauge {
+	if metricType != collector2.Counter && metricType != collector2.Gauge && metricType != collector2.Histogram {
 		w.WriteHeader(http.StatusNotImplemented)
 		return
 	}
@@ -180,8 +206,12 @@ func (h *Handler) GetMetricHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(h.getStatusOnError(err))
 		return
 	}
+	if hideStale(r) && collector2.Collector().IsStale(metricName) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
 
-	if _, err = io.WriteString(w, *value.TextValue); err != nil {
+	if _, err = io.WriteString(w, value.Text()); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
@@ -189,7 +219,34 @@ func (h *Handler) GetMetricHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", "text/plain; charset=utf-8")
 }
 
+// GetQuantileHandler - a method for getting quantile estimation of histogram metric from url.
+func (h *Handler) GetQuantileHandler(w http.ResponseWriter, r *http.Request) {
+	metricName := chi.URLParam(r, "name")
+	q, err := strconv.ParseFloat(chi.URLParam(r, "q"), 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if hideStale(r) && collector2.Collector().IsStale(metricName) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	value, err := collector2.Collector().GetQuantile(metricName, q)
+	if err != nil {
+		w.WriteHeader(h.getStatusOnError(err))
+		return
+	}
+
+	if _, err = io.WriteString(w, strconv.FormatFloat(value, 'f', -1, 64)); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "text/plain; charset=utf-8")
+}
+
 // ShowMetricsHandler - a method for getting all available metrics from server.
+// When alerting defines SLOs, the page also shows their SLI and remaining error budget.
 func (h *Handler) ShowMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", "Content-Type: text/html; charset=utf-8")
 	if r.URL.Path != "/" {
@@ -200,11 +257,109 @@ func (h *Handler) ShowMetricsHandler(w http.ResponseWriter, r *http.Request) {
 	for _, n := range collector2.Collector().GetAvailableMetrics() {
 		page += fmt.Sprintf("<h1>	%s</h1>", n)
 	}
-	tmpl, _ := template.New("data").Parse("<h1>AVAILABLE METRICS</h1>{{range .}}<h3>{{ .}}</h3>{{end}}")
-	if err := tmpl.Execute(w, collector2.Collector().GetAvailableMetrics()); err != nil {
+	names := collector2.Collector().GetAvailableMetrics()
+	views := make([]metricView, 0, len(names))
+	for _, n := range names {
+		stale := collector2.Collector().IsStale(n)
+		if stale && hideStale(r) {
+			continue
+		}
+		md, _ := collector2.Collector().GetMetadata(n)
+		md.ID = n
+		views = append(views, metricView{MetricMetadata: md, Stale: stale})
+	}
+	tmpl, _ := template.New("data").Parse("<h1>AVAILABLE METRICS</h1>{{range .}}<h3>{{ .ID}}{{with .Unit}} ({{ .}}){{end}}{{if .Stale}} [stale]{{end}}</h3>" +
+		"{{with .Description}}<p>{{ .}}</p>{{end}}{{with .Owner}}<p>owner: {{ .}}</p>{{end}}{{end}}")
+	if err := tmpl.Execute(w, views); err != nil {
+		return
+	}
+	if h.alerts == nil {
+		return
+	}
+	if slos := h.alerts.SLOs(time.Now()); len(slos) != 0 {
+		percent := template.FuncMap{"percent": func(v float64) float64 { return v * 100 }}
+		sloTmpl, _ := template.New("slos").Funcs(percent).Parse("<h1>SLO</h1>{{range .}}<h3>{{ .SLO.Name}}</h3>" +
+			"<p>objective {{printf \"%.4g\" .SLO.Objective}} over {{ .SLO.Window}}, SLI {{printf \"%.4g\" .SLI}}, " +
+			"error budget remaining {{printf \"%.1f%%\" (percent .ErrorBudgetRemaining)}}</p>{{end}}")
+		if err := sloTmpl.Execute(w, slos); err != nil {
+			return
+		}
+	}
+}
+
+// RegisterMetadataHandler - a method for registering metric metadata from JSON body of http request.
+func (h *Handler) RegisterMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r.Body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var md collector2.MetricMetadata
+	if err := json.Unmarshal(buf.Bytes(), &md); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if err := collector2.Collector().RegisterMetadata(md); err != nil {
+		w.WriteHeader(h.getStatusOnError(err))
+		return
+	}
+
+	registered, _ := collector2.Collector().GetMetadata(md.ID)
+	answer, err := json.Marshal(registered)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if _, err = w.Write(answer); err != nil {
+		return
+	}
+}
+
+// ListMetadataHandler - a method for getting all registered metric metadata.
+func (h *Handler) ListMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	answer, err := json.Marshal(collector2.Collector().GetAllMetadata())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if _, err = w.Write(answer); err != nil {
+		return
+	}
+}
+
+// DeleteMetricHandler - a method for deleting metric by type and name from url.
+func (h *Handler) DeleteMetricHandler(w http.ResponseWriter, r *http.Request) {
+	metricType := chi.URLParam(r, "type")
+	metricName := chi.URLParam(r, "name")
+
+	if err := collector2.Collector().DeleteMetric(metricName, metricType); err != nil {
+		w.WriteHeader(h.getStatusOnError(err))
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ResetMetricHandler - a method for resetting metric value by type and name from url.
+func (h *Handler) ResetMetricHandler(w http.ResponseWriter, r *http.Request) {
+	metricType := chi.URLParam(r, "type")
+	metricName := chi.URLParam(r, "name")
+
+	if err := collector2.Collector().ResetMetric(metricName, metricType); err != nil {
+		w.WriteHeader(h.getStatusOnError(err))
+		return
+	}
+	resultJSON, err := collector2.Collector().GetMetricJSON(metricName)
+	if err != nil {
+		w.WriteHeader(h.getStatusOnError(err))
+		return
+	}
+	w.Header().Set("content-type", "application/json")
+	if _, err = w.Write(resultJSON); err != nil {
 		return
 	}
-	w.Header().Set("content-type", "Content-Type: text/html; charset=utf-8")
 }
 
 // CheckDatabaseAvailability выполняет проверку доступности базы данных (Ping).
@@ -250,6 +405,20 @@ func (h *Handler) CheckSubscriptionHandler(hh http.Handler) http.Handler {
 	return http.HandlerFunc(checkFn)
 }
 
+// CheckAdminHandler возвращает обработчик, который пропускает только запросы с токеном администратора
+// в заголовке "Authorization: Bearer <token>". Если токен не задан, все запросы отклоняются.
+func (h *Handler) CheckAdminHandler(hh http.Handler) http.Handler {
+	checkAdminFn := func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !h.isAdminToken(token) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		hh.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(checkAdminFn)
+}
+
 // CheckSubnetHandler возвращает обработчик, который проверяет, принадлежит ли входящий IP-адрес доверенной подсети.
 func (h *Handler) CheckSubnetHandler(hh http.Handler) http.Handler {
 	// Функция checkSubnetFn выполняет проверку подсети перед обработкой запроса.
@@ -270,32 +439,43 @@ func (h *Handler) CheckSubnetHandler(hh http.Handler) http.Handler {
 	return http.HandlerFunc(checkSubnetFn)
 }
 
-// collectMetric - метод для сохранения метрики.
-func (h *Handler) collectMetric(metric collector2.MetricRequest) ([]byte, error) {
-	c := collector2.Collector()
-
-	// get metric value
-	var metricValue string
-	switch metric.MType {
-	case collector2.Counter:
-		metricValue = strconv.Itoa(int(*metric.Delta))
-	case collector2.Gauge:
-		metricValue = strconv.FormatFloat(*metric.Value, 'f', 11, 64)
-	default:
-		return nil, collector2.ErrNotImplemented
+// collectMetric - метод для сохранения метрики, полученной от агента agent.
+func (h *Handler) collectMetric(agent string, metric collector2.MetricRequest) ([]byte, error) {
+	metricValue, err := requestValue(metric)
+	if err != nil {
+		return nil, err
 	}
 
 	// save metric
-	if err := c.Collect(metric, metricValue); err != nil {
+	c := collector2.Collector()
+	if err := c.CollectFrom(agent, metric, metricValue); err != nil {
 		return nil, err
 	}
 
 	// get saved metric in JSON format for response
-	resultJSON, err := c.GetMetricJSON(metric.ID)
-	if err != nil {
-		return nil, err
+	return c.GetMetricJSON(metric.ID)
+}
+
+// requestValue возвращает значение метрики из запроса в текстовом виде.
+// Неизвестный тип метрики отклоняется коллектором при сохранении.
+func requestValue(metric collector2.MetricRequest) (string, error) {
+	switch metric.MType {
+	case collector2.Counter:
+		if metric.Delta == nil {
+			return "", collector2.ErrBadRequest
+		}
+		return strconv.Itoa(int(*metric.Delta)), nil
+	case collector2.Gauge:
+		if metric.Value == nil {
+			return "", collector2.ErrBadRequest
+		}
+		return collector2.FormatGauge(*metric.Value), nil
+	case collector2.Histogram:
+		if metric.Histogram == nil {
+			return "", collector2.ErrBadRequest
+		}
 	}
-	return resultJSON, err
+	return "", nil
 }
 
 // checkSubscription - метод для проверки подписки и хеша.
@@ -317,15 +497,50 @@ func (h *Handler) getStatusOnError(err error) int {
 		collector2.ErrBadRequest:     http.StatusBadRequest,
 		collector2.ErrNotImplemented: http.StatusNotImplemented,
 		collector2.ErrNotFound:       http.StatusNotFound,
+		collector2.ErrConflict:       http.StatusConflict,
+		collector2.ErrLimitExceeded:  http.StatusTooManyRequests,
 	}
 
-	if statusCode, ok := statusCodes[err]; ok {
-		return statusCode
+	for target, statusCode := range statusCodes {
+		if errors.Is(err, target) {
+			return statusCode
+		}
 	}
 
 	return http.StatusInternalServerError
 }
 
+// writeSaveError - метод для ответа на ошибку сохранения метрики.
+// При превышении лимита причина отказа передается в теле ответа.
+func (h *Handler) writeSaveError(w http.ResponseWriter, err error) {
+	statusCode := h.getStatusOnError(err)
+	if statusCode == http.StatusTooManyRequests {
+		http.Error(w, err.Error(), statusCode)
+		return
+	}
+	w.WriteHeader(statusCode)
+}
+
+// agentID возвращает идентификатор агента для лимитов: адрес клиента без порта.
+// Заголовок X-Real-IP не учитывается, так как его задает сам клиент.
+func agentID(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// hideStale сообщает, запрошено ли скрытие устаревших метрик параметром запроса stale=hide.
+func hideStale(r *http.Request) bool {
+	return r.URL.Query().Get("stale") == "hide"
+}
+
+// isAdminToken - метод для проверки токена администратора.
+func (h *Handler) isAdminToken(token string) bool {
+	return h.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1
+}
+
 // getHash - метод для получения хеша из тела запроса.
 func (h *Handler) getHash(body []byte) string {
 	want := sha256.Sum256(body)
@@ -334,11 +549,13 @@ func (h *Handler) getHash(body []byte) string {
 }
 
 // New - функция создания нового экземпляра Handler.
-func New(db string, key string, cryptoKey string, trustedSubnet string) (*Handler, error) {
+func New(db string, key string, cryptoKey string, trustedSubnet string, adminToken string, alerts *alerting.Engine) (*Handler, error) {
 	handler := &Handler{
 		dbAddress:     db,
 		key:           key,
 		trustedSubnet: trustedSubnet,
+		adminToken:    adminToken,
+		alerts:        alerts,
 	}
 	if trustedSubnet != "" {
 		_, ipnet, err := net.ParseCIDR(trustedSubnet)
@@ -362,6 +579,12 @@ func New(db string, key string, cryptoKey string, trustedSubnet string) (*Handle
 	return handler, nil
 }
 
+// metricView - метрика на HTML-странице: описание и признак устаревания.
+type metricView struct {
+	collector2.MetricMetadata
+	Stale bool
+}
+
 // Handler - структура, представляющая обработчик запросов.
 // Она содержит методы для сохранения метрик, получения метрик, проверки доступности базы данных и другие.
 type Handler struct {
@@ -370,4 +593,6 @@ type Handler struct {
 	trustedIPNet  *net.IPNet // Добавьте новое поле для хранения IP-подсети
 	key           string
 	cryptoKey     *rsa.PrivateKey
+	adminToken    string
+	alerts        *alerting.Engine // движок оповещений, nil если правила не заданы
 }
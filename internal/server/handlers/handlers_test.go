@@ -4,12 +4,16 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestHandler_SaveListMetricsFromJSON(t *testing.T) {
@@ -51,7 +55,7 @@ func TestHandler_SaveListMetricsFromJSON(t *testing.T) {
 					MType:      "gauge",
 					ID:         "Gauge13",
 					GaugeValue: collector.PtrFloat64(13.1),
-					TextValue:  collector.PtrString("13.10000000000"),
+					TextValue:  collector.PtrString("13.1"),
 				},
 			},
 			expectedCode: http.StatusOK,
@@ -260,7 +264,7 @@ func TestSaveMetricFromJSON(t *testing.T) {
 				MType:      "gauge",
 				ID:         "Gauge1",
 				GaugeValue: collector.PtrFloat64(12.282),
-				TextValue:  collector.PtrString("12.28200000000"),
+				TextValue:  collector.PtrString("12.282"),
 			},
 			expectedCode: http.StatusOK,
 		},
@@ -275,6 +279,17 @@ func TestSaveMetricFromJSON(t *testing.T) {
 			expectedCode:   http.StatusNotImplemented,
 			expectedError:  collector.ErrNotImplemented,
 		},
+		{
+			name: "negative (summary type)",
+			request: collector.MetricRequest{
+				MType: "summary",
+				ID:    "Summary1",
+				Value: collector.PtrFloat64(0.5),
+			},
+			expectedMetric: collector.StoredMetric{},
+			expectedCode:   http.StatusNotImplemented,
+			expectedError:  collector.ErrNotFound,
+		},
 		{
 			name: "negative (invalid name)",
 			request: collector.MetricRequest{
@@ -286,11 +301,80 @@ func TestSaveMetricFromJSON(t *testing.T) {
 			expectedError: collector.ErrNotFound,
 		},
 		{
-			name: "negative (invalid gauge value)",
+			name: "positive (negative gauge)",
 			request: collector.MetricRequest{
 				MType: "gauge",
-				ID:    "invalidGauge",
-				Value: collector.PtrFloat64(-1.9),
+				ID:    "NegativeGauge",
+				Value: collector.PtrFloat64(-1.5),
+			},
+			expectedMetric: collector.StoredMetric{
+				MType:      "gauge",
+				ID:         "NegativeGauge",
+				GaugeValue: collector.PtrFloat64(-1.5),
+				TextValue:  collector.PtrString("-1.5"),
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "positive (gauge add)",
+			request: collector.MetricRequest{
+				MType: "gauge",
+				ID:    "NegativeGauge",
+				Value: collector.PtrFloat64(4),
+				Op:    "add",
+			},
+			expectedMetric: collector.StoredMetric{
+				MType:      "gauge",
+				ID:         "NegativeGauge",
+				GaugeValue: collector.PtrFloat64(2.5),
+				TextValue:  collector.PtrString("2.5"),
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "positive (gauge sub)",
+			request: collector.MetricRequest{
+				MType: "gauge",
+				ID:    "NegativeGauge",
+				Value: collector.PtrFloat64(3),
+				Op:    "sub",
+			},
+			expectedMetric: collector.StoredMetric{
+				MType:      "gauge",
+				ID:         "NegativeGauge",
+				GaugeValue: collector.PtrFloat64(-0.5),
+				TextValue:  collector.PtrString("-0.5"),
+			},
+			expectedCode: http.StatusOK,
+		},
+		{
+			name: "negative (invalid gauge op)",
+			request: collector.MetricRequest{
+				MType: "gauge",
+				ID:    "invalidGaugeOp",
+				Value: collector.PtrFloat64(1),
+				Op:    "mul",
+			},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: collector.ErrNotFound,
+		},
+		{
+			name: "negative (invalid counter value)",
+			request: collector.MetricRequest{
+				MType: "counter",
+				ID:    "invalidCounter",
+				Delta: collector.PtrInt64(-2),
+			},
+			expectedCode:  http.StatusBadRequest,
+			expectedError: collector.ErrNotFound,
+		},
+		{
+			name: "negative (op for counter)",
+			request: collector.MetricRequest{
+				MType: "counter",
+				ID:    "invalidCounterOp",
+				Delta: collector.PtrInt64(2),
+				Op:    "add",
 			},
 			expectedCode:  http.StatusBadRequest,
 			expectedError: collector.ErrNotFound,
@@ -382,10 +466,11 @@ func TestGetMetric(t *testing.T) {
 			expectedCode: http.StatusOK,
 		},
 		{
-			name:         "case3",
-			mType:        "gauge",
-			mName:        "Gauge2",
-			mValue:       "100500.278000100",
+			name:  "case3",
+			mType: "gauge",
+			mName: "Gauge2",
+			// значение gauge возвращается в каноническом виде, а не в том, в котором его передали
+			mValue:       "100500.2780001",
 			expectedCode: http.StatusOK,
 		},
 		{
@@ -423,6 +508,269 @@ func TestGetMetric(t *testing.T) {
 	}
 }
 
+func TestGetMetricDuringExpiry(t *testing.T) {
+	r := chi.NewRouter()
+	h := Handler{}
+	r.Get("/value/{type}/{name}", h.GetMetricHandler)
+	r.Get("/", h.ShowMetricsHandler)
+
+	c := collector.Collector()
+	c.SetExpiry(0, time.Hour)
+	defer c.SetExpiry(0, 0)
+	assert.NoError(t, c.Collect(collector.MetricRequest{ID: "ExpiryGauge", MType: "gauge"}, "1"))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			_ = c.Collect(collector.MetricRequest{ID: fmt.Sprintf("ExpiryGauge%d", i), MType: "gauge"}, "1")
+			c.Expire(time.Now())
+		}
+	}()
+	for i := 0; i < 200; i++ {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/value/gauge/ExpiryGauge", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "1", w.Body.String())
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	<-done
+}
+
+func TestGetQuantile(t *testing.T) {
+	r := chi.NewRouter()
+	h := Handler{}
+	r.Post("/update/", h.SaveMetricFromJSONHandler)
+	r.Post("/update/{type}/{name}/{value}", h.SaveMetricHandler)
+	r.Get("/quantile/{name}/{q}", h.GetQuantileHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	client := resty.New()
+	body, err := json.Marshal(collector.MetricRequest{
+		ID:        "QuantileLatency",
+		MType:     "histogram",
+		Histogram: &collector.HistogramValue{Count: 3, Sum: 3, Bounds: []float64{1, 2}, Buckets: []uint64{1, 1, 1}},
+	})
+	assert.NoError(t, err)
+	resp, err := client.R().SetBody(body).Post(fmt.Sprintf("%s/update/", srv.URL))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	resp, err = client.R().Post(fmt.Sprintf("%s/update/histogram/QuantileLatency/1.5", srv.URL))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	_, _ = client.R().Post(fmt.Sprintf("%s/update/gauge/QuantileGauge/1", srv.URL))
+
+	testCases := []struct {
+		name         string
+		mName        string
+		q            string
+		expectedCode int
+		expected     string
+	}{
+		{name: "median", mName: "QuantileLatency", q: "0.5", expectedCode: http.StatusOK, expected: "1.5"},
+		{name: "overflow bucket", mName: "QuantileLatency", q: "1", expectedCode: http.StatusOK, expected: "2"},
+		{name: "negative: bad quantile", mName: "QuantileLatency", q: "2", expectedCode: http.StatusBadRequest},
+		{name: "negative: not a number", mName: "QuantileLatency", q: "high", expectedCode: http.StatusBadRequest},
+		{name: "negative: not a histogram", mName: "QuantileGauge", q: "0.5", expectedCode: http.StatusNotFound},
+		{name: "negative: unknown metric", mName: "QuantileUnknown", q: "0.5", expectedCode: http.StatusNotFound},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.R().Get(fmt.Sprintf("%s/quantile/%s/%s", srv.URL, tt.mName, tt.q))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode())
+			if tt.expectedCode == http.StatusOK {
+				assert.Equal(t, tt.expected, string(resp.Body()))
+			}
+		})
+	}
+}
+
+func TestRegisterMetadata(t *testing.T) {
+	r := chi.NewRouter()
+	h := Handler{}
+	r.Post("/admin/metadata/", h.RegisterMetadataHandler)
+	r.Get("/admin/metadata/", h.ListMetadataHandler)
+	r.Post("/update/{type}/{name}/{value}", h.SaveMetricHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	client := resty.New()
+	testCases := []struct {
+		name         string
+		body         string
+		expectedCode int
+	}{
+		{name: "register", body: `{"id":"AdminTemperature","unit":"celsius","type":"gauge","owner":"ops"}`, expectedCode: http.StatusOK},
+		{name: "negative: type conflict", body: `{"id":"AdminTemperature","type":"counter"}`, expectedCode: http.StatusConflict},
+		{name: "negative: unknown type", body: `{"id":"AdminOther","type":"summary"}`, expectedCode: http.StatusBadRequest},
+		{name: "negative: bad body", body: `{"id":`, expectedCode: http.StatusBadRequest},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.R().SetBody(tt.body).Post(fmt.Sprintf("%s/admin/metadata/", srv.URL))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode())
+		})
+	}
+
+	resp, err := client.R().Post(fmt.Sprintf("%s/update/counter/AdminTemperature/1", srv.URL))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode())
+	resp, err = client.R().Post(fmt.Sprintf("%s/update/gauge/AdminTemperature/-3.5", srv.URL))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+
+	resp, err = client.R().Get(fmt.Sprintf("%s/admin/metadata/", srv.URL))
+	assert.NoError(t, err)
+	var list []collector.MetricMetadata
+	assert.NoError(t, json.Unmarshal(resp.Body(), &list))
+	assert.Contains(t, list, collector.MetricMetadata{ID: "AdminTemperature", Unit: "celsius", Type: "gauge", Owner: "ops"})
+}
+
+func TestDeleteAndResetMetric(t *testing.T) {
+	r := chi.NewRouter()
+	h := Handler{adminToken: "secret"}
+	r.Post("/update/{type}/{name}/{value}", h.SaveMetricHandler)
+	r.Get("/value/{type}/{name}", h.GetMetricHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(h.CheckAdminHandler)
+		r.Delete("/value/{type}/{name}", h.DeleteMetricHandler)
+		r.Post("/reset/{type}/{name}", h.ResetMetricHandler)
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	client := resty.New()
+	_, _ = client.R().Post(fmt.Sprintf("%s/update/counter/AdminCounter/15", srv.URL))
+	_, _ = client.R().Post(fmt.Sprintf("%s/update/gauge/AdminGauge/2.5", srv.URL))
+
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		token        string
+		expectedCode int
+	}{
+		{name: "negative: no token", method: http.MethodDelete, path: "/value/gauge/AdminGauge", expectedCode: http.StatusUnauthorized},
+		{name: "negative: wrong token", method: http.MethodPost, path: "/reset/counter/AdminCounter", token: "wrong", expectedCode: http.StatusUnauthorized},
+		{name: "reset counter", method: http.MethodPost, path: "/reset/counter/AdminCounter", token: "secret", expectedCode: http.StatusOK},
+		{name: "delete gauge", method: http.MethodDelete, path: "/value/gauge/AdminGauge", token: "secret", expectedCode: http.StatusOK},
+		{name: "negative: delete missing", method: http.MethodDelete, path: "/value/gauge/AdminGauge", token: "secret", expectedCode: http.StatusNotFound},
+		{name: "negative: reset with wrong type", method: http.MethodPost, path: "/reset/gauge/AdminCounter", token: "secret", expectedCode: http.StatusNotFound},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := client.R()
+			if tt.token != "" {
+				req.SetAuthToken(tt.token)
+			}
+			resp, err := req.Execute(tt.method, srv.URL+tt.path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedCode, resp.StatusCode())
+		})
+	}
+
+	resp, err := client.R().Get(fmt.Sprintf("%s/value/counter/AdminCounter", srv.URL))
+	assert.NoError(t, err)
+	assert.Equal(t, "0", string(resp.Body()))
+	resp, err = client.R().Get(fmt.Sprintf("%s/value/gauge/AdminGauge", srv.URL))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode())
+}
+
+func TestIngestLimits(t *testing.T) {
+	collector.Collector().SetLimits(collector.Limits{MaxSeriesPerAgent: 2})
+	defer collector.Collector().SetLimits(collector.Limits{})
+
+	r := chi.NewRouter()
+	h := Handler{}
+	r.Post("/update/{type}/{name}/{value}", h.SaveMetricHandler)
+	r.Post("/update/", h.SaveMetricFromJSONHandler)
+	r.Post("/updates/", h.SaveListMetricsFromJSONHandler)
+
+	testCases := []struct {
+		name         string
+		agent        string
+		realIP       string
+		path         string
+		body         string
+		expectedCode int
+	}{
+		{name: "first series", agent: "10.0.0.1", path: "/update/counter/LimitedCounter1/1", expectedCode: http.StatusOK},
+		{name: "existing series", agent: "10.0.0.1", path: "/update/counter/LimitedCounter1/1", expectedCode: http.StatusOK},
+		{name: "negative: batch over limit", agent: "10.0.0.1", path: "/updates/", body: `[{"id":"LimitedCounter2","type":"counter","delta":1},{"id":"LimitedCounter3","type":"counter","delta":1}]`, expectedCode: http.StatusTooManyRequests},
+		{name: "second series", agent: "10.0.0.1", path: "/update/counter/LimitedCounter2/1", expectedCode: http.StatusOK},
+		{name: "negative: new series over limit", agent: "10.0.0.1", path: "/update/counter/LimitedCounter3/1", expectedCode: http.StatusTooManyRequests},
+		{name: "negative: json over limit", agent: "10.0.0.1", path: "/update/", body: `{"id":"LimitedCounter3","type":"counter","delta":1}`, expectedCode: http.StatusTooManyRequests},
+		{name: "negative: spoofed X-Real-IP", agent: "10.0.0.1", realIP: "10.0.0.9", path: "/update/counter/LimitedCounter3/1", expectedCode: http.StatusTooManyRequests},
+		{name: "other agent", agent: "10.0.0.2", path: "/update/counter/LimitedCounter3/1", expectedCode: http.StatusOK},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.RemoteAddr = tt.agent + ":41000"
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			assert.Equal(t, tt.expectedCode, w.Code)
+			if tt.expectedCode == http.StatusTooManyRequests {
+				assert.Contains(t, w.Body.String(), "limit exceeded")
+			}
+		})
+	}
+
+	// отклоненный пакет не сохраняет ни одной метрики
+	value, err := collector.Collector().GetMetric("LimitedCounter2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), *value.CounterValue)
+}
+
+func TestPrometheus(t *testing.T) {
+	r := chi.NewRouter()
+	h := Handler{}
+	r.Post("/update/{type}/{name}/{value}", h.SaveMetricHandler)
+	r.Get("/metrics", h.PrometheusHandler)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	client := resty.New()
+	_, _ = client.R().Post(fmt.Sprintf("%s/update/counter/PromCounter/3", srv.URL))
+	_, _ = client.R().Post(fmt.Sprintf("%s/update/gauge/PromGauge/1.5", srv.URL))
+	_, _ = client.R().Post(fmt.Sprintf("%s/update/histogram/PromHistogram/0.3", srv.URL))
+	require.NoError(t, collector.Collector().RegisterMetadata(collector.MetricMetadata{ID: "PromCounter", Unit: "requests", Description: `C:\temp` + "\nrequests"}))
+	require.NoError(t, collector.Collector().RegisterMetadata(collector.MetricMetadata{ID: "PromGauge", Unit: "bytes"}))
+	selfmetrics.ObserveHTTP(http.MethodGet, "/metrics", http.StatusOK, 20*time.Millisecond)
+
+	resp, err := client.R().Get(srv.URL + "/metrics")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	for _, line := range []string{
+		`# HELP PromCounter C:\\temp\nrequests (unit: requests)` + "\n# TYPE PromCounter counter\nPromCounter 3\n",
+		"# HELP PromGauge (unit: bytes)\n# TYPE PromGauge gauge\nPromGauge 1.5\n",
+		"# TYPE PromHistogram histogram\n",
+		`PromHistogram_bucket{le="0.25"} 0` + "\n",
+		`PromHistogram_bucket{le="0.5"} 1` + "\n",
+		`PromHistogram_bucket{le="+Inf"} 1` + "\n",
+		"PromHistogram_sum 0.3\nPromHistogram_count 1\n",
+		"# TYPE server_http_requests_total counter\n",
+		`server_http_requests_total{method="GET",route="/metrics",status="200"} 1` + "\n",
+		`server_http_request_duration_seconds_bucket{method="GET",route="/metrics",le="0.025"} 1` + "\n",
+		"# TYPE server_series gauge\n",
+	} {
+		assert.Contains(t, string(resp.Body()), line)
+	}
+
+	resp, err = client.R().Post(fmt.Sprintf("%s/update/counter/server_fake/1", srv.URL))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+}
+
 func TestGetMetricFromJSON(t *testing.T) {
 	r := chi.NewRouter()
 	h := Handler{}
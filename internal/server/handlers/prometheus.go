@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"bufio"
+	collector2 "github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrometheusHandler - a method for exposing all metrics, including server's own metrics, in Prometheus text format.
+// Stale metrics are omitted with "stale=hide" query parameter.
+func (h *Handler) PrometheusHandler(w http.ResponseWriter, r *http.Request) {
+	selfmetrics.UpdateSeries()
+	c := collector2.Collector()
+	metrics := c.Snapshot()
+	if hideStale(r) {
+		kept := metrics[:0]
+		for _, m := range metrics {
+			if !c.IsStale(m.ID) {
+				kept = append(kept, m)
+			}
+		}
+		metrics = kept
+	}
+	metrics = append(metrics, selfmetrics.Requests()...)
+
+	w.Header().Set("content-type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	bw := bufio.NewWriter(w)
+	writePrometheus(bw, metrics)
+	_ = bw.Flush()
+}
+
+// writePrometheus записывает метрики в текстовом формате Prometheus.
+// Метрики с одним именем группируются, и для каждого имени выводятся строки TYPE и, если описание
+// или единица измерения зарегистрированы для метрики или для имени без меток, HELP. Метки из имени метрики вида name{a="1"} сохраняются.
+func writePrometheus(w *bufio.Writer, metrics []collector2.StoredMetric) {
+	type sample struct {
+		name, labels string
+		metric       collector2.StoredMetric
+	}
+	samples := make([]sample, 0, len(metrics))
+	for _, m := range metrics {
+		name, labels := splitMetricID(m.ID)
+		samples = append(samples, sample{name: name, labels: labels, metric: m})
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].name != samples[j].name {
+			return samples[i].name < samples[j].name
+		}
+		return samples[i].labels < samples[j].labels
+	})
+
+	var previous string
+	for _, s := range samples {
+		if s.name != previous {
+			previous = s.name
+			md, ok := collector2.Collector().GetMetadata(s.metric.ID)
+			if !ok {
+				md, ok = collector2.Collector().GetMetadata(s.name)
+			}
+			if help := helpText(md); ok && help != "" {
+				w.WriteString("# HELP " + s.name + " " + help + "\n")
+			}
+			w.WriteString("# TYPE " + s.name + " " + prometheusType(s.metric.MType) + "\n")
+		}
+		m := s.metric
+		switch {
+		case m.MType == collector2.Counter && m.CounterValue != nil:
+			writeSample(w, s.name, s.labels, strconv.FormatInt(*m.CounterValue, 10))
+		case m.MType == collector2.Gauge && m.GaugeValue != nil:
+			writeSample(w, s.name, s.labels, formatPrometheusFloat(*m.GaugeValue))
+		case m.MType == collector2.Histogram && m.HistogramValue != nil:
+			bounds, counts := m.HistogramValue.CumulativeBuckets()
+			for i, bound := range bounds {
+				le := `le="` + formatPrometheusFloat(bound) + `"`
+				if s.labels != "" {
+					le = s.labels + "," + le
+				}
+				writeSample(w, s.name+"_bucket", le, strconv.FormatUint(counts[i], 10))
+			}
+			writeSample(w, s.name+"_sum", s.labels, formatPrometheusFloat(m.HistogramValue.Sum))
+			writeSample(w, s.name+"_count", s.labels, strconv.FormatUint(m.HistogramValue.Count, 10))
+		}
+	}
+}
+
+// writeSample записывает одну строку значения name{labels} value.
+func writeSample(w *bufio.Writer, name, labels, value string) {
+	w.WriteString(name)
+	if labels != "" {
+		w.WriteString("{" + labels + "}")
+	}
+	w.WriteString(" " + value + "\n")
+}
+
+// splitMetricID разделяет имя метрики на имя, допустимое в Prometheus, и метки без фигурных скобок.
+func splitMetricID(id string) (string, string) {
+	var labels string
+	if i := strings.IndexByte(id, '{'); i >= 0 && strings.HasSuffix(id, "}") {
+		id, labels = id[:i], id[i+1:len(id)-1]
+	}
+	name := []byte(id)
+	for i, b := range name {
+		if !(b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b == '_' || b == ':' || i > 0 && b >= '0' && b <= '9') {
+			name[i] = '_'
+		}
+	}
+	return string(name), labels
+}
+
+// prometheusType возвращает тип метрики Prometheus для типа метрики сервера.
+func prometheusType(mtype string) string {
+	switch mtype {
+	case collector2.Counter, collector2.Gauge, collector2.Histogram:
+		return mtype
+	default:
+		return "untyped"
+	}
+}
+
+// helpEscaper экранирует обратную косую черту и перевод строки в тексте HELP.
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// helpText возвращает текст HELP по описанию метрики: описание и единицу измерения в скобках.
+func helpText(md collector2.MetricMetadata) string {
+	help := md.Description
+	if md.Unit != "" {
+		help = strings.TrimSpace(help + " (unit: " + md.Unit + ")")
+	}
+	return helpEscaper.Replace(help)
+}
+
+// formatPrometheusFloat форматирует число в текстовом формате Prometheus.
+func formatPrometheusFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
@@ -3,6 +3,8 @@
 package logger
 
 import (
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 	"net/http"
 	"time"
@@ -10,7 +12,8 @@ import (
 
 var SugarLogger zap.SugaredLogger
 
-// RequestLogger возвращает обработчик HTTP запросов, который выполняет логирование.
+// RequestLogger возвращает обработчик HTTP запросов, который выполняет логирование
+// и учитывает запрос в собственных метриках сервера по шаблону маршрута и коду ответа.
 func RequestLogger(h http.Handler) http.Handler {
 	logFn := func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -23,6 +26,7 @@ func RequestLogger(h http.Handler) http.Handler {
 		h.ServeHTTP(&lw, r)
 
 		duration := time.Since(start)
+		selfmetrics.ObserveHTTP(r.Method, routePattern(r), statusOrOK(rd.status), duration)
 		SugarLogger.Infoln(
 			"uri", r.RequestURI,
 			"method", r.Method,
@@ -37,6 +41,22 @@ func RequestLogger(h http.Handler) http.Handler {
 	return http.HandlerFunc(logFn)
 }
 
+// routePattern возвращает шаблон маршрута chi, обработавшего запрос, чтобы число серий не зависело от URL.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return "unmatched"
+}
+
+// statusOrOK возвращает код ответа; если обработчик не вызвал WriteHeader, ответ отправлен с кодом 200.
+func statusOrOK(status int) int {
+	if status == 0 {
+		return http.StatusOK
+	}
+	return status
+}
+
 // Write записывает данные в http.ResponseWriter и обновляет данные о размере ответа.
 func (r *loggingResponseWriter) Write(b []byte) (int, error) {
 	size, err := r.ResponseWriter.Write(b)
@@ -5,6 +5,7 @@ package router
 import (
 	"fmt"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerting"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/server/handlers"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/server/middlewares/compressor"
 	log "github.com/ZnNr/go-musthave-metrics.git/internal/server/middlewares/logger"
@@ -12,12 +13,15 @@ import (
 )
 
 // New возвращает новый экземпляр маршрутизатора с настроенными обработчиками для обработки HTTP запросов.
-func New(params flags.Params) (*chi.Mux, error) {
+// alerts - движок оповещений для API оповещений и заглушек, nil если правила оповещений не заданы.
+func New(params flags.Params, alerts *alerting.Engine) (*chi.Mux, error) {
 	handler, err := handlers.New(
 		params.DatabaseAddress,
 		params.Key,
 		params.CryptoKeyPath,
 		params.TrustedSubnet,
+		params.AdminToken,
+		alerts,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error while creating handler: %v", err)
@@ -33,9 +37,33 @@ func New(params flags.Params) (*chi.Mux, error) {
 	r.Post("/value/", handler.GetMetricFromJSONHandler)
 	r.Post("/update/{type}/{name}/{value}", handler.SaveMetricHandler)
 	r.Get("/value/{type}/{name}", handler.GetMetricHandler)
+	r.Get("/quantile/{name}/{q}", handler.GetQuantileHandler)
 	r.Get("/", handler.ShowMetricsHandler)
+	r.Get("/metrics", handler.PrometheusHandler)
 	r.Get("/ping", handler.CheckDatabaseAvailability)
 	r.Post("/updates/", handler.SaveListMetricsFromJSONHandler)
+	r.Get("/admin/metadata/", handler.ListMetadataHandler)
+	if params.OpenMetadata {
+		// описания регистрируются без токена только при явном разрешении, так как зарегистрированный тип
+		// заставляет сервер отклонять записи метрики с другим типом
+		r.Post("/admin/metadata/", handler.RegisterMetadataHandler)
+	}
+	r.Get("/admin/silences/", handler.ListSilencesHandler)
+	r.Get("/alerts", handler.ListAlertsHandler)
+	r.Get("/alerts/history", handler.AlertHistoryHandler)
+	r.Get("/slos", handler.ListSLOsHandler)
+	r.Group(func(r chi.Router) {
+		r.Use(handler.CheckAdminHandler)
+		if !params.OpenMetadata {
+			r.Post("/admin/metadata/", handler.RegisterMetadataHandler)
+		}
+		r.Delete("/value/{type}/{name}", handler.DeleteMetricHandler)
+		r.Post("/reset/{type}/{name}", handler.ResetMetricHandler)
+		r.Post("/admin/silences/", handler.CreateSilenceHandler)
+		r.Delete("/admin/silences/{id}", handler.ExpireSilenceHandler)
+		r.Post("/alerts/{id}/ack", handler.AckAlertHandler)
+		r.Post("/admin/alerts/reload", handler.ReloadRulesHandler)
+	})
 
 	return r, nil
 }
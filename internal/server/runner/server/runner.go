@@ -3,21 +3,26 @@ package server
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerting"
 	serverGRPC "github.com/ZnNr/go-musthave-metrics.git/internal/server/grpc"
 	log "github.com/ZnNr/go-musthave-metrics.git/internal/server/middlewares/logger"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/server/router"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/server/saver/database"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/server/saver/file"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/selfmetrics"
 	pb "github.com/ZnNr/go-musthave-metrics.git/proto"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"io"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 )
@@ -28,6 +33,14 @@ const (
 	buildVersion string = "N/A"
 	buildDate    string = "N/A"
 	buildCommit  string = "N/A"
+	// Интервал проверки устаревших метрик
+	expireInterval = time.Second
+
+	// Ключи состояния сервера в хранилище
+	lastSeenStateKey = "last_seen"
+	metadataStateKey = "metadata"
+	alertsStateKey   = "alerts"
+	silencesStateKey = "silences"
 )
 
 // Runner Структура, представляющая собой главный компонент приложения сервера.
@@ -36,6 +49,7 @@ type Runner struct {
 	metricsInterval time.Duration
 	isRestore       bool
 	storeInterval   int
+	expiryEnabled   bool
 	tlsKey          string
 	appSrv          httpServer
 	pprofSrv        httpServer
@@ -43,6 +57,8 @@ type Runner struct {
 	listener        listener
 	logger          *zap.SugaredLogger
 	signals         chan os.Signal
+	alerts          *alerting.Engine
+	alertInterval   time.Duration
 }
 
 // New создает экземпляр Runner с использованием параметров flags.Params.
@@ -61,19 +77,68 @@ func New(params *flags.Params) *Runner {
 	if err != nil {
 		log.SugarLogger.Fatalw(err.Error(), "error", "init metrics saver")
 	}
+	var alerts *alerting.Engine
+	if params.AlertRules != "" {
+		if params.AlertInterval <= 0 {
+			log.SugarLogger.Fatalw(fmt.Sprintf("alert interval must be positive, got %d", params.AlertInterval), "error", "init alerting")
+		}
+		smtp := alerting.EmailConfig{
+			Addr:      params.AlertSMTPAddr,
+			Username:  params.AlertSMTPUsername,
+			Password:  params.AlertSMTPPassword,
+			StartTLS:  params.AlertSMTPStartTLS,
+			From:      params.AlertEmailFrom,
+			To:        strings.Split(params.AlertEmailTo, ","),
+			Templates: params.AlertEmailTemplate,
+		}
+		// Получатели из флагов - получатели по умолчанию, именованные получатели задаются в файле правил.
+		var notifiers alerting.Notifiers
+		if params.AlertWebhook != "" {
+			notifiers = append(notifiers, alerting.NewWebhook(params.AlertWebhook))
+		}
+		if params.AlertSMTPAddr != "" && params.AlertEmailTo != "" {
+			email, err := alerting.NewEmail(smtp)
+			if err != nil {
+				log.SugarLogger.Fatalw(err.Error(), "error", "creating email notifier")
+			}
+			notifiers = append(notifiers, email)
+		}
+		var notifier alerting.Notifier
+		if len(notifiers) != 0 {
+			notifier = notifiers
+		}
+		// Загрузка правил оповещений; тот же файл перечитывается по SIGHUP и через API администратора.
+		alerts = alerting.New(nil, notifier, &log.SugarLogger)
+		alerts.SetRuleFile(params.AlertRules, smtp)
+		if err = alerts.ReloadRules(); err != nil {
+			log.SugarLogger.Fatalw(err.Error(), "error", "loading alert rules")
+		}
+		alerts.SetHistory(saver)
+	}
 	// Инициализация роутера.
-	r, err := router.New(*params)
+	r, err := router.New(*params, alerts)
 	if err != nil {
 		log.SugarLogger.Fatalw(err.Error(), "error", "creating router")
 	}
+	collector.Collector().SetExpiry(
+		time.Duration(params.StaleTTL)*time.Second,
+		time.Duration(params.DeleteTTL)*time.Second,
+	)
+	collector.Collector().SetLimits(collector.Limits{
+		MaxSeries:         params.MaxSeries,
+		MaxSeriesPerAgent: params.MaxSeriesPerAgent,
+		Rate:              params.IngestRate,
+		RatePerAgent:      params.IngestRatePerAgent,
+	})
 	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGHUP)
 
 	runner := &Runner{
 		saver:           saver,
 		metricsInterval: time.Duration(params.StoreInterval),
 		isRestore:       params.Restore,
 		storeInterval:   params.StoreInterval,
+		expiryEnabled:   params.StaleTTL > 0 || params.DeleteTTL > 0,
 		tlsKey:          params.CryptoKeyPath,
 		appSrv: &http.Server{
 			Addr:    params.FlagRunAddr,
@@ -83,14 +148,16 @@ func New(params *flags.Params) *Runner {
 			Addr:    pprofAddr,
 			Handler: nil,
 		},
-		signals: sigs,
-		logger:  &log.SugarLogger,
+		signals:       sigs,
+		logger:        &log.SugarLogger,
+		alerts:        alerts,
+		alertInterval: time.Duration(params.AlertInterval) * time.Second,
 	}
 	if !params.DisableGrpc {
 		// Создание gRPC сервера.
-		s := grpc.NewServer()
+		s := grpc.NewServer(grpc.UnaryInterceptor(serverGRPC.UnaryInterceptor))
 		// Регистрация gRPC сервера.
-		pb.RegisterMetricsServer(s, &serverGRPC.MetricsServer{})
+		pb.RegisterMetricsServer(s, &serverGRPC.MetricsServer{AdminToken: params.AdminToken, Alerts: alerts})
 
 		listen, err := net.Listen("tcp", params.GrpcRunAddr)
 		if err != nil {
@@ -111,17 +178,32 @@ func (r *Runner) Run(ctx context.Context) {
 
 	// Восстановление предыдущих метрик, если необходимо.
 	if r.isRestore {
+		start := time.Now()
 		metrics, err := r.saver.Restore(ctx)
+		selfmetrics.ObserveSaver("restore", time.Since(start), err)
 		if err != nil {
 			r.logger.Error(err.Error(), "restore error")
 		}
-		collector.Collector().Metrics = metrics
+		collector.Collector().Restore(metrics)
+		if err = r.restoreState(ctx); err != nil {
+			r.logger.Error(err.Error(), "restore state error")
+		}
 		r.logger.Info("metrics restored")
 	}
 
 	// Регулярное сохранение метрик.
 	go r.saveMetrics(ctx, r.storeInterval)
 
+	// Удаление устаревших метрик.
+	if r.expiryEnabled {
+		go r.expireMetrics(ctx)
+	}
+
+	// Вычисление правил оповещений.
+	if r.alerts != nil {
+		go r.alerts.Run(ctx, r.alertInterval)
+	}
+
 	// Запуск pprof.
 	go func() {
 		if err := r.pprofSrv.ListenAndServe(); err != nil {
@@ -129,12 +211,16 @@ func (r *Runner) Run(ctx context.Context) {
 		}
 	}()
 
-	// Обработка сигналов.
+	// Обработка сигналов: SIGHUP перечитывает правила оповещений, остальные завершают работу.
 	go func() {
 		sig := <-r.signals
+		for sig == syscall.SIGHUP {
+			r.reloadRules()
+			sig = <-r.signals
+		}
 		r.logger.Info(fmt.Sprintf("got signal: %s", sig.String()))
 		// save metrics
-		if err := r.saver.Save(ctx, collector.Collector().Metrics); err != nil {
+		if err := r.store(ctx); err != nil {
 			r.logger.Error(err.Error(), "save error")
 		} else {
 			r.logger.Info("metrics was successfully saved")
@@ -166,21 +252,159 @@ func (r *Runner) Run(ctx context.Context) {
 	}
 }
 
-// saveMetrics сохраняет метрики с указанным интервалом.
+// reloadRules перечитывает файл правил оповещений; при ошибке продолжают действовать прежние правила.
+func (r *Runner) reloadRules() {
+	if r.alerts == nil {
+		r.logger.Info("alerting is not configured, nothing to reload")
+		return
+	}
+	if err := r.alerts.ReloadRules(); err != nil {
+		r.logger.Errorw(err.Error(), "event", "reload alert rules")
+	}
+}
+
+// CheckRules проверяет файл правил оповещений и пишет в w число правил и получателей.
+// Используется режимом -check-rules для проверки файла до развертывания.
+func CheckRules(path string, w io.Writer) error {
+	file, err := alerting.LoadRuleFile(path)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s: %d rules, %d receivers OK\n", path, len(file.Rules), len(file.Receivers))
+	return err
+}
+
+// saveMetrics сохраняет метрики с интервалом interval в секундах.
+// При неположительном интервале метрики сохраняются только при завершении работы.
 func (r *Runner) saveMetrics(ctx context.Context, interval int) {
-	ticker := time.NewTicker(time.Duration(interval))
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := r.saver.Save(ctx, collector.Collector().Metrics); err != nil {
+			if err := r.store(ctx); err != nil {
 				r.logger.Error(err.Error(), "save error")
 			}
 		}
 	}
 }
 
+// store удаляет из хранилища удаленные метрики и сохраняет текущее состояние метрик.
+// Если удаление не удалось, имена метрик возвращаются в список и удаляются при следующем сохранении,
+// а состояние метрик все равно сохраняется.
+func (r *Runner) store(ctx context.Context) error {
+	if ids := collector.Collector().DrainTombstones(); len(ids) != 0 {
+		start := time.Now()
+		err := r.saver.Delete(ctx, ids)
+		selfmetrics.ObserveSaver("delete", time.Since(start), err)
+		if err != nil {
+			collector.Collector().RestoreTombstones(ids)
+			r.logger.Error(err.Error(), "delete error")
+		}
+	}
+	selfmetrics.UpdateSeries()
+	start := time.Now()
+	err := r.saver.Save(ctx, collector.Collector().Snapshot())
+	if err == nil {
+		err = r.storeState(ctx)
+	}
+	selfmetrics.ObserveSaver("save", time.Since(start), err)
+	return err
+}
+
+// storeState сохраняет время последнего обновления метрик и агентов, описания метрик, состояние оповещений
+// и заглушки, чтобы оповещения об отсутствии данных, описания и заглушки не сбрасывались при перезапуске.
+func (r *Runner) storeState(ctx context.Context) error {
+	lastSeen, err := json.Marshal(collector.Collector().LastSeen())
+	if err != nil {
+		return err
+	}
+	if err = r.saver.SaveState(ctx, lastSeenStateKey, lastSeen); err != nil {
+		return err
+	}
+	metadata, err := json.Marshal(collector.Collector().GetAllMetadata())
+	if err != nil {
+		return err
+	}
+	if err = r.saver.SaveState(ctx, metadataStateKey, metadata); err != nil {
+		return err
+	}
+	if r.alerts == nil {
+		return nil
+	}
+	alerts, err := r.alerts.State()
+	if err != nil {
+		return err
+	}
+	if err = r.saver.SaveState(ctx, alertsStateKey, alerts); err != nil {
+		return err
+	}
+	silences, err := r.alerts.SilencesState()
+	if err != nil {
+		return err
+	}
+	return r.saver.SaveState(ctx, silencesStateKey, silences)
+}
+
+// restoreState восстанавливает состояние, сохраненное storeState.
+func (r *Runner) restoreState(ctx context.Context) error {
+	data, err := r.saver.RestoreState(ctx, lastSeenStateKey)
+	if err != nil {
+		return err
+	}
+	if len(data) != 0 {
+		var lastSeen collector.LastSeenState
+		if err = json.Unmarshal(data, &lastSeen); err != nil {
+			return fmt.Errorf("error while parsing last seen state: %w", err)
+		}
+		collector.Collector().RestoreLastSeen(lastSeen)
+	}
+	if data, err = r.saver.RestoreState(ctx, metadataStateKey); err != nil {
+		return err
+	}
+	if len(data) != 0 {
+		var metadata []collector.MetricMetadata
+		if err = json.Unmarshal(data, &metadata); err != nil {
+			return fmt.Errorf("error while parsing metadata state: %w", err)
+		}
+		collector.Collector().RestoreMetadata(metadata)
+	}
+	if r.alerts == nil {
+		return nil
+	}
+	if data, err = r.saver.RestoreState(ctx, alertsStateKey); err != nil {
+		return err
+	}
+	if err = r.alerts.Restore(data); err != nil {
+		return err
+	}
+	if data, err = r.saver.RestoreState(ctx, silencesStateKey); err != nil {
+		return err
+	}
+	return r.alerts.RestoreSilences(data)
+}
+
+// expireMetrics периодически удаляет метрики, которые не обновлялись дольше заданного времени.
+func (r *Runner) expireMetrics(ctx context.Context) {
+	ticker := time.NewTicker(expireInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if deleted := collector.Collector().Expire(now); len(deleted) != 0 {
+				r.logger.Infof("stale metrics deleted: %v", deleted)
+			}
+		}
+	}
+}
+
 // initSaver инициализирует saver (файл или базу данных) в зависимости от параметров.
 func initSaver(params *flags.Params) (saver, error) {
 	if params.DatabaseAddress != "" {
@@ -207,8 +431,12 @@ func initFileSaver(fileStoragePath string) saver {
 
 //go:generate mockery --inpackage --disable-version-string --filename saver_mock.go --name saver
 type saver interface {
+	alerting.HistoryStore
+	Delete(ctx context.Context, ids []string) error
 	Restore(ctx context.Context) ([]collector.StoredMetric, error)
 	Save(ctx context.Context, metrics []collector.StoredMetric) error
+	SaveState(ctx context.Context, key string, data []byte) error
+	RestoreState(ctx context.Context, key string) ([]byte, error)
 }
 
 //go:generate mockery --inpackage --disable-version-string --filename http_server_mock.go --name httpServer
@@ -1,15 +1,21 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/flags"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerting"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
 	"syscall"
 	"testing"
 	"time"
@@ -20,6 +26,10 @@ func TestRunner_Run(t *testing.T) {
 		mockedSaver := newMockSaver(t)
 		mockedSaver.On("Restore", mock.Anything).Return([]collector.StoredMetric{}, nil)
 		mockedSaver.On("Save", mock.Anything, mock.AnythingOfType("[]collector.StoredMetric")).Return(nil)
+		mockedSaver.On("RestoreState", mock.Anything, "last_seen").Return(nil, nil)
+		mockedSaver.On("SaveState", mock.Anything, "last_seen", mock.AnythingOfType("[]uint8")).Return(nil)
+		mockedSaver.On("RestoreState", mock.Anything, "metadata").Return(nil, nil)
+		mockedSaver.On("SaveState", mock.Anything, "metadata", mock.AnythingOfType("[]uint8")).Return(nil)
 
 		mockedAppServer := newMockServer(t)
 		mockedAppServer.On("ListenAndServe").Return(nil)
@@ -56,6 +66,10 @@ func TestRunner_Run(t *testing.T) {
 		mockedSaver := newMockSaver(t)
 		mockedSaver.On("Restore", mock.Anything).Return([]collector.StoredMetric{}, nil)
 		mockedSaver.On("Save", mock.Anything, mock.AnythingOfType("[]collector.StoredMetric")).Return(nil)
+		mockedSaver.On("RestoreState", mock.Anything, "last_seen").Return(nil, nil)
+		mockedSaver.On("SaveState", mock.Anything, "last_seen", mock.AnythingOfType("[]uint8")).Return(nil)
+		mockedSaver.On("RestoreState", mock.Anything, "metadata").Return(nil, nil)
+		mockedSaver.On("SaveState", mock.Anything, "metadata", mock.AnythingOfType("[]uint8")).Return(nil)
 
 		mockedAppServer := newMockServer(t)
 		mockedAppServer.On("ListenAndServe").Return(nil)
@@ -85,6 +99,7 @@ func TestRunner_Run(t *testing.T) {
 		}
 		go r.Run(ctx)
 		time.Sleep(3 * time.Second)
+		r.signals <- syscall.SIGHUP
 		r.signals <- syscall.SIGTERM
 	})
 }
@@ -119,3 +134,88 @@ func TestNew(t *testing.T) {
 		assert.Equal(t, r.storeInterval, expected.storeInterval)
 	})
 }
+
+func TestRunner_State(t *testing.T) {
+	alerts := `{"alerts":[{"id":"9f19836c067c525f","rule":"silent","labels":{"agent":"10.5.0.1"},"state":"firing","value":120,"summary":"","active_at":"2024-01-01T00:00:00Z"}]}`
+	metadata := `[{"id":"PollCount","unit":"polls","description":"Number of polls","type":"counter"}]`
+	silences := `[{"id":"0a1b2c","metric":"Poll*","starts_at":"2024-01-01T00:00:00Z","ends_at":"2099-01-01T00:00:00Z","comment":"deploy"}]`
+	mockedSaver := newMockSaver(t)
+	mockedSaver.On("RestoreState", mock.Anything, "last_seen").Return([]byte(`{"metrics":{},"agents":{"10.5.0.1":"2024-01-01T00:00:00Z"}}`), nil)
+	mockedSaver.On("RestoreState", mock.Anything, "metadata").Return([]byte(metadata), nil)
+	mockedSaver.On("RestoreState", mock.Anything, "alerts").Return([]byte(alerts), nil)
+	mockedSaver.On("RestoreState", mock.Anything, "silences").Return([]byte(silences), nil)
+	mockedSaver.On("SaveState", mock.Anything, "last_seen", mock.AnythingOfType("[]uint8")).Return(nil)
+	mockedSaver.On("SaveState", mock.Anything, "metadata", []byte(metadata)).Return(nil)
+	mockedSaver.On("SaveState", mock.Anything, "alerts", []byte(alerts)).Return(nil)
+	mockedSaver.On("SaveState", mock.Anything, "silences", []byte(silences)).Return(nil)
+
+	r := Runner{
+		saver:  mockedSaver,
+		alerts: alerting.New(nil, nil, zap.NewNop().Sugar()),
+	}
+	ctx := context.Background()
+	assert.NoError(t, r.restoreState(ctx))
+	seen, ok := collector.Collector().AgentLastSeen("10.5.0.1")
+	assert.True(t, ok)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), seen.UTC())
+	md, ok := collector.Collector().GetMetadata("PollCount")
+	assert.True(t, ok)
+	assert.Equal(t, "polls", md.Unit)
+	assert.Len(t, r.alerts.Alerts(), 1)
+	assert.Len(t, r.alerts.Silences(time.Now()), 1)
+	assert.NoError(t, r.storeState(ctx))
+}
+
+func TestCheckRules(t *testing.T) {
+	dir := t.TempDir()
+	valid := filepath.Join(dir, "valid.json")
+	require.NoError(t, os.WriteFile(valid, []byte(`{"rules":[{"name":"silent","condition":"absent","agent":"*","window":"1m"}],
+		"receivers":[{"name":"ops","log_file":"alerts.log"}]}`), 0666))
+	invalid := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalid, []byte(`{"rules":[{"name":"silent","condition":"absent"}]}`), 0666))
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedOutput string
+		expectedError  bool
+	}{
+		{name: "positive", path: valid, expectedOutput: valid + ": 1 rules, 1 receivers OK\n"},
+		{name: "negative: invalid rules", path: invalid, expectedError: true},
+		{name: "negative: no file", path: filepath.Join(dir, "missing.json"), expectedError: true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			err := CheckRules(tt.path, &out)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedOutput, out.String())
+		})
+	}
+}
+
+func TestRunner_StoreDeleteFailure(t *testing.T) {
+	c := collector.Collector()
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "StoreDeleted", MType: "gauge", Value: collector.PtrFloat64(1)}, "1"))
+	require.NoError(t, c.DeleteMetric("StoreDeleted", "gauge"))
+	deleted := mock.MatchedBy(func(ids []string) bool {
+		return slices.Contains(ids, "StoreDeleted")
+	})
+
+	mockedSaver := newMockSaver(t)
+	mockedSaver.On("Delete", mock.Anything, deleted).Return(errors.New("connection refused")).Once()
+	mockedSaver.On("Delete", mock.Anything, deleted).Return(nil).Once()
+	mockedSaver.On("Save", mock.Anything, mock.AnythingOfType("[]collector.StoredMetric")).Return(nil).Twice()
+	mockedSaver.On("SaveState", mock.Anything, mock.Anything, mock.AnythingOfType("[]uint8")).Return(nil)
+	r := Runner{saver: mockedSaver, logger: zap.NewNop().Sugar()}
+	ctx := context.Background()
+
+	// неудачное удаление не мешает сохранению и повторяется при следующем сохранении
+	assert.NoError(t, r.store(ctx))
+	assert.NoError(t, r.store(ctx))
+	assert.NotContains(t, c.DrainTombstones(), "StoreDeleted")
+}
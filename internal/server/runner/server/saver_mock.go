@@ -5,6 +5,8 @@ package server
 import (
 	context "context"
 
+	alerting "github.com/ZnNr/go-musthave-metrics.git/internal/server/alerting"
+
 	collector "github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
 
 	mock "github.com/stretchr/testify/mock"
@@ -15,6 +17,72 @@ type mockSaver struct {
 	mock.Mock
 }
 
+// AlertHistory provides a mock function with given fields: ctx, q
+func (_m *mockSaver) AlertHistory(ctx context.Context, q alerting.HistoryQuery) ([]alerting.Event, error) {
+	ret := _m.Called(ctx, q)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AlertHistory")
+	}
+
+	var r0 []alerting.Event
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, alerting.HistoryQuery) ([]alerting.Event, error)); ok {
+		return rf(ctx, q)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, alerting.HistoryQuery) []alerting.Event); ok {
+		r0 = rf(ctx, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]alerting.Event)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, alerting.HistoryQuery) error); ok {
+		r1 = rf(ctx, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AppendAlertEvents provides a mock function with given fields: ctx, events
+func (_m *mockSaver) AppendAlertEvents(ctx context.Context, events []alerting.Event) error {
+	ret := _m.Called(ctx, events)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AppendAlertEvents")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []alerting.Event) error); ok {
+		r0 = rf(ctx, events)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Delete provides a mock function with given fields: ctx, ids
+func (_m *mockSaver) Delete(ctx context.Context, ids []string) error {
+	ret := _m.Called(ctx, ids)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Delete")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) error); ok {
+		r0 = rf(ctx, ids)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Restore provides a mock function with given fields: ctx
 func (_m *mockSaver) Restore(ctx context.Context) ([]collector.StoredMetric, error) {
 	ret := _m.Called(ctx)
@@ -45,6 +113,36 @@ func (_m *mockSaver) Restore(ctx context.Context) ([]collector.StoredMetric, err
 	return r0, r1
 }
 
+// RestoreState provides a mock function with given fields: ctx, key
+func (_m *mockSaver) RestoreState(ctx context.Context, key string) ([]byte, error) {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreState")
+	}
+
+	var r0 []byte
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) ([]byte, error)); ok {
+		return rf(ctx, key)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) []byte); ok {
+		r0 = rf(ctx, key)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]byte)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, key)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Save provides a mock function with given fields: ctx, metrics
 func (_m *mockSaver) Save(ctx context.Context, metrics []collector.StoredMetric) error {
 	ret := _m.Called(ctx, metrics)
@@ -63,6 +161,24 @@ func (_m *mockSaver) Save(ctx context.Context, metrics []collector.StoredMetric)
 	return r0
 }
 
+// SaveState provides a mock function with given fields: ctx, key, data
+func (_m *mockSaver) SaveState(ctx context.Context, key string, data []byte) error {
+	ret := _m.Called(ctx, key, data)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveState")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, []byte) error); ok {
+		r0 = rf(ctx, key, data)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // newMockSaver creates a new instance of mockSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func newMockSaver(t interface {
@@ -4,8 +4,11 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerthistory"
 	"go.uber.org/zap"
 	"time"
 )
@@ -13,14 +16,34 @@ import (
 // SQL-запросы
 const (
 	// Запрос для восстановления состояния метрик
-	selectMetricsQuery = `select id, mtype, delta, mvalue from metrics`
+	selectMetricsQuery = `select id, mtype, delta, mvalue, histogram from metrics`
 
-	// Запросы для сохранения состояния метрик (Gauge и Counter)
-	insertGaugeQuery   = `insert into metrics (id, mtype, mvalue) values ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET mvalue = EXCLUDED.mvalue;`
-	insertCounterQuery = `insert into metrics (id, mtype, delta) values ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET delta = EXCLUDED.delta;`
+	// Запросы для сохранения состояния метрик (Gauge, Counter и Histogram)
+	insertGaugeQuery     = `insert into metrics (id, mtype, mvalue) values ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET mvalue = EXCLUDED.mvalue;`
+	insertCounterQuery   = `insert into metrics (id, mtype, delta) values ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET delta = EXCLUDED.delta;`
+	insertHistogramQuery = `insert into metrics (id, mtype, histogram) values ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET histogram = EXCLUDED.histogram;`
 
-	// Запрос для создания таблицы метрик
-	createMetricsTableQuery = `create table if not exists metrics (id text primary key, mtype text, delta bigint, mvalue double precision)`
+	// Запрос для удаления устаревшей метрики
+	deleteMetricQuery = `delete from metrics where id = $1`
+
+	// Запросы для сохранения и восстановления произвольного состояния сервера по ключу
+	upsertStateQuery = `insert into state (key, data) values ($1, $2) ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data;`
+	selectStateQuery = `select data from state where key = $1`
+
+	// Запросы для записи и выборки истории оповещений
+	insertAlertEventQuery  = `insert into alert_history (time, rule, labels, severity, state, value, summary, alert_id) values ($1, $2, $3, $4, $5, $6, $7, $8)`
+	selectAlertEventsQuery = `select alert_id, time, rule, labels, severity, state, value, summary from (
+select id, alert_id, time, rule, labels, severity, state, value, summary from alert_history
+where ($1::text = '' or rule = $1) and ($2::timestamptz is null or time >= $2) and ($3::timestamptz is null or time < $3)
+order by time desc, id desc limit $4) last order by time, id`
+
+	// Запрос для создания таблиц метрик, состояния и истории оповещений; колонки histogram и alert_id добавляются и в таблицы, созданные до их появления
+	createMetricsTableQuery = `create table if not exists metrics (id text primary key, mtype text, delta bigint, mvalue double precision, histogram jsonb);
+alter table metrics add column if not exists histogram jsonb;
+create table if not exists state (key text primary key, data jsonb);
+create table if not exists alert_history (id bigserial primary key, time timestamptz not null, rule text not null, labels jsonb, severity text, state text not null, value double precision, summary text);
+alter table alert_history add column if not exists alert_id text;
+create index if not exists alert_history_time on alert_history (time)`
 )
 
 var log *zap.SugaredLogger
@@ -46,15 +69,16 @@ func (m *Manager) Restore(ctx context.Context) ([]collector.StoredMetric, error)
 		return nil, fmt.Errorf("rows error: %w", err)
 	}
 
-	var metrics []collector.StoredMetric
+	metrics := make([]collector.StoredMetric, 0)
 	for rows.Next() {
 		var (
 			id          string
 			mtype       string
 			deltaFromDB sql.NullInt64
 			valueFromDB sql.NullFloat64
+			histFromDB  sql.NullString
 		)
-		if err = rows.Scan(&id, &mtype, &deltaFromDB, &valueFromDB); err != nil {
+		if err = rows.Scan(&id, &mtype, &deltaFromDB, &valueFromDB, &histFromDB); err != nil {
 			return nil, err
 		}
 		var delta *int64
@@ -65,11 +89,18 @@ func (m *Manager) Restore(ctx context.Context) ([]collector.StoredMetric, error)
 		if valueFromDB.Valid {
 			mvalue = &valueFromDB.Float64
 		}
+		var histogram *collector.HistogramValue
+		if histFromDB.Valid {
+			if err = json.Unmarshal([]byte(histFromDB.String), &histogram); err != nil {
+				return nil, fmt.Errorf("error while parsing histogram %q: %w", id, err)
+			}
+		}
 		metric := collector.StoredMetric{
-			ID:           id,
-			MType:        mtype,
-			CounterValue: delta,
-			GaugeValue:   mvalue,
+			ID:             id,
+			MType:          mtype,
+			CounterValue:   delta,
+			GaugeValue:     mvalue,
+			HistogramValue: histogram,
 		}
 		metrics = append(metrics, metric)
 	}
@@ -88,11 +119,107 @@ func (m *Manager) Save(ctx context.Context, metrics []collector.StoredMetric) er
 			if err := m.execWithRetries(ctx, insertCounterQuery, metric.ID, metric.MType, &metric.CounterValue); err != nil {
 				return fmt.Errorf("error while executing insert counter query: %w", err)
 			}
+		case collector.Histogram:
+			if metric.HistogramValue == nil {
+				continue
+			}
+			if err := m.execWithRetries(ctx, insertHistogramQuery, metric.ID, metric.MType, metric.HistogramValue.String()); err != nil {
+				return fmt.Errorf("error while executing insert histogram query: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// Delete удаляет метрики из БД по именам.
+func (m *Manager) Delete(ctx context.Context, ids []string) error {
+	for _, id := range ids {
+		if err := m.execWithRetries(ctx, deleteMetricQuery, id); err != nil {
+			return fmt.Errorf("error while executing delete query: %w", err)
+		}
+	}
+	return nil
+}
+
+// SaveState сохраняет произвольное состояние сервера (например, оповещений) в формате JSON под ключом key.
+func (m *Manager) SaveState(ctx context.Context, key string, data []byte) error {
+	if err := m.execWithRetries(ctx, upsertStateQuery, key, string(data)); err != nil {
+		return fmt.Errorf("error while executing upsert state query: %w", err)
+	}
+	return nil
+}
+
+// RestoreState восстанавливает состояние сервера, сохраненное под ключом key.
+// Возвращает nil без ошибки, если состояние еще не сохранялось.
+func (m *Manager) RestoreState(ctx context.Context, key string) ([]byte, error) {
+	var data string
+	err := m.db.QueryRowContext(ctx, selectStateQuery, key).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []byte(data), nil
+}
+
+// AppendAlertEvents сохраняет переходы оповещений в таблицу истории.
+func (m *Manager) AppendAlertEvents(ctx context.Context, events []alerthistory.Event) error {
+	for _, event := range events {
+		labels, err := json.Marshal(event.Labels)
+		if err != nil {
+			return err
+		}
+		if err = m.execWithRetries(ctx, insertAlertEventQuery, event.Time, event.Rule, string(labels),
+			event.Severity, event.State, event.Value, event.Summary, event.ID); err != nil {
+			return fmt.Errorf("error while executing insert alert event query: %w", err)
 		}
 	}
 	return nil
 }
 
+// AlertHistory возвращает последние q.Size() событий истории оповещений, подходящих под условия q.
+func (m *Manager) AlertHistory(ctx context.Context, q alerthistory.Query) ([]alerthistory.Event, error) {
+	var from, to sql.NullTime
+	if !q.From.IsZero() {
+		from = sql.NullTime{Time: q.From, Valid: true}
+	}
+	if !q.To.IsZero() {
+		to = sql.NullTime{Time: q.To, Valid: true}
+	}
+	rows, err := m.db.QueryContext(ctx, selectAlertEventsQuery, q.Rule, from, to, q.Size())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]alerthistory.Event, 0)
+	for rows.Next() {
+		var (
+			event    alerthistory.Event
+			id       sql.NullString
+			labels   sql.NullString
+			severity sql.NullString
+			value    sql.NullFloat64
+			summary  sql.NullString
+		)
+		if err = rows.Scan(&id, &event.Time, &event.Rule, &labels, &severity, &event.State, &value, &summary); err != nil {
+			return nil, err
+		}
+		if labels.Valid {
+			if err = json.Unmarshal([]byte(labels.String), &event.Labels); err != nil {
+				return nil, fmt.Errorf("error while parsing alert labels: %w", err)
+			}
+		}
+		event.ID, event.Severity, event.Value, event.Summary = id.String, severity.String, value.Float64, summary.String
+		events = append(events, event)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+	return events, nil
+}
+
 func (m *Manager) execWithRetries(ctx context.Context, query string, args ...interface{}) error {
 	var err error
 	initLogger()
@@ -2,10 +2,13 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerthistory"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestManager_Restore(t *testing.T) {
@@ -16,12 +19,12 @@ func TestManager_Restore(t *testing.T) {
 	}{
 		{
 			name:     "positive: no saved metrics",
-			rows:     sqlmock.NewRows([]string{"id", "mtype", "delta", "mvalue"}),
+			rows:     sqlmock.NewRows([]string{"id", "mtype", "delta", "mvalue", "histogram"}),
 			expected: []collector.StoredMetric{},
 		},
 		{
 			name: "positive: one saved metric",
-			rows: sqlmock.NewRows([]string{"id", "mtype", "delta", "mvalue"}).AddRow("metricName", "counter", 5, nil),
+			rows: sqlmock.NewRows([]string{"id", "mtype", "delta", "mvalue", "histogram"}).AddRow("metricName", "counter", 5, nil, nil),
 			expected: []collector.StoredMetric{
 				{
 					ID:           "metricName",
@@ -32,9 +35,9 @@ func TestManager_Restore(t *testing.T) {
 		},
 		{
 			name: "positive: some saved metrics",
-			rows: sqlmock.NewRows([]string{"id", "mtype", "delta", "mvalue"}).
-				AddRow("metricName", "counter", 5, nil).
-				AddRow("otherMetricName", "gauge", nil, 10.502),
+			rows: sqlmock.NewRows([]string{"id", "mtype", "delta", "mvalue", "histogram"}).
+				AddRow("metricName", "counter", 5, nil, nil).
+				AddRow("otherMetricName", "gauge", nil, 10.502, nil),
 			expected: []collector.StoredMetric{
 				{
 					ID:           "metricName",
@@ -48,6 +51,23 @@ func TestManager_Restore(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "positive: saved histogram",
+			rows: sqlmock.NewRows([]string{"id", "mtype", "delta", "mvalue", "histogram"}).
+				AddRow("latency", "histogram", nil, nil, `{"count":3,"sum":2.5,"bounds":[0.5,1],"buckets":[1,1,1]}`),
+			expected: []collector.StoredMetric{
+				{
+					ID:    "latency",
+					MType: "histogram",
+					HistogramValue: &collector.HistogramValue{
+						Count:   3,
+						Sum:     2.5,
+						Bounds:  []float64{0.5, 1},
+						Buckets: []uint64{1, 1, 1},
+					},
+				},
+			},
+		},
 	}
 	for _, tt := range testCases {
 		t.Run(tt.name, func(t *testing.T) {
@@ -56,7 +76,7 @@ func TestManager_Restore(t *testing.T) {
 				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
 			}
 			mock.ExpectExec("create table if not exists metrics").WillReturnResult(sqlmock.NewResult(1, 1))
-			mock.ExpectQuery("select id, mtype, delta, mvalue from metrics").WillReturnRows(tt.rows)
+			mock.ExpectQuery("select id, mtype, delta, mvalue, histogram from metrics").WillReturnRows(tt.rows)
 			manager, err := New(db)
 			assert.NoError(t, err)
 
@@ -86,6 +106,16 @@ func TestManager_Save(t *testing.T) {
 					MType:        "counter",
 					CounterValue: collector.PtrInt64(10),
 				},
+				{
+					ID:    "latency",
+					MType: "histogram",
+					HistogramValue: &collector.HistogramValue{
+						Count:   3,
+						Sum:     2.5,
+						Bounds:  []float64{0.5, 1},
+						Buckets: []uint64{1, 1, 1},
+					},
+				},
 			},
 		},
 	}
@@ -98,6 +128,7 @@ func TestManager_Save(t *testing.T) {
 			mock.ExpectExec("create table if not exists metrics").WillReturnResult(sqlmock.NewResult(1, 1))
 			mock.ExpectExec("insert into metrics").WithArgs(tt.metrics[0].ID, tt.metrics[0].MType, &tt.metrics[0].GaugeValue).WillReturnResult(sqlmock.NewResult(1, 1))
 			mock.ExpectExec("insert into metrics").WithArgs(tt.metrics[1].ID, tt.metrics[1].MType, &tt.metrics[1].CounterValue).WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectExec("insert into metrics").WithArgs(tt.metrics[2].ID, tt.metrics[2].MType, `{"count":3,"sum":2.5,"bounds":[0.5,1],"buckets":[1,1,1]}`).WillReturnResult(sqlmock.NewResult(1, 1))
 			manager, err := New(db)
 			assert.NoError(t, err)
 
@@ -107,3 +138,69 @@ func TestManager_Save(t *testing.T) {
 		})
 	}
 }
+
+func TestManager_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.ExpectExec("create table if not exists metrics").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("delete from metrics").WithArgs("metricName").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("delete from metrics").WithArgs("otherMetricName").WillReturnResult(sqlmock.NewResult(1, 1))
+	manager, err := New(db)
+	assert.NoError(t, err)
+
+	err = manager.Delete(context.Background(), []string{"metricName", "otherMetricName"})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_State(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	mock.ExpectExec("create table if not exists metrics").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("select data from state").WithArgs("alerts").WillReturnRows(sqlmock.NewRows([]string{"data"}))
+	mock.ExpectExec("insert into state").WithArgs("alerts", `{"alerts":[]}`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("select data from state").WithArgs("alerts").WillReturnRows(sqlmock.NewRows([]string{"data"}).AddRow(`{"alerts":[]}`))
+	manager, err := New(db)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	data, err := manager.RestoreState(ctx, "alerts")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+	assert.NoError(t, manager.SaveState(ctx, "alerts", []byte(`{"alerts":[]}`)))
+	data, err = manager.RestoreState(ctx, "alerts")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"alerts":[]}`, string(data))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestManager_AlertHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	event := alerthistory.Event{ID: "9f19836c067c525f", Time: at, Rule: "cpu", Labels: map[string]string{"agent": "10.0.0.1"}, Severity: "critical", State: "firing", Value: 95, Summary: "high cpu"}
+	mock.ExpectExec("create table if not exists metrics").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("insert into alert_history").
+		WithArgs(at, "cpu", `{"agent":"10.0.0.1"}`, "critical", "firing", 95.0, "high cpu", "9f19836c067c525f").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery("select alert_id, time, rule, labels, severity, state, value, summary from").
+		WithArgs("cpu", sql.NullTime{Time: at, Valid: true}, sql.NullTime{}, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"alert_id", "time", "rule", "labels", "severity", "state", "value", "summary"}).
+			AddRow("9f19836c067c525f", at, "cpu", `{"agent":"10.0.0.1"}`, "critical", "firing", 95.0, "high cpu").
+			AddRow(nil, at.Add(time.Minute), "cpu", nil, nil, "resolved", nil, nil))
+	manager, err := New(db)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	assert.NoError(t, manager.AppendAlertEvents(ctx, []alerthistory.Event{event}))
+	events, err := manager.AlertHistory(ctx, alerthistory.Query{Rule: "cpu", From: at, Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []alerthistory.Event{event, {Time: at.Add(time.Minute), Rule: "cpu", State: "resolved"}}, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
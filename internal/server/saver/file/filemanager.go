@@ -5,8 +5,12 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerthistory"
 	"os"
+	"path/filepath"
+	"sync"
 )
 
 // Restore восстанавливает состояние метрик из файла.
@@ -31,40 +35,152 @@ func (m *Manager) Restore(ctx context.Context) ([]collector.StoredMetric, error)
 
 // Save сохраняет состояние метрик в файл.
 func (m *Manager) Save(ctx context.Context, metrics []collector.StoredMetric) error {
-	var saveError error
-	file, err := os.OpenFile(m.fileName, os.O_WRONLY|os.O_CREATE, 0666)
+	data, err := json.Marshal(&metrics)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := file.Close(); err != nil {
-			saveError = err
-		}
-	}()
+	return writeFileAtomic(m.fileName, append(data, '\n'))
+}
 
-	writer := bufio.NewWriter(file)
+// Delete удаляет метрики из хранилища. Файл содержит полный снимок метрик и перезаписывается
+// при каждом сохранении, поэтому удаленные метрики исчезают из него при следующем вызове Save.
+func (m *Manager) Delete(ctx context.Context, ids []string) error {
+	return nil
+}
 
-	data, err := json.Marshal(&metrics)
+// SaveState сохраняет произвольное состояние сервера (например, оповещений) под ключом key
+// в отдельный файл рядом с файлом метрик.
+func (m *Manager) SaveState(ctx context.Context, key string, data []byte) error {
+	return writeFileAtomic(m.stateFileName(key), data)
+}
+
+// writeFileAtomic записывает данные во временный файл в том же каталоге и переименовывает его в name,
+// чтобы при сбое во время записи в name оставалась предыдущая версия.
+func writeFileAtomic(name string, data []byte) (err error) {
+	file, err := os.CreateTemp(filepath.Dir(name), filepath.Base(name)+".tmp*")
 	if err != nil {
 		return err
 	}
-	if _, err = writer.Write(data); err != nil {
+	defer func() {
+		if err != nil {
+			_ = os.Remove(file.Name())
+		}
+	}()
+	if _, err = file.Write(data); err != nil {
+		_ = file.Close()
 		return err
 	}
-	if err = writer.WriteByte('\n'); err != nil {
+	if err = file.Close(); err != nil {
 		return err
 	}
-	if err = writer.Flush(); err != nil {
+	return os.Rename(file.Name(), name)
+}
+
+// RestoreState восстанавливает состояние сервера, сохраненное под ключом key.
+// Возвращает nil без ошибки, если состояние еще не сохранялось.
+func (m *Manager) RestoreState(ctx context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(m.stateFileName(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// stateFileName возвращает путь к файлу состояния с ключом key.
+func (m *Manager) stateFileName(key string) string {
+	return m.fileName + "." + key
+}
+
+// historyFileSuffix - суффикс файла истории оповещений рядом с файлом метрик.
+const historyFileSuffix = ".alerts_history"
+
+// defaultHistoryMaxSize - размер файла истории, после которого он переименовывается в файл с суффиксом ".1"
+// и история продолжается в новом файле; предыдущий файл ".1" при этом удаляется.
+const defaultHistoryMaxSize = 4 << 20
+
+// AppendAlertEvents дописывает переходы оповещений в файл истории по одному событию JSON в строке.
+func (m *Manager) AppendAlertEvents(ctx context.Context, events []alerthistory.Event) (err error) {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	name := m.fileName + historyFileSuffix
+	if info, err := os.Stat(name); err == nil && info.Size() >= m.historyMaxSize {
+		if err = os.Rename(name, name+".1"); err != nil {
+			return err
+		}
+	}
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	if err != nil {
 		return err
 	}
-	return saveError
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	writer := bufio.NewWriter(file)
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		if _, err = writer.Write(append(data, '\n')); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+// AlertHistory возвращает последние q.Size() событий из файлов истории, подходящих под условия q.
+// Строки, которые не удается разобрать (например, недописанные при сбое), пропускаются.
+func (m *Manager) AlertHistory(ctx context.Context, q alerthistory.Query) ([]alerthistory.Event, error) {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+	events := make([]alerthistory.Event, 0)
+	name := m.fileName + historyFileSuffix
+	for _, name := range []string{name + ".1", name} {
+		var err error
+		if events, err = readHistory(name, q, events); err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// readHistory дописывает к events события файла истории name, подходящие под условия q,
+// сохраняя не более q.Size() последних событий. Отсутствующий файл пропускается.
+func readHistory(name string, q alerthistory.Query, events []alerthistory.Event) ([]alerthistory.Event, error) {
+	file, err := os.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return events, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var event alerthistory.Event
+		if err = json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			continue
+		}
+		if !q.Match(event) {
+			continue
+		}
+		if events = append(events, event); len(events) > q.Size() {
+			events = events[1:]
+		}
+	}
+	return events, scanner.Err()
 }
 
 // New создает новый менеджер для работы с файлами.
 func New(path string) *Manager {
-	return &Manager{fileName: path}
+	return &Manager{fileName: path, historyMaxSize: defaultHistoryMaxSize}
 }
 
 type Manager struct {
-	fileName string
+	fileName       string
+	historyMu      sync.Mutex // сериализует запись и чтение файла истории оповещений
+	historyMaxSize int64      // размер файла истории, после которого начинается новый файл
 }
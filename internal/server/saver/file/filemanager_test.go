@@ -3,9 +3,13 @@ package file
 import (
 	"context"
 	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/server/alerthistory"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestManager_Restore(t *testing.T) {
@@ -116,3 +120,128 @@ func TestManager_Save(t *testing.T) {
 		})
 	}
 }
+
+func TestManager_SaveAfterDelete(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "")
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	manager := New(file.Name())
+	err = manager.Save(ctx, []collector.StoredMetric{
+		{ID: "StaleCounter", MType: "counter", CounterValue: collector.PtrInt64(100500)},
+		{ID: "PollCount", MType: "counter", CounterValue: collector.PtrInt64(1)},
+	})
+	assert.NoError(t, err)
+
+	assert.NoError(t, manager.Delete(ctx, []string{"StaleCounter"}))
+	err = manager.Save(ctx, []collector.StoredMetric{
+		{ID: "PollCount", MType: "counter", CounterValue: collector.PtrInt64(2)},
+	})
+	assert.NoError(t, err)
+
+	b, err := os.ReadFile(file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "[{\"id\":\"PollCount\",\"type\":\"counter\",\"counter_value\":2}]\n", string(b))
+}
+
+func TestManager_State(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	manager := New(filepath.Join(dir, "metrics.json"))
+
+	data, err := manager.RestoreState(ctx, "alerts")
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	assert.NoError(t, manager.SaveState(ctx, "alerts", []byte(`{"alerts":[]}`)))
+	data, err = manager.RestoreState(ctx, "alerts")
+	assert.NoError(t, err)
+	assert.Equal(t, `{"alerts":[]}`, string(data))
+
+	// состояние перезаписывается целиком, временные файлы не остаются
+	assert.NoError(t, manager.SaveState(ctx, "alerts", []byte(`{}`)))
+	data, err = manager.RestoreState(ctx, "alerts")
+	assert.NoError(t, err)
+	assert.Equal(t, `{}`, string(data))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "metrics.json.alerts", entries[0].Name())
+}
+
+func TestManager_AlertHistory(t *testing.T) {
+	ctx := context.Background()
+	manager := New(filepath.Join(t.TempDir(), "metrics.json"))
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events, err := manager.AlertHistory(ctx, alerthistory.Query{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	for i, rule := range []string{"cpu", "memory", "cpu", "cpu"} {
+		state := "firing"
+		if i == 2 {
+			state = "resolved"
+		}
+		require.NoError(t, manager.AppendAlertEvents(ctx, []alerthistory.Event{{
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			Rule:   rule,
+			Labels: map[string]string{"metric": "M"},
+			State:  state,
+			Value:  float64(i),
+		}}))
+	}
+
+	testCases := []struct {
+		name           string
+		query          alerthistory.Query
+		expectedValues []float64
+	}{
+		{name: "all", query: alerthistory.Query{}, expectedValues: []float64{0, 1, 2, 3}},
+		{name: "by rule", query: alerthistory.Query{Rule: "cpu"}, expectedValues: []float64{0, 2, 3}},
+		{name: "period", query: alerthistory.Query{From: start.Add(time.Minute), To: start.Add(3 * time.Minute)}, expectedValues: []float64{1, 2}},
+		{name: "latest", query: alerthistory.Query{Limit: 2}, expectedValues: []float64{2, 3}},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := manager.AlertHistory(ctx, tt.query)
+			require.NoError(t, err)
+			values := make([]float64, 0, len(events))
+			for _, event := range events {
+				values = append(values, event.Value)
+			}
+			assert.Equal(t, tt.expectedValues, values)
+		})
+	}
+	events, err = manager.AlertHistory(ctx, alerthistory.Query{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, alerthistory.Event{Time: start.Add(3 * time.Minute), Rule: "cpu", Labels: map[string]string{"metric": "M"}, State: "firing", Value: 3}, events[0])
+}
+
+func TestManager_AlertHistoryRotation(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "metrics.json")
+	manager := New(path)
+	manager.historyMaxSize = 1
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, manager.AppendAlertEvents(ctx, []alerthistory.Event{{Time: start.Add(time.Duration(i) * time.Minute), Rule: "cpu", State: "firing", Value: float64(i)}}))
+	}
+	// при каждом превышении размера остается только предыдущий файл истории
+	events, err := manager.AlertHistory(ctx, alerthistory.Query{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, 1.0, events[0].Value)
+	assert.Equal(t, 2.0, events[1].Value)
+
+	// поврежденная строка пропускается
+	file, err := os.OpenFile(path+historyFileSuffix, os.O_WRONLY|os.O_APPEND, 0666)
+	require.NoError(t, err)
+	_, err = file.WriteString("{\"time\":\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	events, err = manager.AlertHistory(ctx, alerthistory.Query{})
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
@@ -0,0 +1,140 @@
+// Package selfmetrics записывает собственные метрики сервера: число и длительность HTTP и gRPC запросов,
+// длительность и ошибки сохранения метрик, число серий и время вычисления оповещений.
+// Метрики хранятся в коллекторе под зарезервированным префиксом collector.ReservedPrefix,
+// метки записываются в имени метрики в каноническом виде name{a="1",b="2"}.
+// Метрики HTTP и gRPC запросов записываются на каждом запросе, поэтому хранятся отдельно от коллектора
+// и возвращаются функцией Requests.
+package selfmetrics
+
+import (
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Имена собственных метрик сервера.
+const (
+	HTTPRequests            = collector.ReservedPrefix + "http_requests_total"               // число HTTP запросов по маршруту, методу и статусу
+	HTTPDuration            = collector.ReservedPrefix + "http_request_duration_seconds"     // длительность HTTP запросов по маршруту и методу
+	GRPCRequests            = collector.ReservedPrefix + "grpc_requests_total"               // число gRPC вызовов по методу и коду ответа
+	GRPCDuration            = collector.ReservedPrefix + "grpc_request_duration_seconds"     // длительность gRPC вызовов по методу
+	SaverDuration           = collector.ReservedPrefix + "saver_duration_seconds"            // длительность операций хранилища
+	SaverErrors             = collector.ReservedPrefix + "saver_errors_total"                // число ошибок операций хранилища
+	Series                  = collector.ReservedPrefix + "series"                            // число серий без собственных метрик сервера
+	AlertEvaluationDuration = collector.ReservedPrefix + "alert_evaluation_duration_seconds" // длительность вычисления правил оповещений
+)
+
+// requests хранит метрики HTTP и gRPC запросов. Наблюдение изменяет значение на месте
+// и не захватывает блокировку коллектора.
+var requests = struct {
+	sync.Mutex
+	counters   map[string]int64
+	histograms map[string]*collector.HistogramValue
+}{
+	counters:   make(map[string]int64),
+	histograms: make(map[string]*collector.HistogramValue),
+}
+
+// ObserveHTTP учитывает HTTP запрос к маршруту route (шаблону маршрута chi) с кодом ответа status.
+func ObserveHTTP(method, route string, status int, d time.Duration) {
+	observeRequest(
+		ID(HTTPRequests, "method", method, "route", route, "status", strconv.Itoa(status)),
+		ID(HTTPDuration, "method", method, "route", route),
+		d,
+	)
+}
+
+// ObserveGRPC учитывает gRPC вызов метода method с кодом ответа code.
+func ObserveGRPC(method, code string, d time.Duration) {
+	observeRequest(ID(GRPCRequests, "code", code, "method", method), ID(GRPCDuration, "method", method), d)
+}
+
+// observeRequest увеличивает счетчик запросов и добавляет длительность запроса в гистограмму.
+func observeRequest(counter, histogram string, d time.Duration) {
+	requests.Lock()
+	defer requests.Unlock()
+	requests.counters[counter]++
+	h, ok := requests.histograms[histogram]
+	if !ok {
+		h = collector.NewHistogram(collector.DefaultBuckets)
+		requests.histograms[histogram] = h
+	}
+	_ = h.Observe(d.Seconds())
+}
+
+// Requests возвращает копию метрик HTTP и gRPC запросов, упорядоченную по имени.
+func Requests() []collector.StoredMetric {
+	requests.Lock()
+	metrics := make([]collector.StoredMetric, 0, len(requests.counters)+len(requests.histograms))
+	for id, v := range requests.counters {
+		metrics = append(metrics, collector.StoredMetric{
+			ID:           id,
+			MType:        collector.Counter,
+			CounterValue: collector.PtrInt64(v),
+			TextValue:    collector.PtrString(strconv.FormatInt(v, 10)),
+		})
+	}
+	for id, h := range requests.histograms {
+		h = h.Clone()
+		metrics = append(metrics, collector.StoredMetric{
+			ID:             id,
+			MType:          collector.Histogram,
+			HistogramValue: h,
+			TextValue:      collector.PtrString(h.String()),
+		})
+	}
+	requests.Unlock()
+	sort.Slice(metrics, func(i, j int) bool {
+		return metrics[i].ID < metrics[j].ID
+	})
+	return metrics
+}
+
+// ObserveSaver учитывает операцию хранилища op (save, delete, restore) и ее ошибку.
+func ObserveSaver(op string, d time.Duration, err error) {
+	c := collector.Collector()
+	c.ObserveHistogram(ID(SaverDuration, "op", op), d.Seconds())
+	if err != nil {
+		c.AddCounter(ID(SaverErrors, "op", op), 1)
+	}
+}
+
+// ObserveAlertEvaluation учитывает длительность одного цикла вычисления правил оповещений.
+func ObserveAlertEvaluation(d time.Duration) {
+	collector.Collector().ObserveHistogram(AlertEvaluationDuration, d.Seconds())
+}
+
+// UpdateSeries обновляет число серий, хранящихся на сервере, без учета собственных метрик.
+func UpdateSeries() {
+	var n int
+	c := collector.Collector()
+	for _, m := range c.Snapshot() {
+		if !collector.IsReserved(m.ID) {
+			n++
+		}
+	}
+	c.SetGauge(Series, float64(n))
+}
+
+// ID возвращает имя метрики с метками, переданными парами имя-значение в порядке возрастания имен.
+func ID(name string, labels ...string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i])
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[i+1]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package selfmetrics
+
+import (
+	"errors"
+	"github.com/ZnNr/go-musthave-metrics.git/internal/agent/collector"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestID(t *testing.T) {
+	assert.Equal(t, "server_series", ID(Series))
+	assert.Equal(t, `server_saver_errors_total{op="save"}`, ID(SaverErrors, "op", "save"))
+	assert.Equal(t, `server_grpc_requests_total{code="OK",method="/Metrics/Save\"d"}`, ID(GRPCRequests, "code", "OK", "method", `/Metrics/Save"d`))
+}
+
+func TestObserveSaver(t *testing.T) {
+	ObserveSaver("save", time.Millisecond, nil)
+	ObserveSaver("save", time.Millisecond, errors.New("disk full"))
+
+	c := collector.Collector()
+	duration, err := c.GetMetric(ID(SaverDuration, "op", "save"))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), duration.HistogramValue.Count)
+	errs, err := c.GetMetric(ID(SaverErrors, "op", "save"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), *errs.CounterValue)
+
+	require.NoError(t, c.Collect(collector.MetricRequest{ID: "SelfSeries", MType: "gauge", Value: collector.PtrFloat64(1)}, "1"))
+	UpdateSeries()
+	series, err := c.GetMetric(Series)
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, *series.GaugeValue)
+}
+
+func TestRequests(t *testing.T) {
+	ObserveHTTP("GET", "/value/{type}/{name}", 200, 10*time.Millisecond)
+	ObserveHTTP("GET", "/value/{type}/{name}", 200, 20*time.Millisecond)
+	ObserveGRPC("/metrics.Metrics/GetMetric", "OK", time.Millisecond)
+
+	metrics := make(map[string]collector.StoredMetric)
+	for _, m := range Requests() {
+		metrics[m.ID] = m
+	}
+	requests := metrics[ID(HTTPRequests, "method", "GET", "route", "/value/{type}/{name}", "status", "200")]
+	require.NotNil(t, requests.CounterValue)
+	assert.Equal(t, int64(2), *requests.CounterValue)
+	duration := metrics[ID(HTTPDuration, "method", "GET", "route", "/value/{type}/{name}")]
+	require.NotNil(t, duration.HistogramValue)
+	assert.Equal(t, uint64(2), duration.HistogramValue.Count)
+	grpc := metrics[ID(GRPCRequests, "code", "OK", "method", "/metrics.Metrics/GetMetric")]
+	require.NotNil(t, grpc.CounterValue)
+	assert.Equal(t, int64(1), *grpc.CounterValue)
+
+	for _, name := range collector.Collector().GetAvailableMetrics() {
+		assert.NotContains(t, name, HTTPRequests)
+	}
+}
@@ -20,6 +20,11 @@ const _ = grpc.SupportPackageIsVersion7
 
 const (
 	Metrics_SaveMetricFromJSON_FullMethodName = "/scraper.Metrics/SaveMetricFromJSON"
+	Metrics_DeleteMetric_FullMethodName       = "/scraper.Metrics/DeleteMetric"
+	Metrics_ResetMetric_FullMethodName        = "/scraper.Metrics/ResetMetric"
+	Metrics_ListAlerts_FullMethodName         = "/scraper.Metrics/ListAlerts"
+	Metrics_AlertHistory_FullMethodName       = "/scraper.Metrics/AlertHistory"
+	Metrics_ListMetrics_FullMethodName        = "/scraper.Metrics/ListMetrics"
 )
 
 // MetricsClient is the client API for Metrics service.
@@ -27,6 +32,11 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type MetricsClient interface {
 	SaveMetricFromJSON(ctx context.Context, in *MetricRequest, opts ...grpc.CallOption) (*SaveMetricResponse, error)
+	DeleteMetric(ctx context.Context, in *MetricRequest, opts ...grpc.CallOption) (*SaveMetricResponse, error)
+	ResetMetric(ctx context.Context, in *MetricRequest, opts ...grpc.CallOption) (*SaveMetricResponse, error)
+	ListAlerts(ctx context.Context, in *AlertsRequest, opts ...grpc.CallOption) (*AlertsResponse, error)
+	AlertHistory(ctx context.Context, in *AlertsRequest, opts ...grpc.CallOption) (*AlertsResponse, error)
+	ListMetrics(ctx context.Context, in *ListMetricsRequest, opts ...grpc.CallOption) (*ListMetricsResponse, error)
 }
 
 type metricsClient struct {
@@ -46,11 +56,61 @@ func (c *metricsClient) SaveMetricFromJSON(ctx context.Context, in *MetricReques
 	return out, nil
 }
 
+func (c *metricsClient) DeleteMetric(ctx context.Context, in *MetricRequest, opts ...grpc.CallOption) (*SaveMetricResponse, error) {
+	out := new(SaveMetricResponse)
+	err := c.cc.Invoke(ctx, Metrics_DeleteMetric_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsClient) ResetMetric(ctx context.Context, in *MetricRequest, opts ...grpc.CallOption) (*SaveMetricResponse, error) {
+	out := new(SaveMetricResponse)
+	err := c.cc.Invoke(ctx, Metrics_ResetMetric_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsClient) ListAlerts(ctx context.Context, in *AlertsRequest, opts ...grpc.CallOption) (*AlertsResponse, error) {
+	out := new(AlertsResponse)
+	err := c.cc.Invoke(ctx, Metrics_ListAlerts_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsClient) AlertHistory(ctx context.Context, in *AlertsRequest, opts ...grpc.CallOption) (*AlertsResponse, error) {
+	out := new(AlertsResponse)
+	err := c.cc.Invoke(ctx, Metrics_AlertHistory_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *metricsClient) ListMetrics(ctx context.Context, in *ListMetricsRequest, opts ...grpc.CallOption) (*ListMetricsResponse, error) {
+	out := new(ListMetricsResponse)
+	err := c.cc.Invoke(ctx, Metrics_ListMetrics_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MetricsServer is the server API for Metrics service.
 // All implementations must embed UnimplementedMetricsServer
 // for forward compatibility
 type MetricsServer interface {
 	SaveMetricFromJSON(context.Context, *MetricRequest) (*SaveMetricResponse, error)
+	DeleteMetric(context.Context, *MetricRequest) (*SaveMetricResponse, error)
+	ResetMetric(context.Context, *MetricRequest) (*SaveMetricResponse, error)
+	ListAlerts(context.Context, *AlertsRequest) (*AlertsResponse, error)
+	AlertHistory(context.Context, *AlertsRequest) (*AlertsResponse, error)
+	ListMetrics(context.Context, *ListMetricsRequest) (*ListMetricsResponse, error)
 	mustEmbedUnimplementedMetricsServer()
 }
 
@@ -61,6 +121,21 @@ type UnimplementedMetricsServer struct {
 func (UnimplementedMetricsServer) SaveMetricFromJSON(context.Context, *MetricRequest) (*SaveMetricResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SaveMetricFromJSON not implemented")
 }
+func (UnimplementedMetricsServer) DeleteMetric(context.Context, *MetricRequest) (*SaveMetricResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMetric not implemented")
+}
+func (UnimplementedMetricsServer) ResetMetric(context.Context, *MetricRequest) (*SaveMetricResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetMetric not implemented")
+}
+func (UnimplementedMetricsServer) ListAlerts(context.Context, *AlertsRequest) (*AlertsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAlerts not implemented")
+}
+func (UnimplementedMetricsServer) AlertHistory(context.Context, *AlertsRequest) (*AlertsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AlertHistory not implemented")
+}
+func (UnimplementedMetricsServer) ListMetrics(context.Context, *ListMetricsRequest) (*ListMetricsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMetrics not implemented")
+}
 func (UnimplementedMetricsServer) mustEmbedUnimplementedMetricsServer() {}
 
 // UnsafeMetricsServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +167,96 @@ func _Metrics_SaveMetricFromJSON_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Metrics_DeleteMetric_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).DeleteMetric(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_DeleteMetric_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).DeleteMetric(ctx, req.(*MetricRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metrics_ResetMetric_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MetricRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).ResetMetric(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_ResetMetric_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).ResetMetric(ctx, req.(*MetricRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metrics_ListAlerts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AlertsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).ListAlerts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_ListAlerts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).ListAlerts(ctx, req.(*AlertsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metrics_AlertHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AlertsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).AlertHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_AlertHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).AlertHistory(ctx, req.(*AlertsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Metrics_ListMetrics_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMetricsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MetricsServer).ListMetrics(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Metrics_ListMetrics_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MetricsServer).ListMetrics(ctx, req.(*ListMetricsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Metrics_ServiceDesc is the grpc.ServiceDesc for Metrics service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -103,6 +268,26 @@ var Metrics_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SaveMetricFromJSON",
 			Handler:    _Metrics_SaveMetricFromJSON_Handler,
 		},
+		{
+			MethodName: "DeleteMetric",
+			Handler:    _Metrics_DeleteMetric_Handler,
+		},
+		{
+			MethodName: "ResetMetric",
+			Handler:    _Metrics_ResetMetric_Handler,
+		},
+		{
+			MethodName: "ListAlerts",
+			Handler:    _Metrics_ListAlerts_Handler,
+		},
+		{
+			MethodName: "AlertHistory",
+			Handler:    _Metrics_AlertHistory_Handler,
+		},
+		{
+			MethodName: "ListMetrics",
+			Handler:    _Metrics_ListMetrics_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/scraper.proto",